@@ -5,7 +5,7 @@ set DEST_DIR=c:\TOOLS\exe
 if not exist "%DEST_DIR%" mkdir "%DEST_DIR%"
 
 echo Building...
-go build -o combine.exe combine\main.go
+go build -o combine.exe .\combine
 
 if not %errorlevel%==0 (
     echo.
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// projectConfigNames are the config files looked up in the current directory,
+// in order of preference
+var projectConfigNames = []string{".combine.yaml", ".combine.yml", "combine.toml", ".combine.toml"}
+
+// discoveredOptions are the options a config file found in the current
+// directory may set. The rest (pipe-to, sign-key, verify-signature, unpack,
+// addr, ...) run commands, read keys or write files, so they are only taken
+// from a file named with --config.
+var discoveredOptions = map[string]bool{
+	"output": true, "root": true, "exclude": true, "recursive": true,
+	"hidden": true, "max-depth": true, "max-size": true, "glob-engine": true,
+	"format": true, "bom": true, "newline": true, "jobs": true, "max-memory": true,
+	"max-files": true, "min-files": true, "largest-first": true, "sort": true,
+	"reverse": true, "scan-extensions": true, "line-numbers": true,
+	"package-banners": true, "exclude-substring": true, "ignore-case": true,
+	"tree-hash": true, "no-timestamp": true, "reproducible": true,
+	"code-only": true, "docs-only": true, "normalize-indent": true,
+	"target-indent": true, "list-extensions": true, "embed-manifest": true,
+	"order-note": true, "toc": true, "normalize-newlines": true,
+	"warn-mixed-newlines": true, "encoding": true, "dedup": true,
+	"dedup-hardlinks": true, "collapse-path-depth": true, "skip-minified": true,
+	"minified-line-length": true, "min-size": true, "skip-empty": true,
+	"minified-min-size": true, "language": true, "exclude-language": true,
+	"imports-only": true, "title": true, "header": true, "footer": true,
+	"header-file": true, "footer-file": true, "separator-template-file": true,
+	"max-lines-per-ext": true, "max-tokens": true, "chunk-tokens": true,
+	"token-estimator": true, "max-total-size": true, "split-size": true,
+	"split-lines": true, "part-name": true, "exclude-name": true, "ere": true,
+	"ee": true, "pe": true, "grep": true, "grep-not": true, "quiet": true,
+	"tree": true, "tree-skipped": true, "manifest": true, "clone-report": true,
+	"clone-min-lines": true, "gzip": true, "gzip-level": true, "files-from": true,
+	"changed-since": true, "newer-than": true, "older-than": true, "staged": true,
+	"git-tracked": true, "include-untracked": true, "separator-format": true,
+	"separator-meta": true, "separator-template": true, "content-prefix": true,
+	"content-suffix": true, "no-separator": true, "no-leading-separator": true,
+	"ignore-gitignore": true, "respect-gitattributes": true, "path-style": true,
+	"null": true, "self-check": true, "exact-newlines": true, "verbose": true, "debug": true,
+	"dry-run": true, "list": true, "append": true, "watch": true,
+	"watch-debounce": true, "include-binary": true, "resume": true,
+}
+
+// discoveredPaths are the options naming a file or directory, which a
+// discovered config file may only point inside the current directory
+var discoveredPaths = map[string]bool{
+	"output": true, "root": true, "header-file": true, "footer-file": true,
+	"separator-template-file": true, "manifest": true, "files-from": true,
+}
+
+// projectSettings is one layer of a config file: the top level or a profile
+type projectSettings struct {
+	Patterns []string       `yaml:"patterns" toml:"patterns"`
+	Excludes []string       `yaml:"excludes" toml:"excludes"`
+	Output   string         `yaml:"output" toml:"output"`
+	Root     string         `yaml:"root" toml:"root"`
+	Options  map[string]any `yaml:"options" toml:"options"`
+}
+
+// projectConfig is a .combine.yaml or combine.toml file
+type projectConfig struct {
+	projectSettings `yaml:",inline"`
+	Profiles        map[string]projectSettings `yaml:"profiles" toml:"profiles"`
+}
+
+// projectConfigFlags picks --config, --profile and --no-config out of args
+// before the other flags are parsed
+func projectConfigFlags(args []string) (path, profile string, disabled bool) {
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		switch strings.TrimLeft(args[i], "-") {
+		case "config":
+			if i+1 < len(args) {
+				path = args[i+1]
+				i++
+			}
+		case "profile":
+			if i+1 < len(args) {
+				profile = args[i+1]
+				i++
+			}
+		case "no-config":
+			disabled = true
+		}
+	}
+	return path, profile, disabled
+}
+
+// loadProjectConfig reads the config file named by --config, or the first of
+// projectConfigNames in the current directory, and merges the selected
+// profile over its top level. It returns nil when there is no config file.
+func loadProjectConfig(args []string) (*projectSettings, string, error) {
+	path, profile, disabled := projectConfigFlags(args)
+	if disabled {
+		if profile != "" {
+			return nil, "", fmt.Errorf("--profile cannot be combined with --no-config")
+		}
+		return nil, "", nil
+	}
+	explicit := path != ""
+	if path == "" {
+		for _, name := range projectConfigNames {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	if path == "" {
+		if profile != "" {
+			return nil, "", fmt.Errorf("--profile %s needs a config file (%s)", profile, strings.Join(projectConfigNames, ", "))
+		}
+		return nil, "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("Cannot read config file: %v", err)
+	}
+	var file projectConfig
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &file)
+	} else {
+		err = yaml.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid config file %s: %v", path, err)
+	}
+
+	settings := file.projectSettings
+	if profile != "" {
+		override, ok := file.Profiles[profile]
+		if !ok {
+			names := make([]string, 0, len(file.Profiles))
+			for name := range file.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, "", fmt.Errorf("unknown profile %q in %s (available: %s)", profile, path, strings.Join(names, ", "))
+		}
+		settings.merge(override)
+	}
+	if !explicit {
+		if err := settings.checkDiscovered(); err != nil {
+			return nil, "", fmt.Errorf("%s: %v; pass --config %s to trust it", path, err, path)
+		}
+	}
+	return &settings, path, nil
+}
+
+// merge lays a profile over the top-level settings; options are merged key
+// by key, everything else is replaced when the profile sets it
+func (s *projectSettings) merge(profile projectSettings) {
+	if profile.Patterns != nil {
+		s.Patterns = profile.Patterns
+	}
+	if profile.Excludes != nil {
+		s.Excludes = profile.Excludes
+	}
+	if profile.Output != "" {
+		s.Output = profile.Output
+	}
+	if profile.Root != "" {
+		s.Root = profile.Root
+	}
+	options := make(map[string]any, len(s.Options)+len(profile.Options))
+	for key, value := range s.Options {
+		options[key] = value
+	}
+	for key, value := range profile.Options {
+		options[key] = value
+	}
+	s.Options = options
+}
+
+// checkDiscovered refuses the settings a config file picked up from the
+// current directory may not use: options outside discoveredOptions, and
+// paths leading out of the directory
+func (s *projectSettings) checkDiscovered() error {
+	dir, err := filepath.Abs(".")
+	if err != nil {
+		return err
+	}
+	paths := map[string][]string{"output": {s.Output}, "root": {s.Root}}
+	for key, value := range s.Options {
+		name := strings.TrimLeft(key, "-")
+		if !discoveredOptions[name] {
+			return fmt.Errorf("option %s is not allowed in a config file found in the current directory", name)
+		}
+		if discoveredPaths[name] {
+			paths[name] = append(paths[name], optionValues(value)...)
+		}
+	}
+	for name, values := range paths {
+		for _, value := range values {
+			if value != "" && !insideDir(dir, os.ExpandEnv(value)) {
+				return fmt.Errorf("%s %s is outside the current directory", name, value)
+			}
+		}
+	}
+	return nil
+}
+
+// optionValues returns an option's value, or each item of a list, as strings
+func optionValues(value any) []string {
+	items, ok := value.([]any)
+	if !ok {
+		return []string{fmt.Sprint(value)}
+	}
+	values := make([]string, len(items))
+	for i, item := range items {
+		values[i] = fmt.Sprint(item)
+	}
+	return values
+}
+
+// insideDir reports whether path, relative to the current directory, stays
+// inside dir
+func insideDir(dir, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// args renders the settings as command-line flags, to be parsed ahead of
+// the real ones so those override them. Patterns are left out; they only
+// apply when the command line names none.
+func (s *projectSettings) args() ([]string, error) {
+	var args []string
+	if s.Output != "" {
+		args = append(args, "-o", s.Output)
+	}
+	if s.Root != "" {
+		args = append(args, "--root", s.Root)
+	}
+	for _, exclude := range s.Excludes {
+		// One -e per entry, its commas escaped so SplitPatterns keeps it whole
+		args = append(args, "-e", strings.ReplaceAll(exclude, ",", `\,`))
+	}
+
+	keys := make([]string, 0, len(s.Options))
+	for key := range s.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		flag := "--" + strings.TrimLeft(key, "-")
+		switch value := s.Options[key].(type) {
+		case bool:
+			switch {
+			case value:
+				args = append(args, flag)
+			case switchFlags[flag] != nil || flag == "--hidden":
+				args = append(args, flag+"=false")
+			case flag == "--quiet":
+				// Quiet is off unless asked for; nothing to pass
+			default:
+				return nil, fmt.Errorf("option %s cannot be false", key)
+			}
+		case []any:
+			// Lists repeat the flag (ere: [a, b] -> --ere a --ere b)
+			for _, item := range value {
+				args = append(args, flag, fmt.Sprint(item))
+			}
+		default:
+			args = append(args, flag, fmt.Sprint(value))
+		}
+	}
+	return args, nil
+}
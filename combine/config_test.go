@@ -0,0 +1,281 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfig writes a config file into a new directory and makes it the
+// working directory for the rest of the test
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func TestProjectSettingsMerge(t *testing.T) {
+	base := projectSettings{
+		Patterns: []string{"*.go"},
+		Excludes: []string{"vendor"},
+		Output:   "all.txt",
+		Options:  map[string]any{"recursive": true, "format": "text"},
+	}
+	base.merge(projectSettings{
+		Patterns: []string{"*.md"},
+		Options:  map[string]any{"format": "markdown", "toc": true},
+	})
+	if !sameStrings(base.Patterns, []string{"*.md"}) || !sameStrings(base.Excludes, []string{"vendor"}) || base.Output != "all.txt" {
+		t.Errorf("merged settings = %+v", base)
+	}
+	want := map[string]any{"recursive": true, "format": "markdown", "toc": true}
+	if len(base.Options) != len(want) {
+		t.Fatalf("options = %v, want %v", base.Options, want)
+	}
+	for key, value := range want {
+		if base.Options[key] != value {
+			t.Errorf("option %s = %v, want %v", key, base.Options[key], value)
+		}
+	}
+}
+
+func TestProjectSettingsArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings projectSettings
+		want     []string
+		err      bool
+	}{
+		{"output root excludes", projectSettings{Output: "o.txt", Root: "src", Excludes: []string{"a", "b"}},
+			[]string{"-o", "o.txt", "--root", "src", "-e", "a", "-e", "b"}, false},
+		{"exclude with commas", projectSettings{Excludes: []string{"odd,name.txt", "*.{go,md}"}},
+			[]string{"-e", `odd\,name.txt`, "-e", `*.{go\,md}`}, false},
+		{"quiet false", projectSettings{Options: map[string]any{"quiet": false}}, nil, false},
+		{"switches", projectSettings{Options: map[string]any{"recursive": true, "toc": false}},
+			[]string{"--recursive", "--toc=false"}, false},
+		{"hidden false", projectSettings{Options: map[string]any{"hidden": false}},
+			[]string{"--hidden=false"}, false},
+		{"values and lists", projectSettings{Options: map[string]any{"max-depth": 2, "ere": []any{"a", "b"}}},
+			[]string{"--ere", "a", "--ere", "b", "--max-depth", "2"}, false},
+		{"action flag set to false", projectSettings{Options: map[string]any{"largest-first": false}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.settings.args()
+			if (err != nil) != tt.err {
+				t.Fatalf("args() error = %v, want error %v", err, tt.err)
+			}
+			if !sameStrings(got, tt.want) {
+				t.Errorf("args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDiscovered(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("COMBINE_TEST_DIR", "/tmp")
+	tests := []struct {
+		name     string
+		settings projectSettings
+		ok       bool
+	}{
+		{"shaping options", projectSettings{Output: "out/all.txt", Options: map[string]any{"toc": true, "max-depth": 3}}, true},
+		{"pipe-to", projectSettings{Options: map[string]any{"pipe-to": "sh -c id"}}, false},
+		{"sign-key", projectSettings{Options: map[string]any{"sign-key": "k"}}, false},
+		{"addr", projectSettings{Options: map[string]any{"addr": ":80"}}, false},
+		{"env expansion", projectSettings{Options: map[string]any{"env-expand-in-patterns": true}}, false},
+		{"output outside", projectSettings{Output: "../all.txt"}, false},
+		{"absolute root", projectSettings{Root: "/etc"}, false},
+		{"manifest through a variable", projectSettings{Options: map[string]any{"manifest": "$COMBINE_TEST_DIR/m.json"}}, false},
+		{"files-from list outside", projectSettings{Options: map[string]any{"files-from": []any{"ok.txt", "../x.txt"}}}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.settings.checkDiscovered(); (err == nil) != tt.ok {
+			t.Errorf("%s: checkDiscovered() = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestLoadProjectConfig(t *testing.T) {
+	const yamlConfig = `
+patterns: ["*.go"]
+output: all.txt
+options:
+  recursive: true
+profiles:
+  docs:
+    patterns: ["*.md"]
+    options:
+      format: markdown
+  ship:
+    options:
+      pipe-to: "cat"
+`
+	writeConfig(t, ".combine.yaml", yamlConfig)
+
+	settings, path, err := loadProjectConfig(nil)
+	if err != nil || path != ".combine.yaml" {
+		t.Fatalf("loadProjectConfig = %q, %v", path, err)
+	}
+	if !sameStrings(settings.Patterns, []string{"*.go"}) || settings.Options["recursive"] != true {
+		t.Errorf("top level = %+v", settings)
+	}
+
+	settings, _, err = loadProjectConfig([]string{"--profile", "docs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameStrings(settings.Patterns, []string{"*.md"}) || settings.Options["format"] != "markdown" || settings.Options["recursive"] != true {
+		t.Errorf("docs profile = %+v", settings)
+	}
+
+	for _, args := range [][]string{
+		{"--profile", "missing"},
+		{"--profile", "ship"},
+		{"--profile", "docs", "--no-config"},
+	} {
+		if _, _, err := loadProjectConfig(args); err == nil {
+			t.Errorf("loadProjectConfig(%q) succeeded", args)
+		}
+	}
+	if _, _, err := loadProjectConfig([]string{"--config", ".combine.yaml", "--profile", "ship"}); err != nil {
+		t.Errorf("--config does not trust the file: %v", err)
+	}
+	if settings, _, err := loadProjectConfig([]string{"--no-config"}); settings != nil || err != nil {
+		t.Errorf("--no-config = %+v, %v", settings, err)
+	}
+}
+
+func TestLoadProjectConfigTOML(t *testing.T) {
+	writeConfig(t, "combine.toml", `
+patterns = ["*.go"]
+excludes = ["vendor"]
+
+[options]
+max-depth = 2
+
+[profiles.ci.options]
+no-timestamp = true
+`)
+	settings, _, err := loadProjectConfig([]string{"--profile", "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameStrings(settings.Excludes, []string{"vendor"}) || settings.Options["no-timestamp"] != true {
+		t.Errorf("settings = %+v", settings)
+	}
+	args, err := settings.args()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(args, " "); got != "-e vendor --max-depth 2 --no-timestamp" {
+		t.Errorf("args() = %q", got)
+	}
+}
+
+func TestParseFlagsOverridesConfig(t *testing.T) {
+	writeConfig(t, ".combine.yaml", `
+patterns: ["*.go"]
+excludes: [vendor, testdata, "odd,name.txt"]
+output: all.txt
+options:
+  recursive: true
+  hidden: false
+  quiet: false
+  max-depth: 3
+`)
+	tests := []struct {
+		name       string
+		args       []string
+		recursive  bool
+		skipHidden bool
+		maxDepth   int
+		output     string
+		patterns   []string
+		excludes   []string
+	}{
+		{"config alone", nil, true, true, 3, "all.txt", []string{"*.go"}, []string{"vendor", "testdata", "odd,name.txt"}},
+		{"switches turned off", []string{"--recursive=false", "-hidden=true", "-max-depth=1"},
+			false, false, 1, "all.txt", []string{"*.go"}, []string{"vendor", "testdata", "odd,name.txt"}},
+		{"command line lists replace the config's", []string{"-e", "gen", "-o", "x.txt", "*.md"},
+			true, true, 3, "x.txt", []string{"*.md"}, []string{"gen"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseFlags("run", tt.args)
+			if config.Recursive != tt.recursive || config.SkipHidden != tt.skipHidden || config.MaxDepth != tt.maxDepth || config.Output != tt.output {
+				t.Errorf("recursive=%v skipHidden=%v maxDepth=%d output=%q, want %v %v %d %q",
+					config.Recursive, config.SkipHidden, config.MaxDepth, config.Output,
+					tt.recursive, tt.skipHidden, tt.maxDepth, tt.output)
+			}
+			if !sameStrings(config.Patterns, tt.patterns) || !sameStrings(config.Excludes, tt.excludes) {
+				t.Errorf("patterns=%q excludes=%q, want %q %q", config.Patterns, config.Excludes, tt.patterns, tt.excludes)
+			}
+		})
+	}
+}
+
+func sameStrings(a, b []string) bool {
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
+func TestHeaderFooterFiles(t *testing.T) {
+	dir := writeConfig(t, ".combine.yaml", `
+patterns: ["*.go"]
+output: all.txt
+options:
+  header-file: prompt.md
+`)
+	for name, content := range map[string]string{
+		"prompt.md":  "Review this code:\n",
+		"stats.tmpl": "{{.FileCount}} files\n",
+		"other.md":   "Other prompt\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		args   []string
+		header string
+		footer string
+	}{
+		{"from the config", nil, "Review this code:\n", ""},
+		{"footer file", []string{"--footer-file", "stats.tmpl"}, "Review this code:\n", "{{.FileCount}} files\n"},
+		{"command line wins", []string{"--header-file=other.md"}, "Other prompt\n", ""},
+		{"inline text wins", []string{"--header", "inline"}, "inline", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseFlags("run", tt.args)
+			if config.Header != tt.header || config.Footer != tt.footer {
+				t.Errorf("header %q, footer %q; want %q, %q", config.Header, config.Footer, tt.header, tt.footer)
+			}
+		})
+	}
+}
+
+func TestSeparatorTemplateFile(t *testing.T) {
+	// Only the inline template has its \n and \t escapes expanded
+	writeConfig(t, "banner.tmpl", `--- {{.Index}}:\t{{.RelPath}} ---`+"\n")
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"*.go", "-o", "all.txt", "--separator-template-file", "banner.tmpl"}, `--- {{.Index}}:\t{{.RelPath}} ---` + "\n"},
+		{[]string{"*.go", "-o", "all.txt", "--separator-template-file=banner.tmpl"}, `--- {{.Index}}:\t{{.RelPath}} ---` + "\n"},
+		{[]string{"*.go", "-o", "all.txt", "--separator-template", `{{.Name}}\t{{.Size}}\n`}, "{{.Name}}\t{{.Size}}\n"},
+	}
+	for _, tt := range tests {
+		if config := parseFlags("run", tt.args); config.SeparatorTemplate != tt.want {
+			t.Errorf("%q: separator template %q, want %q", tt.args, config.SeparatorTemplate, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cumulus13/combine-go/pkg/combiner"
+)
+
+// valueFlags are the options that take a value
+var valueFlags = map[string]bool{
+	"-o": true, "--output": true, "-p": true, "-e": true, "--exclude": true,
+	"--root": true, "--max-depth": true, "--max-size": true, "--glob-engine": true,
+	"--format": true, "--bom": true, "--newline": true, "-j": true, "--jobs": true,
+	"--max-memory": true, "--max-files": true, "--min-files": true, "--sort": true,
+	"--normalize-indent": true, "--target-indent": true, "--unpack": true,
+	"--encoding": true, "--collapse-path-depth": true, "--minified-line-length": true,
+	"--min-size": true, "--minified-min-size": true, "--language": true,
+	"--exclude-language": true, "--title": true, "--header": true, "--footer": true,
+	"--header-file": true, "--footer-file": true, "--separator-template-file": true,
+	"--max-lines-per-ext": true, "--max-tokens": true, "--chunk-tokens": true,
+	"--token-estimator": true, "--max-total-size": true, "--split-size": true,
+	"--split-lines": true, "--part-name": true, "--exclude-name": true,
+	"--ere": true, "--ee": true, "--pe": true, "--grep": true, "--grep-not": true,
+	"--watch-debounce": true, "--addr": true, "--sign-key": true,
+	"--verify-signature": true, "--manifest": true, "--clone-min-lines": true,
+	"--gzip-level": true, "--files-from": true, "--changed-since": true,
+	"--newer-than": true, "--older-than": true, "--separator-format": true,
+	"--separator-meta": true, "--separator-template": true, "--content-prefix": true,
+	"--content-suffix": true, "--path-style": true, "--pipe-to": true,
+	"--config": true, "--profile": true,
+}
+
+// switchFlags are the on/off options that only set their own field, so
+// --name=false can turn off one a config file turned on
+var switchFlags = map[string]func(*combiner.Options) *bool{
+	"-r":                       func(o *combiner.Options) *bool { return &o.Recursive },
+	"--recursive":              func(o *combiner.Options) *bool { return &o.Recursive },
+	"--reverse":                func(o *combiner.Options) *bool { return &o.Reverse },
+	"--scan-extensions":        func(o *combiner.Options) *bool { return &o.ScanExtensions },
+	"--line-numbers":           func(o *combiner.Options) *bool { return &o.LineNumbers },
+	"--package-banners":        func(o *combiner.Options) *bool { return &o.PackageBanners },
+	"--exclude-substring":      func(o *combiner.Options) *bool { return &o.ExcludeSubstring },
+	"--ignore-case":            func(o *combiner.Options) *bool { return &o.IgnoreCase },
+	"--tree-hash":              func(o *combiner.Options) *bool { return &o.TreeHash },
+	"--no-timestamp":           func(o *combiner.Options) *bool { return &o.NoTimestamp },
+	"--reproducible":           func(o *combiner.Options) *bool { return &o.NoTimestamp },
+	"--list-extensions":        func(o *combiner.Options) *bool { return &o.ListExtensions },
+	"--embed-manifest":         func(o *combiner.Options) *bool { return &o.EmbedManifest },
+	"--order-note":             func(o *combiner.Options) *bool { return &o.OrderNote },
+	"--toc":                    func(o *combiner.Options) *bool { return &o.TOC },
+	"--normalize-newlines":     func(o *combiner.Options) *bool { return &o.NormalizeNewlines },
+	"--warn-mixed-newlines":    func(o *combiner.Options) *bool { return &o.WarnMixedNewlines },
+	"--dedup":                  func(o *combiner.Options) *bool { return &o.Dedup },
+	"--dedup-hardlinks":        func(o *combiner.Options) *bool { return &o.DedupHardlinks },
+	"--skip-minified":          func(o *combiner.Options) *bool { return &o.SkipMinified },
+	"--skip-empty":             func(o *combiner.Options) *bool { return &o.SkipEmpty },
+	"--imports-only":           func(o *combiner.Options) *bool { return &o.ImportsOnly },
+	"--append":                 func(o *combiner.Options) *bool { return &o.Append },
+	"--watch":                  func(o *combiner.Options) *bool { return &o.Watch },
+	"--tree":                   func(o *combiner.Options) *bool { return &o.Tree },
+	"--tree-skipped":           func(o *combiner.Options) *bool { return &o.TreeSkipped },
+	"--env-expand-in-patterns": func(o *combiner.Options) *bool { return &o.EnvExpand },
+	"--clone-report":           func(o *combiner.Options) *bool { return &o.CloneReport },
+	"--gzip":                   func(o *combiner.Options) *bool { return &o.Gzip },
+	"--staged":                 func(o *combiner.Options) *bool { return &o.Staged },
+	"--git-tracked":            func(o *combiner.Options) *bool { return &o.GitTracked },
+	"--include-untracked":      func(o *combiner.Options) *bool { return &o.IncludeUntracked },
+	"--no-separator":           func(o *combiner.Options) *bool { return &o.NoSeparator },
+	"--no-leading-separator":   func(o *combiner.Options) *bool { return &o.NoLeadingSeparator },
+	"--ignore-gitignore":       func(o *combiner.Options) *bool { return &o.IgnoreGitignore },
+	"--respect-gitattributes":  func(o *combiner.Options) *bool { return &o.RespectGitattributes },
+	"--dry-run":                func(o *combiner.Options) *bool { return &o.DryRun },
+	"--list":                   func(o *combiner.Options) *bool { return &o.List },
+	"-0":                       func(o *combiner.Options) *bool { return &o.NullSeparated },
+	"--null":                   func(o *combiner.Options) *bool { return &o.NullSeparated },
+	"--self-check":             func(o *combiner.Options) *bool { return &o.SelfCheck },
+	"--exact-newlines":         func(o *combiner.Options) *bool { return &o.ExactNewlines },
+	"--verbose":                func(o *combiner.Options) *bool { return &o.Verbose },
+}
+
+// actionFlags take no value but do more than set a field, so they can be
+// given (or =true) but not turned off with =false. --hidden is the
+// exception: the flag loop handles --hidden=false itself.
+var actionFlags = map[string]bool{
+	"--largest-first": true, "--code-only": true, "--docs-only": true,
+	"--resume": true, "--include-binary": true, "--debug": true,
+	"-q": true, "--quiet": true, "--hidden": true, "--no-hidden": true,
+	"-v": true, "--version": true, "-h": true, "--help": true, "--no-config": true,
+}
+
+// knownFlag reports whether name is one of the options parseFlags accepts
+func knownFlag(name string) bool {
+	return valueFlags[name] || switchFlags[name] != nil || actionFlags[name]
+}
+
+// normalizeArgs rewrites args into the forms the flag loop expects: a long
+// option spelled with one dash (-glob-engine) gets its second dash,
+// --name=value becomes --name value, --name=true becomes --name and
+// --name=false is kept for the loop to turn the switch off. Only known
+// option names are rewritten, so a pattern starting with "-" and the value
+// following a flag are passed through untouched.
+func normalizeArgs(args []string) ([]string, error) {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			out = append(out, arg)
+			continue
+		}
+		name, val, hasValue := strings.Cut(arg, "=")
+		if len(name) > 2 && name[1] != '-' && knownFlag("-"+name) {
+			name = "-" + name
+		}
+		if !knownFlag(name) {
+			out = append(out, arg)
+			continue
+		}
+		switch {
+		case valueFlags[name]:
+			out = append(out, name)
+			if hasValue {
+				out = append(out, val)
+			} else if i+1 < len(args) {
+				out = append(out, args[i+1])
+				i++
+			}
+		case !hasValue || val == "true":
+			out = append(out, name)
+		case val != "false":
+			return nil, fmt.Errorf("%s takes true or false, not %q", name, val)
+		case switchFlags[name] != nil || name == "--hidden":
+			out = append(out, name+"=false")
+		default:
+			return nil, fmt.Errorf("%s cannot be set to false", name)
+		}
+	}
+	return out, nil
+}
+
+// switchArg reports whether arg is a normalized switch, and the value to set
+func switchArg(arg string) (name string, on bool, ok bool) {
+	name = strings.TrimSuffix(arg, "=false")
+	if switchFlags[name] == nil {
+		return "", false, false
+	}
+	return name, name == arg, true
+}
+
+// mustNormalizeArgs is normalizeArgs for parseFlags, exiting on a bad switch
+func mustNormalizeArgs(args []string) []string {
+	args, err := normalizeArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return args
+}
@@ -0,0 +1,99 @@
+package main
+
+import "testing"
+
+func TestNormalizeArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		err  bool
+	}{
+		{[]string{"-glob-engine", "standard"}, []string{"--glob-engine", "standard"}, false},
+		{[]string{"--max-depth=2"}, []string{"--max-depth", "2"}, false},
+		{[]string{"-max-depth=2"}, []string{"--max-depth", "2"}, false},
+		{[]string{"-o=out.txt"}, []string{"-o", "out.txt"}, false},
+		{[]string{"--title=a=b"}, []string{"--title", "a=b"}, false},
+		{[]string{"--recursive=true"}, []string{"--recursive"}, false},
+		{[]string{"-recursive=false"}, []string{"--recursive=false"}, false},
+		{[]string{"--hidden=false"}, []string{"--hidden=false"}, false},
+		{[]string{"-r", "*.go"}, []string{"-r", "*.go"}, false},
+		{[]string{"-e", "-weird-name"}, []string{"-e", "-weird-name"}, false},
+		{[]string{"--title", "-toc"}, []string{"--title", "-toc"}, false},
+		{[]string{"-unknown=1", "-x"}, []string{"-unknown=1", "-x"}, false},
+		{[]string{"--toc=yes"}, nil, true},
+		{[]string{"--largest-first=false"}, nil, true},
+		{[]string{"--version=false"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeArgs(tt.args)
+		if (err != nil) != tt.err {
+			t.Errorf("normalizeArgs(%q) error = %v, want error %v", tt.args, err, tt.err)
+			continue
+		}
+		if !sameStrings(got, tt.want) {
+			t.Errorf("normalizeArgs(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSwitchArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		name string
+		on   bool
+		ok   bool
+	}{
+		{"--recursive", "--recursive", true, true},
+		{"--recursive=false", "--recursive", false, true},
+		{"-r", "-r", true, true},
+		{"--hidden=false", "", false, false},
+		{"--largest-first", "", false, false},
+		{"*.go", "", false, false},
+	}
+	for _, tt := range tests {
+		name, on, ok := switchArg(tt.arg)
+		if name != tt.name || on != tt.on || ok != tt.ok {
+			t.Errorf("switchArg(%q) = %q, %v, %v; want %q, %v, %v", tt.arg, name, on, ok, tt.name, tt.on, tt.ok)
+		}
+	}
+}
+
+func TestFlagTablesDisjoint(t *testing.T) {
+	for name := range valueFlags {
+		if switchFlags[name] != nil || actionFlags[name] {
+			t.Errorf("%s is both a value flag and a switch", name)
+		}
+	}
+	for name := range switchFlags {
+		if actionFlags[name] {
+			t.Errorf("%s is both a switch and an action flag", name)
+		}
+	}
+}
+
+func TestRepeatablePatterns(t *testing.T) {
+	writeConfig(t, "unused.txt", "") // an empty directory, no project config
+	tests := []struct {
+		name     string
+		args     []string
+		patterns []string
+		excludes []string
+	}{
+		{"comma list", []string{"-p", "*.go,*.md", "-e", "vendor,gen"}, []string{"*.go", "*.md"}, []string{"vendor", "gen"}},
+		{"repeated", []string{"-p", "*.go", "-p", "*.md", "-e", "vendor", "--exclude", "gen"}, []string{"*.go", "*.md"}, []string{"vendor", "gen"}},
+		{"mixed", []string{"-p", "*.go,*.md", "-p", "*.txt", "-e", "a", "-e", "b,c"}, []string{"*.go", "*.md", "*.txt"}, []string{"a", "b", "c"}},
+		{"brace groups keep their commas", []string{"-p", "src/*.{go,mod}", "-p", "*.md"}, []string{"src/*.{go,mod}", "*.md"}, nil},
+		{"positional patterns first", []string{"-p", "*.go", "README.md"}, []string{"README.md", "*.go"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := parseFlags("run", append(tt.args, "-o", "all.txt"))
+			if !sameStrings(config.Patterns, tt.patterns) {
+				t.Errorf("patterns = %q, want %q", config.Patterns, tt.patterns)
+			}
+			if !sameStrings(config.Excludes, tt.excludes) {
+				t.Errorf("excludes = %q, want %q", config.Excludes, tt.excludes)
+			}
+		})
+	}
+}
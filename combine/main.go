@@ -3,23 +3,18 @@ package main
 import (
 	"bufio"
 	"bytes"
-	// "flag"
+	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"net/http"
 	"os"
-	"path/filepath"
-	"sort"
+	"regexp"
 	"strings"
-	"time"
 	"strconv"
+	"time"
 
-	"github.com/atotto/clipboard"
-)
-
-const (
-	// VERSION        = "2.2.0"
-	MAX_FILE_SIZE  = 100 * 1024 * 1024 // 100MB
-	BUFFER_SIZE    = 8192
+	"github.com/cumulus13/combine-go/pkg/combiner"
 )
 
 var (
@@ -28,127 +23,6 @@ var (
 	Repo    = "https://github.com/cumulus13/combine-go"
 )
 
-// CommentStyle defines how to format comments for different file types
-type CommentStyle struct {
-	SingleLine string
-	BlockStart string
-	BlockEnd   string
-}
-
-// Config holds command-line arguments
-type Config struct {
-	Patterns        []string
-	Output          string
-	Excludes        []string
-	Root            string
-	NoSeparator     bool
-	Encoding        string
-	NewlineType     string
-	MaxSize         int64
-	IgnoreGitignore bool
-	DryRun          bool
-	Verbose         bool
-	Debug           bool
-	Recursive		bool
-}
-
-// FileInfo holds information about processed files
-type FileInfo struct {
-	Path   string
-	Reason string
-}
-
-// Comment styles by extension
-var commentStyles = map[string]CommentStyle{
-	// # comments
-	".py":   {SingleLine: "#"},
-	".rb":   {SingleLine: "#"},
-	".sh":   {SingleLine: "#"},
-	".bash": {SingleLine: "#"},
-	".zsh":  {SingleLine: "#"},
-	".yaml": {SingleLine: "#"},
-	".yml":  {SingleLine: "#"},
-	".toml": {SingleLine: "#"},
-	".conf": {SingleLine: "#"},
-	".ini":  {SingleLine: "#"},
-	".r":    {SingleLine: "#"},
-	".pl":   {SingleLine: "#"},
-	".pm":   {SingleLine: "#"},
-
-	// // comments
-	".js":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".ts":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".jsx":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".tsx":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".java":  {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".c":     {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".cpp":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".cc":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".h":     {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".hpp":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".cs":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".go":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".swift": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".kt":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".scala": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".rs":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".dart":  {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".php":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-
-	// Markup
-	".html": {BlockStart: "<!--", BlockEnd: "-->"},
-	".xml":  {BlockStart: "<!--", BlockEnd: "-->"},
-	".svg":  {BlockStart: "<!--", BlockEnd: "-->"},
-	".css":  {BlockStart: "/*", BlockEnd: "*/"},
-	".scss": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-	".sass": {SingleLine: "//"},
-	".less": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
-
-	// Others
-	".sql":  {SingleLine: "--", BlockStart: "/*", BlockEnd: "*/"},
-	".lisp": {SingleLine: ";"},
-	".clj":  {SingleLine: ";"},
-	".scm":  {SingleLine: ";"},
-	".lua":  {SingleLine: "--", BlockStart: "--[[", BlockEnd: "]]"},
-	".bat":  {SingleLine: "REM"},
-	".cmd":  {SingleLine: "REM"},
-	".vb":   {SingleLine: "'"},
-	".m":    {SingleLine: "%"},
-	".tex":  {SingleLine: "%"},
-	".txt":  {SingleLine: "#"},
-	".md":   {BlockStart: "<!--", BlockEnd: "-->"},
-	".rst":  {SingleLine: ".."},
-}
-
-// Binary file extensions
-var binaryExtensions = map[string]bool{
-	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".bin": true, ".dat": true,
-	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".ico": true,
-	".mp3": true, ".mp4": true, ".wav": true, ".avi": true, ".mov": true, ".flv": true,
-	".zip": true, ".tar": true, ".gz": true, ".bz2": true, ".7z": true, ".rar": true,
-	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
-	".ppt": true, ".pptx": true, ".pyc": true, ".pyo": true, ".class": true,
-	".o": true, ".obj": true,
-}
-
-// Known text file extensions
-var textExtensions = map[string]bool{
-	".js": true, ".ts": true, ".jsx": true, ".tsx": true, ".json": true,
-	".html": true, ".htm": true, ".xml": true, ".css": true, ".scss": true,
-	".sass": true, ".less": true, ".md": true, ".txt": true, ".csv": true,
-	".py": true, ".rb": true, ".java": true, ".c": true, ".cpp": true,
-	".h": true, ".hpp": true, ".go": true, ".rs": true, ".php": true,
-	".sh": true, ".bash": true, ".zsh": true, ".bat": true, ".cmd": true,
-	".ps1": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true,
-	".conf": true, ".cfg": true, ".sql": true, ".r": true, ".m": true,
-	".pl": true, ".pm": true, ".lua": true, ".swift": true, ".kt": true,
-	".dart": true, ".vue": true, ".svelte": true, ".astro": true,
-	".cs": true, ".vb": true, ".fs": true, ".lisp": true, ".clj": true,
-	".scm": true, ".scala": true, ".erl": true, ".ex": true, ".exs": true,
-	".dockerfile": true, ".gitignore": true, ".env": true, ".editorconfig": true,
-	".rst": true, ".adoc": true, ".textile": true, ".org": true,
-}
-
 func init() {
 	Version = readVersion()
 }
@@ -185,47 +59,85 @@ func readVersion() string {
 	return "unknown"
 }
 
+// commands are the subcommands; any other first argument is read in the
+// legacy flat form, which behaves like run
+var commands = map[string]bool{"run": true, "split": true, "list": true, "watch": true, "serve": true}
+
+// serveAddr is where the serve command listens (--addr)
+var serveAddr = "localhost:8080"
+
+// quiet drops the summary and progress reports (-q)
+var quiet bool
+
+// splitCommand takes the subcommand off the arguments
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 && commands[args[0]] {
+		return args[0], args[1:]
+	}
+	return "run", args
+}
+
 func main() {
-	config := parseFlags()
+	command, args := splitCommand(os.Args[1:])
+	config := parseFlags(command, args)
+	config.Version = Version
+	config.Stdout = os.Stdout
 
 	if config.Debug {
 		config.Verbose = true
 	}
 
-	// Validate root directory
-	rootInfo, err := os.Stat(config.Root)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Root directory does not exist: %s\n", config.Root)
-		os.Exit(1)
+	// Answer HTTP requests with a fresh output instead of writing one
+	if command == "serve" {
+		os.Exit(serve(config))
 	}
-	if !rootInfo.IsDir() {
-		fmt.Fprintf(os.Stderr, "Error: Root path is not a directory: %s\n", config.Root)
-		os.Exit(1)
+
+	// With -o - the combined output owns stdout; everything else goes to stderr
+	if config.Output == "-" && !config.List {
+		os.Stdout = os.Stderr
 	}
 
-	// Load gitignore patterns
-	var gitignorePatterns []string
-	if !config.IgnoreGitignore {
-		gitignorePatterns = loadGitignore(config.Root, config.Verbose)
+	// Quiet runs only report warnings and errors
+	if quiet && !config.List && !config.ScanExtensions {
+		if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+			os.Stdout = devNull
+		}
 	}
 
-	// Combine all exclusion patterns
-	allExcludes := append(config.Excludes, gitignorePatterns...)
+	// Recreate files from a combined output instead of combining
+	if config.Unpack != "" {
+		os.Exit(combiner.UnpackCombined(config))
+	}
 
-	// Find files
-	if config.Verbose {
-		fmt.Println("Searching for files...")
+	// Check a signed output instead of combining
+	if config.VerifySignature != "" {
+		os.Exit(combiner.VerifySignature(config))
 	}
 
-	// files, skipped := findFiles(config.Root, config.Patterns, allExcludes, config.MaxSize, config.Verbose)
-	files, skipped := findFiles(config.Root, config.Patterns, allExcludes, config.MaxSize, config.Verbose, config.Recursive)
+	ctx := context.Background()
+	c := combiner.New(config)
+	report, err := c.Select(ctx)
+	if err != nil {
+		exit(err)
+	}
+
+	// Discovery-only extension report
+	if config.ScanExtensions {
+		combiner.PrintExtensionScan(report.Files)
+		os.Exit(0)
+	}
+
+	// Discovery-only listing
+	if config.List {
+		combiner.PrintFileList(config, report.Files)
+		os.Exit(0)
+	}
 
 	// Print summary
-	printSummary(config, files, skipped)
+	combiner.PrintSummary(config, report)
 
-	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: No files found matching the patterns")
-		os.Exit(1)
+	if err := c.Combine(ctx, report); err != nil {
+		exit(err)
 	}
 
 	// Dry run mode
@@ -234,367 +146,96 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Combine files
-	if config.Verbose {
-		fmt.Println("Combining files...")
+	// Keep the output up to date until interrupted
+	if config.Watch {
+		os.Exit(c.Watch(ctx, report))
 	}
-
-	exitCode := combineFiles(config, files)
-	os.Exit(exitCode)
 }
 
-// func parseFlags() *Config {
-// 	config := &Config{}
-
-// 	var patterns string
-// 	var excludes string
-
-// 	flag.StringVar(&patterns, "p", "", "Glob patterns (comma-separated), e.g., \"*.py,*.txt\"")
-// 	flag.StringVar(&config.Output, "o", "", "Output file path (required)")
-// 	flag.StringVar(&excludes, "e", "", "Exclude patterns (comma-separated)")
-// 	flag.StringVar(&config.Root, "root", ".", "Root directory to search")
-// 	flag.BoolVar(&config.NoSeparator, "no-separator", false, "Don't add separators between files")
-// 	flag.StringVar(&config.Encoding, "encoding", "utf-8", "Output file encoding")
-// 	flag.StringVar(&config.NewlineType, "newline", "lf", "Newline type: lf, crlf, cr")
-// 	flag.Int64Var(&config.MaxSize, "max-size", MAX_FILE_SIZE, "Maximum file size in bytes")
-// 	flag.BoolVar(&config.IgnoreGitignore, "ignore-gitignore", false, "Don't read .gitignore")
-// 	flag.BoolVar(&config.DryRun, "dry-run", false, "Preview without writing")
-// 	flag.BoolVar(&config.Verbose, "v", false, "Verbose output")
-// 	flag.BoolVar(&config.Debug, "debug", false, "Debug mode")
-
-// 	version := flag.Bool("version", false, "Show version")
-
-// 	// Custom usage
-// 	flag.Usage = func() {
-// 		fmt.Fprintf(os.Stderr, "combine v%s - Combine multiple files matching patterns\n\n", VERSION)
-// 		fmt.Fprintf(os.Stderr, "Usage:\n")
-// 		fmt.Fprintf(os.Stderr, "  combine [patterns] -o output.txt [options]\n\n")
-// 		fmt.Fprintf(os.Stderr, "Examples:\n")
-// 		fmt.Fprintf(os.Stderr, "  combine *.py *.txt -o combined.txt\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -p \"*.go,*.mod\" -o golang.txt\n")
-// 		fmt.Fprintf(os.Stderr, "  combine src/**/*.cpp -o output.cpp\n")
-// 		fmt.Fprintf(os.Stderr, "  combine \"*.md\" README LICENSE -o docs.txt\n\n")
-// 		fmt.Fprintf(os.Stderr, "Options:\n")
-// 		flag.PrintDefaults()
-// 	}
-
-// 	flag.Parse()
-
-// 	if *version {
-// 		fmt.Printf("combine v%s\n", VERSION)
-// 		os.Exit(0)
-// 	}
-
-// 	// Parse -p glob patterns
-// 	if patterns != "" {
-// 		parts := strings.Split(patterns, ",")
-// 		for _, p := range parts {
-// 			p = strings.TrimSpace(p)
-// 			if p != "" {
-// 				config.Patterns = append(config.Patterns, p)
-// 			}
-// 		}
-// 	}
-
-// 	// Add positional args as patterns
-// 	positional := flag.Args()
-// 	for _, arg := range positional {
-// 		arg = strings.TrimSpace(arg)
-// 		if arg != "" {
-// 			config.Patterns = append(config.Patterns, arg)
-// 		}
-// 	}
-
-// 	// Validate: must have patterns
-// 	if len(config.Patterns) == 0 {
-// 		fmt.Fprintln(os.Stderr, "Error: No patterns supplied.")
-// 		flag.Usage()
-// 		os.Exit(1)
-// 	}
-
-// 	// Validate output
-// 	if config.Output == "" {
-// 		fmt.Fprintln(os.Stderr, "Error: Output file (-o) is required.")
-// 		flag.Usage()
-// 		os.Exit(1)
-// 	}
-
-// 	// Parse excludes
-// 	if excludes != "" {
-// 		parts := strings.Split(excludes, ",")
-// 		for _, p := range parts {
-// 			p = strings.TrimSpace(p)
-// 			if p != "" {
-// 				config.Excludes = append(config.Excludes, p)
-// 			}
-// 		}
-// 	}
-
-// 	// Normalize newline type
-// 	config.NewlineType = strings.ToLower(config.NewlineType)
-
-// 	return config
-// }
-
-// func parseFlags() *Config {
-// 	config := &Config{}
-
-// 	var patternsStr string
-// 	var excludesStr string
-
-// 	flag.StringVar(&patternsStr, "p", "", "Glob patterns (comma-separated), e.g., \"*.py,*.txt\"")
-// 	flag.StringVar(&config.Output, "o", "", "Output file path (required)")
-// 	flag.StringVar(&excludesStr, "e", "", "Exclude patterns (comma-separated)")
-// 	flag.StringVar(&config.Root, "root", ".", "Root directory to search")
-// 	flag.BoolVar(&config.NoSeparator, "no-separator", false, "Don't add separators between files")
-// 	flag.StringVar(&config.Encoding, "encoding", "utf-8", "Output file encoding (default: utf-8)")
-// 	flag.StringVar(&config.NewlineType, "newline", "lf", "Newline type: lf, crlf, cr")
-// 	flag.Int64Var(&config.MaxSize, "max-size", MAX_FILE_SIZE, "Maximum file size in bytes")
-// 	flag.BoolVar(&config.IgnoreGitignore, "ignore-gitignore", false, "Don't read .gitignore")
-// 	flag.BoolVar(&config.DryRun, "dry-run", false, "Preview without writing")
-// 	flag.BoolVar(&config.Verbose, "v", false, "Verbose output")
-// 	flag.BoolVar(&config.Debug, "debug", false, "Debug mode (implies verbose)")
-
-// 	version := flag.Bool("version", false, "Show version")
-
-// 	flag.Usage = func() {
-// 		fmt.Fprintf(os.Stderr, "combine v%s - Combine multiple files matching patterns\n\n", VERSION)
-// 		fmt.Fprintf(os.Stderr, "Usage:\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -o OUTPUT [OPTIONS] [PATTERN/FILE...]\n\n")
-// 		fmt.Fprintf(os.Stderr, "Examples:\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -o combined.txt *.py *.txt\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -o golang.txt -p \"*.go,*.mod\"\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -o output.cpp src/**/*.cpp\n")
-// 		fmt.Fprintf(os.Stderr, "  combine -o docs.txt \"*.md\" README LICENSE\n\n")
-// 		fmt.Fprintf(os.Stderr, "Options:\n")
-// 		flag.PrintDefaults()
-// 	}
-
-// 	flag.Parse()
-
-// 	if *version {
-// 		fmt.Printf("combine v%s\n", VERSION)
-// 		os.Exit(0)
-// 	}
-
-// 	// Get positional args (after flags)
-// 	positional := flag.Args()
-// 	if patternsStr != "" {
-// 		parts := strings.Split(patternsStr, ",")
-// 		for _, p := range parts {
-// 			p = strings.TrimSpace(p)
-// 			if p != "" {
-// 				config.Patterns = append(config.Patterns, p)
-// 			}
-// 		}
-// 	}
-
-// 	// Add positional args (e.g., *.md, *.py, README.md)
-// 	for _, arg := range positional {
-// 		arg = strings.TrimSpace(arg)
-// 		if arg != "" {
-// 			config.Patterns = append(config.Patterns, arg)
-// 		}
-// 	}
-
-// 	if config.Output == "" {
-// 		fmt.Fprintln(os.Stderr, "Error: -o OUTPUT is required")
-// 		flag.Usage()
-// 		os.Exit(1)
-// 	}
-
-// 	if len(config.Patterns) == 0 {
-// 		fmt.Fprintln(os.Stderr, "Error: no file patterns provided")
-// 		flag.Usage()
-// 		os.Exit(1)
-// 	}
-
-// 	if excludesStr != "" {
-// 		parts := strings.Split(excludesStr, ",")
-// 		for _, p := range parts {
-// 			p = strings.TrimSpace(p)
-// 			if p != "" {
-// 				config.Excludes = append(config.Excludes, p)
-// 			}
-// 		}
-// 	}
-
-// 	if config.Debug {
-// 		config.Verbose = true
-// 	}
-
-// 	return config
-// }
-
-// =====================================================================
-// func parseFlags() *Config {
-// 	if len(os.Args) == 1 {
-// 		fmt.Fprintln(os.Stderr, "Error: No arguments provided.")
-// 		showUsage()
-// 		os.Exit(1)
-// 	}
-
-// 	config := &Config{
-// 		Root:        ".",
-// 		Encoding:    "utf-8",
-// 		NewlineType: "lf",
-// 		MaxSize:     MAX_FILE_SIZE,
-// 	}
-
-// 	outputFile := ""
-// 	excludesStr := ""
-// 	var patterns []string
-
-// 	// Scan all args to find flags anywhere
-// 	for i := 1; i < len(os.Args); i++ {
-// 		arg := os.Args[i]
-// 		switch arg {
-// 		case "-o", "--output":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: -o requires a filename")
-// 				os.Exit(1)
-// 			}
-// 			outputFile = os.Args[i+1]
-// 			i++ // skip next arg
-// 		case "-e", "--exclude":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: -e requires patterns")
-// 				os.Exit(1)
-// 			}
-// 			excludesStr = os.Args[i+1]
-// 			i++
-// 		case "--root":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: --root requires a path")
-// 				os.Exit(1)
-// 			}
-// 			config.Root = os.Args[i+1]
-// 			i++
-// 		case "--encoding":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: --encoding requires a value")
-// 				os.Exit(1)
-// 			}
-// 			config.Encoding = os.Args[i+1]
-// 			i++
-// 		case "--newline":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: --newline requires a value")
-// 				os.Exit(1)
-// 			}
-// 			config.NewlineType = os.Args[i+1]
-// 			i++
-// 		case "--max-size":
-// 			if i+1 >= len(os.Args) {
-// 				fmt.Fprintln(os.Stderr, "Error: --max-size requires a value")
-// 				os.Exit(1)
-// 			}
-// 			val, err := strconv.ParseInt(os.Args[i+1], 10, 64)
-// 			if err != nil {
-// 				fmt.Fprintf(os.Stderr, "Error: invalid --max-size value: %v\n", err)
-// 				os.Exit(1)
-// 			}
-// 			config.MaxSize = val
-// 			i++
-// 		case "--ignore-gitignore":
-// 			config.IgnoreGitignore = true
-// 		case "--dry-run":
-// 			config.DryRun = true
-// 		case "-v", "--verbose":
-// 			config.Verbose = true
-// 		case "--debug":
-// 			config.Debug = true
-// 			config.Verbose = true
-// 		case "--no-separator":
-// 			config.NoSeparator = true
-// 		case "--version", "-V":
-// 			fmt.Printf("combine v%s\n", VERSION)
-// 			os.Exit(0)
-// 		case "-h", "--help":
-// 			showUsage()
-// 			os.Exit(0)
-// 		default:
-// 			// It's a pattern/file
-// 			patterns = append(patterns, arg)
-// 		}
-// 	}
-
-// 	// Assign after parsing
-// 	config.Output = outputFile
-// 	config.Patterns = patterns
-
-// 	// Parse excludes
-// 	if excludesStr != "" {
-// 		for _, p := range strings.Split(excludesStr, ",") {
-// 			p = strings.TrimSpace(p)
-// 			if p != "" {
-// 				config.Excludes = append(config.Excludes, p)
-// 			}
-// 		}
-// 	}
-
-// 	// Validation
-// 	if config.Output == "" {
-// 		fmt.Fprintln(os.Stderr, "Error: -o OUTPUT is required")
-// 		showUsage()
-// 		os.Exit(1)
-// 	}
-// 	if len(config.Patterns) == 0 {
-// 		fmt.Fprintln(os.Stderr, "Error: no file patterns provided")
-// 		showUsage()
-// 		os.Exit(1)
-// 	}
-
-// 	return config
-// }
+// serve answers every HTTP request with a freshly combined output
+func serve(config *combiner.Options) int {
+	fmt.Printf("Serving combined output on http://%s/ (Ctrl+C to stop)\n", serveAddr)
+	if err := http.ListenAndServe(serveAddr, combiner.New(config)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return 0
+}
 
-// func showUsage() {
-// 	fmt.Fprintf(os.Stderr, "combine v%s - Combine multiple files matching patterns\n\n", VERSION)
-// 	fmt.Fprintf(os.Stderr, "Usage:\n")
-// 	fmt.Fprintf(os.Stderr, "  combine [PATTERN/FILE...] -o OUTPUT [OPTIONS]\n\n")
-// 	fmt.Fprintf(os.Stderr, "Examples:\n")
-// 	fmt.Fprintf(os.Stderr, "  combine *.py *.txt -o combined.txt\n")
-// 	fmt.Fprintf(os.Stderr, "  combine README.md LICENSE -o docs.txt\n")
-// 	fmt.Fprintf(os.Stderr, "  combine -p \"*.go,*.mod\" -o golang.txt\n\n")
-// 	fmt.Fprintf(os.Stderr, "Options:\n")
-// 	fmt.Fprintf(os.Stderr, "  -o, --output FILE        Output file path (required)\n")
-// 	fmt.Fprintf(os.Stderr, "  -e, --exclude PATTERNS   Exclude patterns (comma-separated)\n")
-// 	fmt.Fprintf(os.Stderr, "  --root DIR               Root directory (default: .)\n")
-// 	fmt.Fprintf(os.Stderr, "  --no-separator           Don't add separators between files\n")
-// 	fmt.Fprintf(os.Stderr, "  --encoding ENC           Output encoding (default: utf-8)\n")
-// 	fmt.Fprintf(os.Stderr, "  --newline TYPE           Newline: lf, crlf, cr (default: lf)\n")
-// 	fmt.Fprintf(os.Stderr, "  --max-size BYTES         Max file size (default: 100MB)\n")
-// 	fmt.Fprintf(os.Stderr, "  --ignore-gitignore       Don't read .gitignore\n")
-// 	fmt.Fprintf(os.Stderr, "  --dry-run                Preview without writing\n")
-// 	fmt.Fprintf(os.Stderr, "  -v, --verbose            Verbose output\n")
-// 	fmt.Fprintf(os.Stderr, "  --debug                  Debug mode\n")
-// 	fmt.Fprintf(os.Stderr, "  --version                Show version\n")
-// 	fmt.Fprintf(os.Stderr, "  -h, --help               Show this help\n")
-// }
-// =====================================================================
+// exit reports a failed run on stderr and exits with its exit code
+func exit(err error) {
+	code := 1
+	var exitErr *combiner.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.Code
+	}
+	if exitErr == nil || exitErr.Err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(code)
+}
 
-func parseFlags() *Config {
-	args := os.
-	Args[1:]
-	if len(args) == 0 {
+func parseFlags(command string, args []string) *combiner.Options {
+	// Settings from .combine.yaml / combine.toml are parsed first, so the
+	// flags given on the command line override them
+	args = mustNormalizeArgs(args)
+	var project *projectSettings
+	var projectFile string
+	if command != "split" {
+		var err error
+		project, projectFile, err = loadProjectConfig(args)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if len(args) == 0 && command == "run" && project == nil {
 		fmt.Fprintln(os.Stderr, "Error: No arguments provided")
 		printUsage()
 		os.Exit(1)
 	}
-
-	config := &Config{
-		Root:        ".",
-		Encoding:    "utf-8",
-		NewlineType: "lf",
-		MaxSize:     MAX_FILE_SIZE,
+	var projectArgs int
+	if project != nil {
+		if command == "serve" {
+			project.Output = ""
+		}
+		projectFlags, err := project.args()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", projectFile, err)
+			os.Exit(1)
+		}
+		projectFlags = mustNormalizeArgs(projectFlags)
+		projectArgs = len(projectFlags)
+		args = append(projectFlags, args...)
 	}
 
-	var patternsFromP string
-	var excludesFromE string
+	config := combiner.DefaultOptions()
+
+	// -p and -e may be repeated; each value is a comma-separated list
+	var patternsFromP []string
+	var excludesFromE []string
+	var cliExcludes bool
+	var roots []string
+	var cliRoots bool
+	var formatSet bool
 	var i int
 
+	// value consumes the argument following a flag that requires one
+	value := func(name string) string {
+		if i+1 >= len(args) {
+			fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", name)
+			os.Exit(1)
+		}
+		i++
+		return args[i]
+	}
+
 	for i = 0; i < len(args); i++ {
 		arg := args[i]
+		if name, on, ok := switchArg(arg); ok {
+			*switchFlags[name](config) = on
+			continue
+		}
 		switch arg {
 		case "-o", "--output":
 			if i+1 >= len(args) {
@@ -608,24 +249,45 @@ func parseFlags() *Config {
 				fmt.Fprintln(os.Stderr, "Error: -p requires a pattern string")
 				os.Exit(1)
 			}
-			patternsFromP = args[i+1]
+			patternsFromP = append(patternsFromP, args[i+1])
 			i++
 		case "-e", "--exclude":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "Error: -e requires patterns")
 				os.Exit(1)
 			}
-			excludesFromE = args[i+1]
+			// -e on the command line replaces the config file's excludes
+			if i >= projectArgs && !cliExcludes {
+				excludesFromE, cliExcludes = nil, true
+			}
+			excludesFromE = append(excludesFromE, args[i+1])
 			i++
-		case "-r", "--recursive":
-			config.Recursive = true
 		case "--root":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "Error: --root requires a path")
 				os.Exit(1)
 			}
-			config.Root = args[i+1]
+			// --root on the command line replaces the config file's root
+			if i >= projectArgs && !cliRoots {
+				roots, cliRoots = nil, true
+			}
+			for _, root := range strings.Split(args[i+1], ",") {
+				if root = strings.TrimSpace(root); root != "" {
+					roots = append(roots, root)
+				}
+			}
 			i++
+		case "--hidden":
+			config.SkipHidden = false
+		case "--hidden=false", "--no-hidden":
+			config.SkipHidden = true
+		case "--max-depth":
+			n, err := strconv.Atoi(value("--max-depth"))
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-depth: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MaxDepth = n
 		case "--max-size":
 			if i+1 >= len(args) {
 				fmt.Fprintln(os.Stderr, "Error: --max-size requires a number")
@@ -638,20 +300,327 @@ func parseFlags() *Config {
 			}
 			config.MaxSize = val
 			i++
-		case "--no-separator":
-			config.NoSeparator = true
-		case "--ignore-gitignore":
-			config.IgnoreGitignore = true
-		case "--dry-run":
-			config.DryRun = true
-		case "--verbose":
-			config.Verbose = true
+		case "--glob-engine":
+			config.GlobEngine = strings.ToLower(value("--glob-engine"))
+			if !combiner.ValidGlobEngine(config.GlobEngine) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --glob-engine: %s (use standard or doublestar)\n", config.GlobEngine)
+				os.Exit(1)
+			}
+		case "--format":
+			formatSet = true
+			config.Format = strings.ToLower(value("--format"))
+			if !combiner.ValidFormat(config.Format) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --format: %s (use text, csv, markdown, json or xml)\n", config.Format)
+				os.Exit(1)
+			}
+		case "--bom":
+			config.BOM = strings.ToLower(value("--bom"))
+			if config.BOM != "auto" && config.BOM != "always" && config.BOM != "never" {
+				fmt.Fprintf(os.Stderr, "Error: invalid --bom: %s (use auto, always or never)\n", config.BOM)
+				os.Exit(1)
+			}
+		case "--newline":
+			config.NewlineType = strings.ToLower(value("--newline"))
+			switch config.NewlineType {
+			case "lf", "crlf", "cr", "auto":
+			default:
+				fmt.Fprintf(os.Stderr, "Error: invalid --newline: %s (use lf, crlf, cr or auto)\n", config.NewlineType)
+				os.Exit(1)
+			}
+		case "--jobs", "-j":
+			val, err := strconv.Atoi(value("--jobs"))
+			if err != nil || val < 1 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --jobs: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.Jobs = val
+		case "--max-memory":
+			val, err := combiner.ParseSize(value("--max-memory"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-memory: %v\n", err)
+				os.Exit(1)
+			}
+			config.MaxMemory = val
+		case "--max-files":
+			val, err := strconv.Atoi(value("--max-files"))
+			if err != nil || val < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-files: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MaxFiles = val
+		case "--min-files":
+			val, err := strconv.Atoi(value("--min-files"))
+			if err != nil || val < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --min-files: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MinFiles = val
+		case "--largest-first":
+			config.LargestFirst = true
+			config.Sort = combiner.SORT_SIZE
+			config.Reverse = true
+		case "--sort":
+			config.Sort = strings.ToLower(value("--sort"))
+			if !combiner.ValidSort(config.Sort) {
+				fmt.Fprintf(os.Stderr, "Error: invalid --sort: %s (use path, size, mtime or pattern)\n", config.Sort)
+				os.Exit(1)
+			}
+		case "--code-only", "--docs-only":
+			preset := strings.TrimSuffix(strings.TrimPrefix(arg, "--"), "-only")
+			if config.Preset != "" && config.Preset != preset {
+				fmt.Fprintln(os.Stderr, "Error: --code-only and --docs-only are mutually exclusive")
+				os.Exit(1)
+			}
+			config.Preset = preset
+		case "--normalize-indent":
+			config.NormalizeIndent = strings.ToLower(value("--normalize-indent"))
+			if config.NormalizeIndent != "auto" {
+				fmt.Fprintf(os.Stderr, "Error: invalid --normalize-indent: %s (use auto)\n", config.NormalizeIndent)
+				os.Exit(1)
+			}
+		case "--target-indent":
+			style, err := combiner.ParseIndentStyle(value("--target-indent"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			config.TargetIndent = &style
+		case "--resume":
+			config.Resume = true
+			config.EmbedManifest = true
+		case "--unpack":
+			config.Unpack = value("--unpack")
+		case "--encoding":
+			config.Encoding = value("--encoding")
+			if err := combiner.ValidEncoding(config.Encoding); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		case "--collapse-path-depth":
+			val, err := strconv.Atoi(value("--collapse-path-depth"))
+			if err != nil || val < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --collapse-path-depth: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.CollapseDepth = val
+		case "--minified-line-length":
+			val, err := strconv.Atoi(value("--minified-line-length"))
+			if err != nil || val <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --minified-line-length: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MinifiedLineLength = val
+		case "--min-size":
+			val, err := combiner.ParseSize(value("--min-size"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --min-size: %v\n", err)
+				os.Exit(1)
+			}
+			config.MinSize = val
+		case "--minified-min-size":
+			val, err := combiner.ParseSize(value("--minified-min-size"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --minified-min-size: %v\n", err)
+				os.Exit(1)
+			}
+			config.MinifiedMinSize = val
+		case "--language":
+			for _, l := range strings.Split(value("--language"), ",") {
+				if l = combiner.NormalizeLanguage(l); l != "" {
+					config.Languages = append(config.Languages, l)
+				}
+			}
+		case "--exclude-language":
+			for _, l := range strings.Split(value("--exclude-language"), ",") {
+				if l = combiner.NormalizeLanguage(l); l != "" {
+					config.ExcludeLanguages = append(config.ExcludeLanguages, l)
+				}
+			}
+		case "--title":
+			config.Title = value("--title")
+		case "--header":
+			config.Header = value("--header")
+		case "--footer":
+			config.Footer = value("--footer")
+		case "--header-file", "--footer-file", "--separator-template-file":
+			data, err := os.ReadFile(value(arg))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: Cannot read %s: %v\n", arg, err)
+				os.Exit(1)
+			}
+			switch arg {
+			case "--header-file":
+				config.Header = string(data)
+			case "--footer-file":
+				config.Footer = string(data)
+			default:
+				config.SeparatorTemplate = string(data)
+			}
+		case "--max-lines-per-ext":
+			if config.MaxLinesPerExt == nil {
+				config.MaxLinesPerExt = make(map[string]int)
+			}
+			if err := combiner.ParseExtensionLimits(value("--max-lines-per-ext"), config.MaxLinesPerExt); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --max-lines-per-ext: %v\n", err)
+				os.Exit(1)
+			}
+		case "--max-tokens":
+			n, err := strconv.ParseInt(value("--max-tokens"), 10, 64)
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-tokens: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MaxTokens = n
+		case "--chunk-tokens":
+			n, err := strconv.ParseInt(value("--chunk-tokens"), 10, 64)
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --chunk-tokens: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.ChunkTokens = n
+		case "--token-estimator":
+			config.TokenEstimator = strings.ToLower(value("--token-estimator"))
+			if combiner.TokenEstimators[config.TokenEstimator] == nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --token-estimator: %s (use bytes, words, cl100k_base or o200k_base)\n", config.TokenEstimator)
+				os.Exit(1)
+			}
+		case "--max-total-size":
+			size, err := combiner.ParseSize(value("--max-total-size"))
+			if err != nil || size <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --max-total-size: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.MaxTotalSize = size
+		case "--split-size":
+			size, err := combiner.ParseSize(value("--split-size"))
+			if err != nil || size <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --split-size: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.SplitSize = size
+		case "--split-lines":
+			n, err := strconv.ParseInt(value("--split-lines"), 10, 64)
+			if err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --split-lines: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.SplitLines = n
+		case "--part-name":
+			config.PartName = value("--part-name")
+		case "--exclude-name":
+			for _, name := range strings.Split(value("--exclude-name"), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					config.ExcludeNames = append(config.ExcludeNames, name)
+				}
+			}
+		case "--ere", "--ee":
+			expr := value(arg)
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s regex %q: %v\n", arg, expr, err)
+				os.Exit(1)
+			}
+			config.ExcludeRegexps = append(config.ExcludeRegexps, re)
+		case "--pe":
+			expr := value("--pe")
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --pe regex %q: %v\n", expr, err)
+				os.Exit(1)
+			}
+			config.IncludeRegexps = append(config.IncludeRegexps, re)
+		case "--grep", "--grep-not":
+			expr := value(arg)
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid %s regex %q: %v\n", arg, expr, err)
+				os.Exit(1)
+			}
+			if arg == "--grep" {
+				config.GrepRegexps = append(config.GrepRegexps, re)
+			} else {
+				config.GrepNotRegexps = append(config.GrepNotRegexps, re)
+			}
+		case "--watch-debounce":
+			d, err := time.ParseDuration(value("--watch-debounce"))
+			if err != nil || d < 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid --watch-debounce: %s (e.g. 300ms, 2s)\n", args[i])
+				os.Exit(1)
+			}
+			config.WatchDebounce = d
+		case "-q", "--quiet":
+			quiet = true
+		case "--addr":
+			serveAddr = value("--addr")
+		case "--sign-key":
+			config.SignKey = value("--sign-key")
+		case "--verify-signature":
+			config.VerifySignature = value("--verify-signature")
+		case "--manifest":
+			config.Manifest = value("--manifest")
+		case "--include-binary":
+			config.IncludeBinary = true
+		case "--clone-min-lines":
+			n, err := strconv.Atoi(value("--clone-min-lines"))
+			if err != nil || n < 2 {
+				fmt.Fprintf(os.Stderr, "Error: --clone-min-lines must be at least 2: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.CloneMinLines = n
+		case "--gzip-level":
+			level, err := strconv.Atoi(value("--gzip-level"))
+			if err != nil || level < gzip.NoCompression || level > gzip.BestCompression {
+				fmt.Fprintf(os.Stderr, "Error: --gzip-level must be between 0 and 9: %s\n", args[i])
+				os.Exit(1)
+			}
+			config.Gzip = true
+			config.GzipLevel = level
+		case "--files-from":
+			config.FilesFrom = value("--files-from")
+		case "--changed-since":
+			config.ChangedSince = value("--changed-since")
+		case "--newer-than", "--older-than":
+			t, err := combiner.ParseTimeBound(value(arg), time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s takes a duration (48h, 14d) or a date (2024-01-01): %s\n", arg, args[i])
+				os.Exit(1)
+			}
+			if arg == "--newer-than" {
+				config.NewerThan = t
+			} else {
+				config.OlderThan = t
+			}
+		case "--separator-format":
+			config.SeparatorFormat = combiner.UnescapeTemplate(value("--separator-format"))
+		case "--separator-meta":
+			for _, field := range strings.Split(value("--separator-meta"), ",") {
+				field = strings.ToLower(strings.TrimSpace(field))
+				if field == "" {
+					continue
+				}
+				if !combiner.ValidSeparatorMeta(field) {
+					fmt.Fprintf(os.Stderr, "Error: invalid --separator-meta field: %s (use size, lines, sha256, mtime or git)\n", field)
+					os.Exit(1)
+				}
+				config.SeparatorMeta = append(config.SeparatorMeta, field)
+			}
+		case "--separator-template":
+			config.SeparatorTemplate = combiner.UnescapeTemplate(value("--separator-template"))
+		case "--content-prefix":
+			config.ContentPrefix = combiner.UnescapeTemplate(value("--content-prefix"))
+		case "--content-suffix":
+			config.ContentSuffix = combiner.UnescapeTemplate(value("--content-suffix"))
+		case "--path-style":
+			config.PathStyle = strings.ToLower(value("--path-style"))
+			if config.PathStyle != "relative" && config.PathStyle != "absolute" {
+				fmt.Fprintf(os.Stderr, "Error: invalid --path-style: %s (use relative or absolute)\n", config.PathStyle)
+				os.Exit(1)
+			}
+		case "--pipe-to":
+			config.PipeTo = value("--pipe-to")
 		case "--debug":
 			config.Debug = true
 			config.Verbose = true
-		// case "--version", "-V":
-		// 	fmt.Printf("combine v%s\n", VERSION)
-		// 	os.Exit(0)
 		case "-v", "--version":
 			fmt.Printf("combine version %s\n", Version)
 			fmt.Printf("Author: %s\n", Author)
@@ -660,724 +629,275 @@ func parseFlags() *Config {
 		case "-h", "--help":
 			printUsage()
 			os.Exit(0)
+		case "--config", "--profile":
+			// Already applied by loadProjectConfig
+			value(arg)
+		case "--no-config":
 		default:
-			// Assume it's a file pattern
-			config.Patterns = append(config.Patterns, arg)
-		}
-	}
-
-	// Add patterns from -p
-	if patternsFromP != "" {
-		for _, p := range strings.Split(patternsFromP, ",") {
-			p = strings.TrimSpace(p)
-			if p != "" {
-				config.Patterns = append(config.Patterns, p)
-			}
-		}
-	}
-
-	// Parse excludes
-	if excludesFromE != "" {
-		for _, p := range strings.Split(excludesFromE, ",") {
-			p = strings.TrimSpace(p)
-			if p != "" {
-				config.Excludes = append(config.Excludes, p)
+			if i < projectArgs {
+				fmt.Fprintf(os.Stderr, "Error: unknown option %s in %s\n", args[i], projectFile)
+				os.Exit(1)
 			}
+			// Assume it's a file pattern
+			config.Patterns = append(config.Patterns, args[i])
 		}
 	}
 
-	// Final validation
-	if config.Output == "" {
-		fmt.Fprintln(os.Stderr, "Error: -o OUTPUT is required")
-		printUsage()
-		os.Exit(1)
-	}
-	if len(config.Patterns) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: no file patterns provided")
-		printUsage()
-		os.Exit(1)
-	}
-
-	return config
-}
-
-func printUsage() {
-	VERSION := readVersion()
-	fmt.Fprintf(os.Stderr, "combine v%s - Combine files matching patterns\n\n", VERSION)
-	fmt.Fprintf(os.Stderr, "Usage:\n")
-	fmt.Fprintf(os.Stderr, "  combine [FILES/PATTERNS...] -o OUTPUT [OPTIONS]\n")
-	fmt.Fprintf(os.Stderr, "  combine -p \"*.md,*.py\" -o OUTPUT [OPTIONS]\n\n")
-	fmt.Fprintf(os.Stderr, "Examples:\n")
-	fmt.Fprintf(os.Stderr, "  combine *.md *.py -o dotenv.txt\n")
-	fmt.Fprintf(os.Stderr, "  combine README.md setup.py -o out.txt\n")
-	fmt.Fprintf(os.Stderr, "  combine -p \"*.go,go.mod\" -o golang.txt\n\n")
-	fmt.Fprintf(os.Stderr, "Options:\n")
-	fmt.Fprintf(os.Stderr, "  -o FILE                 Output file (required)\n")
-	fmt.Fprintf(os.Stderr, "  -p \"pat1,pat2\"          Patterns (comma-separated)\n")
-	fmt.Fprintf(os.Stderr, "  -e \"pat1,pat2\"          Exclude patterns\n")
-	fmt.Fprintf(os.Stderr, "  -r, --recursive         Search recursively in subdirectories\n")
-	fmt.Fprintf(os.Stderr, "  --root DIR              Search root (default: .)\n")
-	fmt.Fprintf(os.Stderr, "  --max-size BYTES        Max file size (default: 100MB)\n")
-	fmt.Fprintf(os.Stderr, "  --no-separator          Skip file separators\n")
-	fmt.Fprintf(os.Stderr, "  --ignore-gitignore      Skip .gitignore\n")
-	fmt.Fprintf(os.Stderr, "  --dry-run               Show what would be combined\n")
-	fmt.Fprintf(os.Stderr, "  --verbose               Verbose output\n")
-	fmt.Fprintf(os.Stderr, "  --debug                 Debug mode\n")
-	fmt.Fprintf(os.Stderr, "  -v --version            Show version\n")
-	fmt.Fprintf(os.Stderr, "  -h                      Show help\n")
-}
-
-func loadGitignore(root string, verbose bool) []string {
-	patterns := []string{}
-	gitignorePath := filepath.Join(root, ".gitignore")
-
-	file, err := os.Open(gitignorePath)
-	if err != nil {
-		return patterns
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		patterns = append(patterns, line)
-	}
-
-	if verbose {
-		fmt.Printf("Loaded %d patterns from .gitignore\n", len(patterns))
-	}
-
-	return patterns
-}
-
-func matchExcluded(path, root string, patterns []string) bool {
-	relPath, err := filepath.Rel(root, path)
-	if err != nil {
-		return false
+	// Several roots are searched one by one below their common parent
+	switch {
+	case len(roots) == 1:
+		config.Root = roots[0]
+	case len(roots) > 1:
+		config.Root = roots[0]
+		config.Roots = roots
 	}
 
-	relPath = filepath.ToSlash(relPath)
-
-	for _, pattern := range patterns {
-		pattern = strings.TrimSpace(pattern)
-		
-		// Direct substring match
-		if strings.Contains(relPath, pattern) {
-			return true
+	// The subcommand stands in for the flag it replaces
+	switch command {
+	case "split":
+		if len(config.Patterns) != 1 {
+			fmt.Fprintln(os.Stderr, "Error: split needs exactly one combined file")
+			os.Exit(1)
 		}
-
-		// Pattern matching
-		matched, _ := filepath.Match(pattern, filepath.Base(relPath))
-		if matched {
-			return true
+		config.Unpack = config.Patterns[0]
+		config.Patterns = nil
+		// -o names the target directory; --root works as with --unpack
+		if config.Output != "" {
+			config.Root = config.Output
+			config.Roots = nil
+			config.Output = ""
 		}
-
-		// Check parent directories
-		parts := strings.Split(relPath, "/")
-		for _, part := range parts {
-			if part == strings.TrimSuffix(pattern, "/") {
-				return true
-			}
+	case "list":
+		config.List = true
+	case "watch":
+		config.Watch = true
+	case "serve":
+		if config.Output != "" {
+			fmt.Fprintln(os.Stderr, "Error: serve sends the output as the HTTP response; drop -o")
+			os.Exit(1)
 		}
+		config.Output = "-"
 	}
 
-	return false
-}
-
-func isBinaryFile(path string) bool {
-	ext := strings.ToLower(filepath.Ext(path))
-
-	// Check binary extensions
-	if binaryExtensions[ext] {
-		return true
+	// The config file's patterns apply when the command line names none
+	if project != nil && len(config.Patterns) == 0 && len(patternsFromP) == 0 {
+		config.Patterns = append(config.Patterns, project.Patterns...)
 	}
 
-	// Check text extensions
-	if textExtensions[ext] {
-		return false
-	}
-
-	// Check content
-	file, err := os.Open(path)
-	if err != nil {
-		return true
-	}
-	defer file.Close()
-
-	buffer := make([]byte, BUFFER_SIZE)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return true
+	// Add patterns from -p
+	for _, list := range patternsFromP {
+		config.Patterns = append(config.Patterns, combiner.SplitPatterns(list)...)
 	}
 
-	buffer = buffer[:n]
-
-	// Empty file is text
-	if n == 0 {
-		return false
+	// Parse excludes
+	for _, list := range excludesFromE {
+		config.Excludes = append(config.Excludes, combiner.SplitPatterns(list)...)
 	}
 
-	// Check for null bytes
-	if bytes.Contains(buffer, []byte{0}) {
-		return true
+	if config.EnvExpand {
+		combiner.ExpandConfigEnv(config)
 	}
 
-	// Check ratio of non-printable characters
-	nonPrintable := 0
-	for _, b := range buffer {
-		if b < 32 && b != 9 && b != 10 && b != 13 {
-			nonPrintable++
-		}
+	// Without -format, the output's extension decides (bundle.csv -> csv)
+	if !formatSet && config.Output != "" {
+		config.Format = combiner.FormatForOutput(config.Output)
 	}
 
-	ratio := float64(nonPrintable) / float64(len(buffer))
-	return ratio > 0.3
-}
-
-// func findFiles(root string, patterns []string, excludes []string, maxSize int64, verbose bool) ([]string, []FileInfo) {
-// 	allFiles := make(map[string]bool)
-// 	var skipped []FileInfo
-
-// 	// Collect files from all patterns
-// 	for _, pattern := range patterns {
-// 		matches, err := filepath.Glob(filepath.Join(root, pattern))
-// 		if err == nil {
-// 			for _, match := range matches {
-// 				allFiles[match] = true
-// 			}
-// 		}
-
-// 		// Also support recursive patterns
-// 		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-// 			if err != nil {
-// 				return nil
-// 			}
-// 			if info.IsDir() {
-// 				return nil
-// 			}
-
-// 			matched, _ := filepath.Match(pattern, filepath.Base(path))
-// 			if matched {
-// 				allFiles[path] = true
-// 			}
-
-// 			return nil
-// 		})
-// 	}
-
-// 	// Convert to sorted slice
-// 	var files []string
-// 	for file := range allFiles {
-// 		files = append(files, file)
-// 	}
-// 	sort.Strings(files)
-
-// 	// Filter and validate
-// 	var results []string
-// 	for _, file := range files {
-// 		info, err := os.Stat(file)
-// 		if err != nil {
-// 			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Cannot stat: %v", err)})
-// 			continue
-// 		}
-
-// 		if !info.Mode().IsRegular() {
-// 			continue
-// 		}
-
-// 		// Check exclusions
-// 		if matchExcluded(file, root, excludes) {
-// 			skipped = append(skipped, FileInfo{file, "Matched exclusion pattern"})
-// 			continue
-// 		}
-
-// 		// Check file size
-// 		if info.Size() > maxSize {
-// 			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Too large (%.1f MB)", float64(info.Size())/1024/1024)})
-// 			continue
-// 		}
-
-// 		// Check if binary
-// 		if isBinaryFile(file) {
-// 			skipped = append(skipped, FileInfo{file, "Binary file"})
-// 			continue
-// 		}
-
-// 		results = append(results, file)
-// 	}
-
-// 	return results, skipped
-// }
-
-// func findFiles(root string, patterns []string, excludes []string, maxSize int64, verbose bool) ([]string, []FileInfo) {
-// 	allFiles := make(map[string]bool)
-// 	var skipped []FileInfo
-
-// 	for _, pattern := range patterns {
-// 		// Try glob relative to root
-// 		matches, err := filepath.Glob(filepath.Join(root, pattern))
-// 		if err != nil {
-// 			skipped = append(skipped, FileInfo{Path: pattern, Reason: fmt.Sprintf("Invalid pattern: %v", err)})
-// 			continue
-// 		}
-
-// 		if len(matches) == 0 {
-// 			// Maybe it's an absolute path or literal file?
-// 			if info, err := os.Stat(pattern); err == nil && !info.IsDir() {
-// 				allFiles[pattern] = true
-// 				continue
-// 			}
-// 			// Or relative to root
-// 			absPath := filepath.Join(root, pattern)
-// 			if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
-// 				allFiles[absPath] = true
-// 				continue
-// 			}
-// 			// Otherwise, skip (no match)
-// 			if verbose {
-// 				fmt.Printf("No matches for pattern: %s\n", pattern)
-// 			}
-// 			continue
-// 		}
-
-// 		for _, m := range matches {
-// 			allFiles[m] = true
-// 		}
-// 	}
-
-// 	files := make([]string, 0, len(allFiles))
-// 	for f := range allFiles {
-// 		files = append(files, f)
-// 	}
-// 	sort.Strings(files)
-
-// 	// Filter valid text files
-// 	var results []string
-// 	for _, file := range files {
-// 		info, err := os.Stat(file)
-// 		if err != nil {
-// 			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Stat error: %v", err)})
-// 			continue
-// 		}
-// 		if !info.Mode().IsRegular() {
-// 			continue
-// 		}
-// 		if matchExcluded(file, root, excludes) {
-// 			skipped = append(skipped, FileInfo{file, "Excluded"})
-// 			continue
-// 		}
-// 		if info.Size() > maxSize {
-// 			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Too large (%.1f MB)", float64(info.Size())/1024/1024)})
-// 			continue
-// 		}
-// 		if isBinaryFile(file) {
-// 			skipped = append(skipped, FileInfo{file, "Binary file"})
-// 			continue
-// 		}
-// 		results = append(results, file)
-// 	}
-
-// 	return results, skipped
-// }
-
-func findFiles(root string, patterns []string, excludes []string, maxSize int64, verbose bool, recursive bool) ([]string, []FileInfo) {
-	allFiles := make(map[string]bool)
-	var skipped []FileInfo
-
-	if recursive {
-		// Walk entire tree and match against base name for each pattern
-		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return nil
-			}
-			if !info.Mode().IsRegular() {
-				return nil
-			}
-
-			// Skip if excluded
-			if matchExcluded(path, root, excludes) {
-				return nil
-			}
-
-			// Check each pattern
-			base := filepath.Base(path)
-			for _, pat := range patterns {
-				// Handle absolute/literal files in patterns
-				if filepath.IsAbs(pat) || (len(pat) > 0 && pat[0] == '.') {
-					absPat, _ := filepath.Abs(pat)
-					if path == absPat {
-						allFiles[path] = true
-						return nil
-					}
-				}
-
-				// Try simple glob match on basename
-				if matched, _ := filepath.Match(pat, base); matched {
-					allFiles[path] = true
-					return nil
-				}
-			}
-			return nil
-		})
-		if err != nil && verbose {
-			fmt.Fprintf(os.Stderr, "Warning: error during recursive walk: %v\n", err)
-		}
-	} else {
-		// Non-recursive: original glob logic
-		for _, pattern := range patterns {
-			matches, err := filepath.Glob(filepath.Join(root, pattern))
-			if err != nil {
-				skipped = append(skipped, FileInfo{Path: pattern, Reason: fmt.Sprintf("Invalid pattern: %v", err)})
-				continue
-			}
-
-			if len(matches) == 0 {
-				// Check if it's a literal file
-				if info, err := os.Stat(pattern); err == nil && !info.IsDir() {
-					allFiles[pattern] = true
-					continue
-				}
-				absPath := filepath.Join(root, pattern)
-				if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
-					allFiles[absPath] = true
-					continue
-				}
-				if verbose {
-					fmt.Printf("No matches for pattern: %s\n", pattern)
-				}
-				continue
-			}
-
-			for _, m := range matches {
-				allFiles[m] = true
-			}
-		}
-	}
-
-	// Convert map to slice
-	files := make([]string, 0, len(allFiles))
-	for f := range allFiles {
-		files = append(files, f)
+	if err := combiner.ResolveRoots(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	sort.Strings(files)
 
-	// Final filtering: size, binary, etc.
-	var results []string
-	for _, file := range files {
-		info, err := os.Stat(file)
-		if err != nil {
-			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Stat error: %v", err)})
-			continue
-		}
-		if !info.Mode().IsRegular() {
-			continue
-		}
-		if matchExcluded(file, root, excludes) {
-			skipped = append(skipped, FileInfo{file, "Excluded"})
-			continue
-		}
-		if info.Size() > maxSize {
-			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Too large (%.1f MB)", float64(info.Size())/1024/1024)})
-			continue
-		}
-		if isBinaryFile(file) {
-			skipped = append(skipped, FileInfo{file, "Binary file"})
-			continue
-		}
-		results = append(results, file)
+	// Move re: patterns to the regexps, before their colons are taken for
+	// line ranges
+	if err := combiner.ExtractRegexPatterns(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	return results, skipped
-}
-
-func getCommentStyle(path string) CommentStyle {
-	ext := strings.ToLower(filepath.Ext(path))
-	if style, ok := commentStyles[ext]; ok {
-		return style
+	// Pull line ranges (main.go:10-40) off the patterns
+	if err := combiner.ExtractLineRanges(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
-	return CommentStyle{SingleLine: "#"}
-}
 
-func createSeparator(path, root string, index int, style CommentStyle) string {
-	relPath, _ := filepath.Rel(root, path)
-	relPath = filepath.ToSlash(relPath)
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-
-	separator := "\n"
-
-	if style.BlockStart != "" && style.BlockEnd != "" {
-		separator += fmt.Sprintf("%s\n FILE %d: %s\n Combined at: %s\n%s\n\n",
-			style.BlockStart, index, relPath, timestamp, style.BlockEnd)
-	} else if style.SingleLine != "" {
-		line := strings.Repeat("=", 70)
-		separator += fmt.Sprintf("%s %s\n%s FILE %d: %s\n%s Combined at: %s\n%s %s\n\n",
-			style.SingleLine, line,
-			style.SingleLine, index, relPath,
-			style.SingleLine, timestamp,
-			style.SingleLine, line)
-	} else {
-		line := strings.Repeat("=", 70)
-		separator += fmt.Sprintf("%s\n FILE %d: %s\n%s\n\n", line, index, relPath, line)
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
 
-	return separator
-}
-
-func getNewline(newlineType string) string {
-	switch strings.ToLower(newlineType) {
-	case "crlf", "\\r\\n":
-		return "\r\n"
-	case "cr", "\\r":
-		return "\r"
-	default:
-		return "\n"
+	// Unpacking needs neither patterns nor an output
+	if config.Unpack != "" {
+		return config
 	}
-}
-
-// func combineFiles(config *Config, files []string) int {
-// 	// Remove output file from input files
-// 	absOutput, _ := filepath.Abs(config.Output)
-// 	var filteredFiles []string
-// 	for _, file := range files {
-// 		absFile, _ := filepath.Abs(file)
-// 		if absFile != absOutput {
-// 			filteredFiles = append(filteredFiles, file)
-// 		}
-// 	}
-// 	files = filteredFiles
-
-// 	if len(files) == 0 {
-// 		fmt.Fprintln(os.Stderr, "Error: No files to combine after filtering")
-// 		return 1
-// 	}
-
-// 	// Create output directory if needed
-// 	outputDir := filepath.Dir(config.Output)
-// 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-// 		fmt.Fprintf(os.Stderr, "Error: Cannot create output directory: %v\n", err)
-// 		return 2
-// 	}
-
-// 	// Open output file
-// 	outFile, err := os.Create(config.Output)
-// 	if err != nil {
-// 		fmt.Fprintf(os.Stderr, "Error: Cannot create output file: %v\n", err)
-// 		return 2
-// 	}
-// 	defer outFile.Close()
-
-// 	writer := bufio.NewWriter(outFile)
-// 	defer writer.Flush()
-
-// 	newline := getNewline(config.NewlineType)
-// 	successCount := 0
-// 	errorCount := 0
-
-// 	for idx, filePath := range files {
-// 		if config.Verbose {
-// 			fmt.Printf("Processing [%d/%d]: %s\n", idx+1, len(files), filepath.Base(filePath))
-// 		}
-
-// 		// Read file
-// 		content, err := os.ReadFile(filePath)
-// 		if err != nil {
-// 			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
-// 			errorCount++
-// 			continue
-// 		}
-
-// 		// Add separator
-// 		if !config.NoSeparator {
-// 			style := getCommentStyle(filePath)
-// 			separator := createSeparator(filePath, config.Root, idx+1, style)
-// 			writer.WriteString(separator)
-// 		}
-
-// 		// Write content
-// 		writer.Write(content)
-
-// 		// Ensure newline at end
-// 		if len(content) > 0 && !bytes.HasSuffix(content, []byte(newline)) {
-// 			writer.WriteString(newline)
-// 		}
-
-// 		successCount++
-// 	}
-
-// 	fmt.Println("\n" + strings.Repeat("=", 70))
-// 	fmt.Printf("SUCCESS: Combined %d files into %s\n", successCount, config.Output)
-// 	if errorCount > 0 {
-// 		fmt.Printf("WARNING: %d files were skipped due to errors\n", errorCount)
-// 	}
-// 	fmt.Println(strings.Repeat("=", 70))
-
-// 	return 0
-// }
-
-func combineFiles(config *Config, files []string) int {
-	// 1. Removes the output file from the input list
-	absOutput, _ := filepath.Abs(config.Output)
-	var filteredFiles []string
-	for _, file := range files {
-		absFile, _ := filepath.Abs(file)
-		if absFile != absOutput {
-			filteredFiles = append(filteredFiles, file)
+	if config.VerifySignature != "" {
+		if config.SignKey == "" {
+			fmt.Fprintln(os.Stderr, "Error: --verify-signature requires --sign-key")
+			os.Exit(1)
 		}
+		return config
 	}
-	files = filteredFiles
-
-	if len(files) == 0 {
-		fmt.Fprintln(os.Stderr, "Error: No files to combine after filtering")
-		return 1
-	}
-
-	// 2. Process the content to combine
-	var combinedContent bytes.Buffer
-	newline := getNewline(config.NewlineType)
-	successCount := 0
-	errorCount := 0
-
-	for idx, filePath := range files {
-		if config.Verbose {
-			fmt.Printf("Processing [%d/%d]: %s\n", idx+1, len(files), filepath.Base(filePath))
-		}
-
-		// Read files
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
-			errorCount++
-			continue
-		}
-
-		// Add separator
-		if !config.NoSeparator {
-			style := getCommentStyle(filePath)
-			separator := createSeparator(filePath, config.Root, idx+1, style)
-			combinedContent.WriteString(separator)
-		}
-
-		// Write content
-		combinedContent.Write(content)
 
-		// Ensure newline at the end
-		if len(content) > 0 && !bytes.HasSuffix(content, []byte(newline)) {
-			combinedContent.WriteString(newline)
+	// Scanning looks at every file below the root
+	if config.ScanExtensions {
+		if len(config.Patterns) == 0 {
+			config.Patterns = []string{"*"}
 		}
-
-		successCount++
+		config.Recursive = true
+		return config
 	}
 
-	// 3. Determine the output destination (Clipboard or File)
-	outputIsClipboard := config.Output == "c"
-
-	if outputIsClipboard {
-		// Output to Clipboard (Assuming package 'clipboard' is available)
-		// Need to import: import "github.com/atotto/clipboard"
-		err := clipboard.WriteAll(combinedContent.String()) // Use string for clipboard
-		if err != nil {
-			fmt.Println("Failed to write content to clipboard!")
-			return 2
-		} else {
-			fmt.Println("Content has been written to clipboard!")
-		}
-	} else if config.Output != "" {
-		// Output ke File
-		
-		// Create an output directory if necessary
-		outputDir := filepath.Dir(config.Output)
-		if outputDir != "." { // Cek apakah ada direktori selain direktori saat ini
-			if err := os.MkdirAll(outputDir, 0755); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Cannot create output directory: %v\n", err)
-				return 2
-			}
-			// Maybe it doesn't need to be printed every time, but follows the original logic:
-			// fmt.Printf("%s created\n", outputDir)
-		}
-
-		// Open/Create output file
-		outFile, err := os.Create(config.Output)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Cannot create output file: %v\n", err)
-			return 2
-		}
-		defer outFile.Close()
-
-		writer := bufio.NewWriter(outFile)
-		defer writer.Flush()
-		
-		// Writes the entire combined contents to a file
-		if _, err := writer.Write(combinedContent.Bytes()); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to write combined content to file: %v\n", err)
-			return 2
-		}
-	} else {
-        // Case when config.Output is empty and not 'c'.
-		fmt.Fprintln(os.Stderr, "Error: Output target is not defined.")
-		return 2
+	// Final validation
+	if config.Output == "" && !config.List && config.PipeTo == "" {
+		fmt.Fprintln(os.Stderr, "Error: -o OUTPUT is required")
+		printUsage()
+		os.Exit(1)
 	}
-
-	// 4. Statistical Output
-	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Printf("SUCCESS: Combined %d files into %s\n", successCount, config.Output)
-	if errorCount > 0 {
-		fmt.Printf("WARNING: %d files were skipped due to errors\n", errorCount)
+	if len(config.Patterns) == 0 && len(config.IncludeRegexps) == 0 && config.FilesFrom == "" {
+		fmt.Fprintln(os.Stderr, "Error: no file patterns provided")
+		printUsage()
+		os.Exit(1)
 	}
-	fmt.Println(strings.Repeat("=", 70))
 
-	return 0
+	return config
 }
 
-func printSummary(config *Config, files []string, skipped []FileInfo) {
-	fmt.Println("\n" + strings.Repeat("=", 70))
-	fmt.Println("COMBINE FILES - SUMMARY")
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Printf("Root directory    : %s\n", config.Root)
-	fmt.Printf("Output file       : %s\n", config.Output)
-	fmt.Printf("Search patterns   : %s\n", strings.Join(config.Patterns, ", "))
-	fmt.Printf("Files found       : %d\n", len(files))
-	fmt.Printf("Files excluded    : %d\n", len(skipped))
-	if config.DryRun {
-		fmt.Printf("Mode    	          : DRY-RUN (no changes)\n")
-	} else {
-		fmt.Printf("Mode              : EXECUTION\n")
-	}
-	fmt.Println(strings.Repeat("=", 70))
-
-	if len(skipped) > 0 {
-		fmt.Println("\nEXCLUDED FILES (showing first 15):")
-		limit := len(skipped)
-		if limit > 15 {
-			limit = 15
-		}
-		for i := 0; i < limit; i++ {
-			relPath, _ := filepath.Rel(config.Root, skipped[i].Path)
-			fmt.Printf("  × %s\n", relPath)
-			fmt.Printf("    Reason: %s\n", skipped[i].Reason)
-		}
-		if len(skipped) > 15 {
-			fmt.Printf("  ... and %d more files\n\n", len(skipped)-15)
-		}
-	}
-
-	if config.DryRun && len(files) > 0 {
-		fmt.Println("\nFILES TO BE COMBINED (showing first 20):")
-		limit := len(files)
-		if limit > 20 {
-			limit = 20
-		}
-		for i := 0; i < limit; i++ {
-			relPath, _ := filepath.Rel(config.Root, files[i])
-			info, _ := os.Stat(files[i])
-			sizeKB := float64(info.Size()) / 1024
-			fmt.Printf("  ✓ %s (%.1f KB)\n", relPath, sizeKB)
-		}
-		if len(files) > 20 {
-			fmt.Printf("  ... and %d more files\n", len(files)-20)
-		}
-		fmt.Printf("\nTotal: %d files will be combined\n", len(files))
-	}
+func printUsage() {
+	VERSION := readVersion()
+	fmt.Fprintf(os.Stderr, "combine v%s - Combine files matching patterns\n\n", VERSION)
+	fmt.Fprintf(os.Stderr, "Usage:\n")
+	fmt.Fprintf(os.Stderr, "  combine [run] [FILES/PATTERNS...] -o OUTPUT [OPTIONS]\n")
+	fmt.Fprintf(os.Stderr, "  combine -p \"*.md,*.py\" -o OUTPUT [OPTIONS]\n")
+	fmt.Fprintf(os.Stderr, "  combine split COMBINED [-o DIR] [OPTIONS]\n")
+	fmt.Fprintf(os.Stderr, "  combine list [FILES/PATTERNS...] [OPTIONS]\n")
+	fmt.Fprintf(os.Stderr, "  combine watch [FILES/PATTERNS...] -o OUTPUT [OPTIONS]\n")
+	fmt.Fprintf(os.Stderr, "  combine serve [FILES/PATTERNS...] [--addr HOST:PORT] [OPTIONS]\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  run                     Combine the matching files (default, also without a command)\n")
+	fmt.Fprintf(os.Stderr, "  split                   Recreate the original files from a combined output (--unpack)\n")
+	fmt.Fprintf(os.Stderr, "  list                    Print the files that would be combined (--list)\n")
+	fmt.Fprintf(os.Stderr, "  watch                   Combine, then rebuild whenever a source changes (--watch)\n")
+	fmt.Fprintf(os.Stderr, "  serve                   Answer each HTTP GET with a freshly combined output\n\n")
+	fmt.Fprintf(os.Stderr, "Examples:\n")
+	fmt.Fprintf(os.Stderr, "  combine *.md *.py -o dotenv.txt\n")
+	fmt.Fprintf(os.Stderr, "  combine README.md setup.py -o out.txt\n")
+	fmt.Fprintf(os.Stderr, "  combine -p \"*.go,go.mod\" -o golang.txt\n")
+	fmt.Fprintf(os.Stderr, "  combine main.go:10-40 util.go:100- -o context.txt\n\n")
+	fmt.Fprintf(os.Stderr, "Options:\n")
+	fmt.Fprintf(os.Stderr, "  -o FILE                 Output file (required; - writes to stdout)\n")
+	fmt.Fprintf(os.Stderr, "  -q, --quiet             Print no summary or progress, only warnings and errors\n")
+	fmt.Fprintf(os.Stderr, "  --gzip                  Gzip-compress the output file (or stdout)\n")
+	fmt.Fprintf(os.Stderr, "  --gzip-level N          Gzip compression level, 0 (none) to 9 (best); implies --gzip\n")
+	fmt.Fprintf(os.Stderr, "  --append                Append to the output, continuing its FILE numbering\n")
+	fmt.Fprintf(os.Stderr, "  --pipe-to CMD           Stream the output into CMD's stdin instead of a file\n")
+	fmt.Fprintf(os.Stderr, "  -p \"pat1,pat2\"          Patterns (comma-separated, repeatable); !pat takes files back out of earlier matches\n")
+	fmt.Fprintf(os.Stderr, "  --config FILE           Read settings from FILE (default: .combine.yaml or combine.toml if present,\n")
+	fmt.Fprintf(os.Stderr, "                          limited to the options that shape the output; see README)\n")
+	fmt.Fprintf(os.Stderr, "  --profile NAME          Apply the named profile from the config file\n")
+	fmt.Fprintf(os.Stderr, "  --no-config             Ignore the config file in the current directory\n")
+	fmt.Fprintf(os.Stderr, "  --files-from FILE       Combine exactly the paths listed in FILE (- for stdin), in order; NUL-separated lists work too\n")
+	fmt.Fprintf(os.Stderr, "  --changed-since REF     Only combine files that differ between the git REF and the working tree\n")
+	fmt.Fprintf(os.Stderr, "  --newer-than WHEN       Only combine files modified after WHEN: a duration back (48h, 14d, 2w) or a date\n")
+	fmt.Fprintf(os.Stderr, "  --older-than WHEN       Only combine files modified before WHEN: a duration back (48h, 14d, 2w) or a date\n")
+	fmt.Fprintf(os.Stderr, "  --staged                Only combine files with changes staged in the git index (pre-commit hooks)\n")
+	fmt.Fprintf(os.Stderr, "  --git-tracked           Take the candidates from git ls-files instead of walking the tree\n")
+	fmt.Fprintf(os.Stderr, "  --include-untracked     With --changed-since or --git-tracked, also combine untracked (not ignored) files\n")
+	fmt.Fprintf(os.Stderr, "  -e \"pat1,pat2\"          Exclude patterns (comma-separated, repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-name NAME     Exclude files named exactly NAME anywhere (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --pe REGEX              Also combine paths matching a Go regexp (repeatable; or -p \"re:REGEX\")\n")
+	fmt.Fprintf(os.Stderr, "  --ere, --ee REGEX       Exclude paths matching a Go regexp (repeatable; or -e \"re:REGEX\")\n")
+	fmt.Fprintf(os.Stderr, "  --grep REGEX            Only combine files with a line matching a Go regexp (repeatable: any of them)\n")
+	fmt.Fprintf(os.Stderr, "  --grep-not REGEX        Skip files with a line matching a Go regexp (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-substring     Also exclude paths merely containing an exclude pattern\n")
+	fmt.Fprintf(os.Stderr, "  --ignore-case           Match patterns, excludes and path regexps regardless of case (*.md finds README.MD)\n")
+	fmt.Fprintf(os.Stderr, "  -r, --recursive         Search recursively in subdirectories\n")
+	fmt.Fprintf(os.Stderr, "  --env-expand-in-patterns Expand $VAR/${VAR} in -p, -e, -o and --root ($$ is a literal $)\n")
+	fmt.Fprintf(os.Stderr, "  --root DIR              Search root (default: .); repeat it (or DIR1,DIR2) to search several,\n")
+	fmt.Fprintf(os.Stderr, "                          with all paths (output, patterns, -e, --max-depth) relative to their common parent\n")
+	fmt.Fprintf(os.Stderr, "  --hidden=true|false     Include dotfiles and dot-directories (default: true; .git is always skipped)\n")
+	fmt.Fprintf(os.Stderr, "  --max-depth N           Don't descend more than N levels: 1 keeps the root's own files (default: 0, no limit)\n")
+	fmt.Fprintf(os.Stderr, "  --max-size BYTES        Max file size (default: 100MB)\n")
+	fmt.Fprintf(os.Stderr, "  --min-size SIZE         Skip files smaller than SIZE (e.g. 64, 1KB)\n")
+	fmt.Fprintf(os.Stderr, "  --skip-empty            Skip empty and whitespace-only files\n")
+	fmt.Fprintf(os.Stderr, "  --format FORMAT         Output format: text, csv (file inventory), markdown, json, xml (default: text)\n")
+	fmt.Fprintf(os.Stderr, "  --tree                  Start with a directory tree of the selected files\n")
+	fmt.Fprintf(os.Stderr, "  --tree-skipped          Also show the excluded files in the --tree, marked (skipped)\n")
+	fmt.Fprintf(os.Stderr, "  --encoding NAME         Output encoding: utf-8, utf-8-bom, utf-16le, utf-16be, latin1, ... (default: utf-8)\n")
+	fmt.Fprintf(os.Stderr, "  --bom MODE              Byte order mark: auto, always, never (default: auto)\n")
+	fmt.Fprintf(os.Stderr, "  --newline TYPE          Newline: lf, crlf, cr, auto (default: lf)\n")
+	fmt.Fprintf(os.Stderr, "  --normalize-newlines    Convert the line endings inside each file to the --newline type\n")
+	fmt.Fprintf(os.Stderr, "  --warn-mixed-newlines   Warn about files that mix LF, CRLF and CR line endings\n")
+	fmt.Fprintf(os.Stderr, "  --max-tokens N          Stop adding files once the estimated tokens would exceed N\n")
+	fmt.Fprintf(os.Stderr, "  --chunk-tokens N        Split the output into numbered parts (out.part1.txt, ...) of at most N tokens each\n")
+	fmt.Fprintf(os.Stderr, "  --split-size SIZE       Split the output into numbered parts of at most SIZE each (e.g. 10MB)\n")
+	fmt.Fprintf(os.Stderr, "  --split-lines N         Split the output into numbered parts of at most N lines each\n")
+	fmt.Fprintf(os.Stderr, "  --part-name TMPL        Name of the parts ({stem}, {ext}, {n}, {parts}; default: {stem}.part{n}.{ext})\n")
+	fmt.Fprintf(os.Stderr, "  --token-estimator NAME  Token count: bytes (bytes/4), words (words*4/3), or the cl100k_base/o200k_base tokenizers (default: bytes)\n")
+	fmt.Fprintf(os.Stderr, "  --max-total-size SIZE   Stop adding files once the output would exceed SIZE (e.g. 400KB)\n")
+	fmt.Fprintf(os.Stderr, "  --max-lines-per-ext LIMITS Cap the lines each extension contributes (e.g. .go=2000,.md=300)\n")
+	fmt.Fprintf(os.Stderr, "  --max-files N           Combine at most N files (after ordering)\n")
+	fmt.Fprintf(os.Stderr, "  --min-files N           Fail (exit code 4) if fewer than N files match\n")
+	fmt.Fprintf(os.Stderr, "  --sort MODE             Order files by path, size, mtime or pattern (default: path)\n")
+	fmt.Fprintf(os.Stderr, "  --reverse               Reverse the --sort order\n")
+	fmt.Fprintf(os.Stderr, "  --largest-first         Order files by size, largest first\n")
+	fmt.Fprintf(os.Stderr, "  --order-note            Record the file ordering in the header\n")
+	fmt.Fprintf(os.Stderr, "  -j, --jobs N            Render files in N parallel workers via temp shards\n")
+	fmt.Fprintf(os.Stderr, "  --max-memory SIZE       Near SIZE (e.g. 256MB), switch off the features that buffer every file\n")
+	fmt.Fprintf(os.Stderr, "  --glob-engine ENGINE    Glob engine: doublestar, standard (default: doublestar)\n")
+	fmt.Fprintf(os.Stderr, "  --no-separator          Skip file separators\n")
+	fmt.Fprintf(os.Stderr, "  --separator-format TMPL Custom separator ({index}, {path}, {abspath}, {name}, {ext}, {size}, {mtime}, {hash})\n")
+	fmt.Fprintf(os.Stderr, "  --separator-meta FIELDS  Add file metadata to the separators: size, lines, sha256, mtime, git (comma-separated)\n")
+	fmt.Fprintf(os.Stderr, "  --separator-template TMPL Go text/template for the separators, written as is ({{.Index}}, {{.RelPath}}, {{.Size}}, {{.SHA256}}, {{.Language}}, {{.CommentPrefix}}, ...; --separator-template-file FILE to read it)\n")
+	fmt.Fprintf(os.Stderr, "  --package-banners       Banner whenever the directory (package) changes\n")
+	fmt.Fprintf(os.Stderr, "  --no-timestamp          Omit the \"Combined at\" line for reproducible output\n")
+	fmt.Fprintf(os.Stderr, "  --no-leading-separator  Skip the separator before the first file only\n")
+	fmt.Fprintf(os.Stderr, "  --collapse-path-depth N Show only the first N directories of separator paths\n")
+	fmt.Fprintf(os.Stderr, "  --dedup                 Include files with byte-identical content only once\n")
+	fmt.Fprintf(os.Stderr, "  --dedup-hardlinks       Include hardlinked copies of the same file only once\n")
+	fmt.Fprintf(os.Stderr, "  --include-binary        Include binary files as base64 blocks (restored by --unpack)\n")
+	fmt.Fprintf(os.Stderr, "  --skip-minified         Skip minified files (.min. in name or very long lines)\n")
+	fmt.Fprintf(os.Stderr, "  --minified-line-length N Average line length treated as minified (default: 300)\n")
+	fmt.Fprintf(os.Stderr, "  --minified-min-size SIZE Only files at least this big are checked (default: 1KB)\n")
+	fmt.Fprintf(os.Stderr, "  --code-only             Skip documentation (*.md, *.rst, *.txt, LICENSE*, docs/)\n")
+	fmt.Fprintf(os.Stderr, "  --docs-only             Only include documentation\n")
+	fmt.Fprintf(os.Stderr, "  --language LANGS        Only include these languages (e.g. go,python)\n")
+	fmt.Fprintf(os.Stderr, "  --exclude-language LANGS Exclude these languages (e.g. json,csv)\n")
+	fmt.Fprintf(os.Stderr, "  --imports-only          Only keep import/require lines (Go, JS/TS, Python)\n")
+	fmt.Fprintf(os.Stderr, "  --line-numbers          Prefix each content line with its line number in the source file\n")
+	fmt.Fprintf(os.Stderr, "  --normalize-indent auto Detect each file's indentation (tabs or N spaces)\n")
+	fmt.Fprintf(os.Stderr, "  --target-indent STYLE   With --normalize-indent, convert to tab or N spaces\n")
+	fmt.Fprintf(os.Stderr, "  --title TEXT            Title banner written once at the top\n")
+	fmt.Fprintf(os.Stderr, "  --header TEXT           Text written at the very top, a template with {{.FileCount}}, {{.Size}}, {{.Tokens}}, ... (--header-file FILE to read it)\n")
+	fmt.Fprintf(os.Stderr, "  --footer TEXT           Text written after the last file, a template like --header (--footer-file FILE to read it)\n")
+	fmt.Fprintf(os.Stderr, "  --toc                   Table of contents at the top (index, path, size, start line)\n")
+	fmt.Fprintf(os.Stderr, "  --manifest FILE.json    Write a JSON manifest with each file's byte offset and length in the output\n")
+	fmt.Fprintf(os.Stderr, "  --embed-manifest        List the selected files in the header so the output can be resumed\n")
+	fmt.Fprintf(os.Stderr, "  --resume                Continue an interrupted combine into the existing -o file\n")
+	fmt.Fprintf(os.Stderr, "  --content-prefix TMPL   Line written before each file's content ({path}, {index}, {name}, {ext}, {abspath})\n")
+	fmt.Fprintf(os.Stderr, "  --content-suffix TMPL   Line written after each file's content\n")
+	fmt.Fprintf(os.Stderr, "  --ignore-gitignore      Skip .gitignore\n")
+	fmt.Fprintf(os.Stderr, "  --respect-gitattributes Use .gitattributes text/binary declarations\n")
+	fmt.Fprintf(os.Stderr, "  --watch                 Combine again whenever matching files change (until Ctrl+C)\n")
+	fmt.Fprintf(os.Stderr, "  --watch-debounce DUR    Quiet time after the last change before --watch rebuilds (default: 300ms)\n")
+	fmt.Fprintf(os.Stderr, "  --addr HOST:PORT        Listen address for serve (default: localhost:8080)\n")
+	fmt.Fprintf(os.Stderr, "  --dry-run               Show what would be combined\n")
+	fmt.Fprintf(os.Stderr, "  --list                  Only print the matched file paths and exit\n")
+	fmt.Fprintf(os.Stderr, "  --scan-extensions       Report the extensions below --root (count, size) and exit\n")
+	fmt.Fprintf(os.Stderr, "  --list-extensions       Show each extension's share of the bytes in the summary\n")
+	fmt.Fprintf(os.Stderr, "  --clone-report          Report blocks of identical lines duplicated across files\n")
+	fmt.Fprintf(os.Stderr, "  --clone-min-lines N     Smallest block --clone-report looks for (default: 6)\n")
+	fmt.Fprintf(os.Stderr, "  --path-style STYLE      Paths printed by --list: relative, absolute (default: relative)\n")
+	fmt.Fprintf(os.Stderr, "  --null, -0              Separate --list output (and --files-from input) with NUL characters\n")
+	fmt.Fprintf(os.Stderr, "  --self-check            Split the output again and verify it matches the inputs\n")
+	fmt.Fprintf(os.Stderr, "  --exact-newlines        Mark files without a final newline so --unpack restores them exactly\n")
+	fmt.Fprintf(os.Stderr, "  --tree-hash             Merkle root hash of the inputs in the summary and header\n")
+	fmt.Fprintf(os.Stderr, "  --sign-key KEY          Append an HMAC-SHA256 signature trailer to the output\n")
+	fmt.Fprintf(os.Stderr, "  --verify-signature FILE Check FILE's signature trailer with --sign-key (exit code 5 if invalid)\n")
+	fmt.Fprintf(os.Stderr, "  --unpack FILE           Recreate the files of a combined FILE (- for stdin, gzip allowed) under --root\n")
+	fmt.Fprintf(os.Stderr, "  --verbose               Verbose output\n")
+	fmt.Fprintf(os.Stderr, "  --debug                 Debug mode (with per-file timing)\n")
+	fmt.Fprintf(os.Stderr, "  -v --version            Show version\n")
+	fmt.Fprintf(os.Stderr, "  -h                      Show help\n\n")
+	fmt.Fprintf(os.Stderr, "Long options may be written --name=value; switches accept --name=false to turn off\n")
+	fmt.Fprintf(os.Stderr, "one set by the config file.\n")
 }
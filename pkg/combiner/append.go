@@ -0,0 +1,85 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// appendTailSize is how much of an existing output -append scans for FILE markers
+const appendTailSize = 1 << 20
+
+var fileIndexLine = regexp.MustCompile(`(?m)^\S*\s?FILE (\d+): `)
+
+// highestFileIndex returns the largest "FILE N:" index in the output at
+// path, looking at its tail first and at the whole file only if the tail
+// has no marker. A missing or marker-free file yields 0.
+func highestFileIndex(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	for _, start := range []int64{info.Size() - appendTailSize, 0} {
+		if start < 0 {
+			start = 0
+		}
+		data, err := io.ReadAll(io.NewSectionReader(file, start, info.Size()-start))
+		if err != nil {
+			return 0, err
+		}
+		highest := 0
+		for _, m := range fileIndexLine.FindAllSubmatch(data, -1) {
+			if n, _ := strconv.Atoi(string(m[1])); n > highest {
+				highest = n
+			}
+		}
+		if highest > 0 || start == 0 {
+			return highest, nil
+		}
+	}
+	return 0, nil
+}
+
+// appendOutput adds the files to the end of an existing output, numbering
+// them after the highest FILE index already in it
+func appendOutput(config *Options, files []string) (int, int, error) {
+	highest, err := highestFileIndex(config.Output)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot read %s: %v", config.Output, err)
+	}
+	existing := int64(0)
+	if info, err := os.Stat(config.Output); err == nil {
+		existing = info.Size()
+	}
+	if highest > 0 {
+		fmt.Printf("Appending after FILE %d of %s\n", highest, config.Output)
+	}
+
+	outFile, err := os.OpenFile(config.Output, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot open output file: %v", err)
+	}
+	defer outFile.Close()
+
+	writer := bufio.NewWriter(outFile)
+	appended := *config
+	appended.IndexOffset = highest
+	appended.Continuing = existing > 0
+	successCount, errorCount := writeOutput(writer, &appended, files)
+	if err := writer.Flush(); err != nil {
+		return successCount, errorCount, fmt.Errorf("Failed to write combined content to file: %v", err)
+	}
+	return successCount, errorCount, nil
+}
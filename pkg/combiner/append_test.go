@@ -0,0 +1,55 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendNumbering(t *testing.T) {
+	first := testOptions(t, writeTree(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"}), "*.txt")
+	combine(t, first)
+
+	second := testOptions(t, writeTree(t, map[string]string{"c.txt": "c\n", "d.go": "package d\n"}), "*")
+	second.Output = first.Output
+	second.Append = true
+	out := combine(t, second)
+
+	for i, name := range []string{"a.txt", "b.txt", "c.txt", "d.go"} {
+		want := fmt.Sprintf("FILE %d: %s\n", i+1, name)
+		if strings.Count(out, want) != 1 {
+			t.Errorf("want one %q in:\n%s", want, out)
+		}
+	}
+	if got := len(splitCombined([]byte(out))); got != 4 {
+		t.Errorf("%d sections after appending, want 4", got)
+	}
+}
+
+func TestHighestFileIndex(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"no markers\n", 0},
+		{"# FILE 3: a.txt\n# FILE 12: b.txt\n# FILE 7: c.txt\n", 12},
+		{"/*\n FILE 4: a.css\n*/\n", 4},
+		{"text mentioning FILE 99: inline\n", 0},
+	}
+	for i, tt := range tests {
+		path := filepath.Join(dir, fmt.Sprintf("out%d", i))
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := highestFileIndex(path); err != nil || got != tt.want {
+			t.Errorf("highestFileIndex(%q) = %d, %v; want %d", tt.content, got, err, tt.want)
+		}
+	}
+	if got, err := highestFileIndex(filepath.Join(dir, "missing")); err != nil || got != 0 {
+		t.Errorf("missing output: %d, %v", got, err)
+	}
+}
@@ -0,0 +1,57 @@
+package combiner
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const directoryBannerLabel = "DIRECTORY: "
+
+// directoryLabel is the root-relative directory of a file as shown in its banner
+func directoryLabel(root, file string) string {
+	relPath, _ := filepath.Rel(root, file)
+	return path.Dir(filepath.ToSlash(relPath)) + "/"
+}
+
+// directoryBanner returns the -package-banners block announcing file's
+// directory, or "" when the previous file lives in the same directory
+func directoryBanner(config *Options, previous, file string) string {
+	if !config.PackageBanners {
+		return ""
+	}
+	dir := directoryLabel(config.Root, file)
+	if previous != "" && directoryLabel(config.Root, previous) == dir {
+		return ""
+	}
+	if config.Format == FORMAT_MARKDOWN {
+		return "\n## " + dir + "\n"
+	}
+	return "\n" + createBanner([]string{directoryBannerLabel + dir}, outputCommentStyle(config))
+}
+
+// trimDirectoryBanner removes the banner announcing nextPath's directory
+// from the end of a section split out of a combined file
+func trimDirectoryBanner(content []byte, nextPath string) []byte {
+	label := directoryBannerLabel + path.Dir(nextPath) + "/"
+	lines := strings.Split(string(content), "\n")
+	last := len(lines) - 1 // the empty string after the final newline
+
+	// The label line sits between two rules, followed by the block comment
+	// closer for block styles; the opener then precedes the first rule
+	for l := last - 1; l >= 2 && l >= last-3; l-- {
+		if !strings.HasSuffix(lines[l], label) || !strings.HasSuffix(lines[l-1], separatorRule) ||
+			l+1 >= last || !strings.HasSuffix(lines[l+1], separatorRule) {
+			continue
+		}
+		start := l - 1
+		if l+3 == last {
+			start = l - 2
+		}
+		if start < 1 || lines[start-1] != "" {
+			return content
+		}
+		return []byte(strings.Join(lines[:start-1], "\n") + "\n")
+	}
+	return content
+}
@@ -0,0 +1,47 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPackageBanners(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":         "package main\n",
+		"b.go":         "package main\n",
+		"pkg/x/x.go":   "package x\n",
+		"pkg/x/y.go":   "package x\n",
+		"pkg/z/z.go":   "package z\n",
+		"pkg/z/z.md":   "# z\n",
+		"pkg/z/zz.txt": "zz\n",
+	})
+	tests := []struct {
+		format string
+		banner func(dir string) string
+	}{
+		{FORMAT_TEXT, func(dir string) string { return "# " + directoryBannerLabel + dir + "\n" }},
+		{FORMAT_MARKDOWN, func(dir string) string { return "\n## " + dir + "\n" }},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "**/*")
+		config.PackageBanners = true
+		config.Format = tt.format
+		out := combine(t, config)
+		for _, dir := range []string{"./", "pkg/x/", "pkg/z/"} {
+			if got := strings.Count(out, tt.banner(dir)); got != 1 {
+				t.Errorf("%s: %d banners for %s, want 1:\n%s", tt.format, got, dir, out)
+			}
+		}
+	}
+}
+
+func TestPackageBannersSplit(t *testing.T) {
+	files := map[string]string{"a.go": "package a\n", "sub/b.py": "print()\n", "sub/c.css": "p {}\n", "z/d.txt": "d\n"}
+	config := testOptions(t, writeTree(t, files), "**/*")
+	config.PackageBanners = true
+	for _, section := range splitCombined([]byte(combine(t, config))) {
+		if want := files[section.Path]; string(section.Content) != want {
+			t.Errorf("%s: content %q, want %q", section.Path, section.Content, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package combiner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// base64LineLength is the width of the lines in a base64 block
+const base64LineLength = 76
+
+var (
+	base64Begin = regexp.MustCompile(`BEGIN BASE64 (.+) \((\d+) bytes\)`)
+	base64End   = "END BASE64"
+)
+
+// fenceLine turns text into a comment line in the given style
+func fenceLine(style CommentStyle, text string) string {
+	if style.BlockStart != "" && style.BlockEnd != "" {
+		return style.BlockStart + " " + text + " " + style.BlockEnd
+	}
+	if style.SingleLine != "" {
+		return style.SingleLine + " " + text
+	}
+	return text
+}
+
+// encodeBinary renders a binary file for -include-binary: its base64 encoding
+// between two comment fences, the first one naming the file and its size
+func encodeBinary(filePath string, content []byte) []byte {
+	style := getCommentStyle(filePath)
+	encoded := base64.StdEncoding.EncodeToString(content)
+
+	var b bytes.Buffer
+	b.WriteString(fenceLine(style, fmt.Sprintf("BEGIN BASE64 %s (%d bytes)", filepath.Base(filePath), len(content))) + "\n")
+	for len(encoded) > base64LineLength {
+		b.WriteString(encoded[:base64LineLength] + "\n")
+		encoded = encoded[base64LineLength:]
+	}
+	if encoded != "" {
+		b.WriteString(encoded + "\n")
+	}
+	b.WriteString(fenceLine(style, base64End) + "\n")
+	return b.Bytes()
+}
+
+// decodeBinary recognizes a section written by encodeBinary and returns the
+// original bytes. ok is false when content is not a complete base64 block
+// or its size doesn't match the fence, in which case it is left as text.
+func decodeBinary(content []byte) (data []byte, ok bool) {
+	lines := strings.Split(strings.TrimRight(string(content), "\r\n"), "\n")
+	if len(lines) < 2 || !strings.Contains(lines[len(lines)-1], base64End) {
+		return nil, false
+	}
+	m := base64Begin.FindStringSubmatch(lines[0])
+	if m == nil {
+		return nil, false
+	}
+	size, _ := strconv.Atoi(m[2])
+
+	var encoded strings.Builder
+	for _, line := range lines[1 : len(lines)-1] {
+		encoded.WriteString(strings.TrimSpace(line))
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil || len(data) != size {
+		return nil, false
+	}
+	return data, true
+}
@@ -0,0 +1,110 @@
+package combiner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncodeBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		content []byte
+		fence   string
+	}{
+		{"empty", "empty.bin", nil, "# BEGIN BASE64 empty.bin (0 bytes)\n"},
+		{"small", "icon.png", []byte{0x89, 'P', 'N', 'G', 0, 1, 2}, "# BEGIN BASE64 icon.png (7 bytes)\n"},
+		{"one full line", "a.bin", bytes.Repeat([]byte{0}, 57), "# BEGIN BASE64 a.bin (57 bytes)\n"},
+		{"several lines", "app.wasm", bytes.Repeat([]byte{0, 0xff, 'a'}, 100), "# BEGIN BASE64 app.wasm (300 bytes)\n"},
+		{"block comments", "blob.css", []byte{0, 1}, "/* BEGIN BASE64 blob.css (2 bytes) */\n"},
+		{"markup comments", "blob.html", []byte{0, 1}, "<!-- BEGIN BASE64 blob.html (2 bytes) -->\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeBinary(tt.path, tt.content)
+			if !bytes.HasPrefix(encoded, []byte(tt.fence)) {
+				t.Errorf("starts %q, want %q", encoded[:min(len(encoded), 60)], tt.fence)
+			}
+			for _, line := range strings.Split(string(encoded), "\n") {
+				if len(line) > base64LineLength && !strings.Contains(line, "BASE64") {
+					t.Errorf("line of %d characters, over %d", len(line), base64LineLength)
+				}
+			}
+			decoded, ok := decodeBinary(encoded)
+			if !ok || !bytes.Equal(decoded, tt.content) {
+				t.Errorf("decodeBinary = %v, %v; want the original bytes", decoded, ok)
+			}
+		})
+	}
+}
+
+func TestDecodeBinaryRejects(t *testing.T) {
+	good := string(encodeBinary("a.bin", []byte("hello")))
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"text", "plain text\n"},
+		{"no end fence", strings.TrimSuffix(good, "# END BASE64\n")},
+		{"wrong size", strings.Replace(good, "(5 bytes)", "(6 bytes)", 1)},
+		{"not base64", "# BEGIN BASE64 a.bin (5 bytes)\n!!!!\n# END BASE64\n"},
+		{"no begin fence", "aGVsbG8=\n# END BASE64\n"},
+	}
+	for _, tt := range tests {
+		if _, ok := decodeBinary([]byte(tt.content)); ok {
+			t.Errorf("%s: decoded %q", tt.name, tt.content)
+		}
+	}
+}
+
+func TestIncludeBinaryRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"icon.png":   "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"app.wasm":   strings.Repeat("\x00asm\x01\x00\x00\x00", 40),
+		"readme.txt": "text stays text\n",
+		"huge.bin":   strings.Repeat("\x00", 2048),
+	}
+	root := writeTree(t, files)
+	tests := []struct {
+		include bool
+		want    []string
+	}{
+		{false, []string{"readme.txt"}},
+		{true, []string{"app.wasm", "icon.png", "readme.txt"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*")
+		config.IncludeBinary = tt.include
+		config.MaxSize = 1024 // keeps guarding binaries too
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("include=%v: got %q, want %q", tt.include, got, tt.want)
+		}
+	}
+
+	config := testOptions(t, root, "*")
+	config.IncludeBinary = true
+	config.MaxSize = 1024
+	out := combine(t, config)
+	if strings.Contains(out, "\x00") {
+		t.Error("raw binary bytes in the output")
+	}
+
+	target := t.TempDir()
+	unpack := DefaultOptions()
+	unpack.Root = target
+	unpack.Unpack = config.Output
+	var code int
+	captureStdout(t, func() { code = UnpackCombined(unpack) })
+	if code != 0 {
+		t.Fatalf("UnpackCombined = %d", code)
+	}
+	for _, name := range []string{"icon.png", "app.wasm", "readme.txt"} {
+		got, err := os.ReadFile(filepath.Join(target, name))
+		if err != nil || string(got) != files[name] {
+			t.Errorf("%s = %q, %v; want the original bytes", name, got, err)
+		}
+	}
+}
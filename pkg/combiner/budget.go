@@ -0,0 +1,230 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Token estimators selectable with -token-estimator (see also the
+// tokenizers in tokens.go)
+const (
+	TOKENS_BYTES = "bytes"
+	TOKENS_WORDS = "words"
+)
+
+// TokenEstimators give a rough token count from the totals of some output.
+// bytes/4 is the usual rule of thumb for English text and code, and words
+// assumes 3 words per 4 tokens; the tokenizers report what they counted.
+var TokenEstimators = map[string]func(t outputTotals) int64{
+	TOKENS_BYTES: func(t outputTotals) int64 {
+		return (t.Bytes + 3) / 4
+	},
+	TOKENS_WORDS: func(t outputTotals) int64 {
+		return (t.Words*4 + 2) / 3
+	},
+	TOKENS_CL100K: func(t outputTotals) int64 {
+		return t.Tokens
+	},
+	TOKENS_O200K: func(t outputTotals) int64 {
+		return t.Tokens
+	},
+}
+
+// outputTotals describes how much output a run produced (before -encoding)
+type outputTotals struct {
+	Bytes  int64
+	Lines  int64
+	Words  int64 // runs of non-whitespace
+	Tokens int64 // counted by the -token-estimator tokenizer, if it is one
+	inWord bool  // whether the last byte counted was part of a word
+
+	encoding *tiktoken.Tiktoken // the tokenizer, nil for the heuristics
+}
+
+// newTotals starts counting output for the -token-estimator
+func newTotals(config *Options) outputTotals {
+	return outputTotals{encoding: tokenizer(config)}
+}
+
+// count adds data, which continues what was counted before
+func (t *outputTotals) count(data []byte) {
+	t.Bytes += int64(len(data))
+	t.Lines += int64(bytes.Count(data, []byte("\n")))
+	for _, c := range data {
+		space := c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+		if !space && !t.inWord {
+			t.Words++
+		}
+		t.inWord = !space
+	}
+	if t.encoding != nil {
+		t.Tokens += countTokens(t.encoding, data)
+	}
+}
+
+func (t *outputTotals) add(o outputTotals) {
+	t.Bytes += o.Bytes
+	t.Lines += o.Lines
+	t.Words += o.Words
+	t.Tokens += o.Tokens
+}
+
+// tokens estimates the token count with the -token-estimator heuristic
+func (t outputTotals) tokens(config *Options) int64 {
+	return TokenEstimators[config.TokenEstimator](t)
+}
+
+// describe summarizes the totals for the reports
+func (t outputTotals) describe(config *Options) string {
+	return fmt.Sprintf("%s, %d lines, ~%d tokens", formatSize(t.Bytes), t.Lines, t.tokens(config))
+}
+
+// totalsWriter counts the bytes, lines and words passing through it
+type totalsWriter struct {
+	w      io.Writer
+	totals *outputTotals
+}
+
+func (t *totalsWriter) Write(p []byte) (int, error) {
+	n, err := t.w.Write(p)
+	t.totals.count(p[:n])
+	return n, err
+}
+
+// measure returns the totals of a piece of output
+func measure(config *Options, data []byte) outputTotals {
+	t := newTotals(config)
+	t.count(data)
+	return t
+}
+
+// sectionTotals renders every file's section (separator and content) without
+// writing it and measures it; unreadable files count as empty
+func sectionTotals(config *Options, files []string) []outputTotals {
+	newline := getNewline(config.newlineType())
+	totals := make([]outputTotals, len(files))
+	var section bytes.Buffer
+	for idx, filePath := range files {
+		content, err := loadContent(config, filePath)
+		if err != nil {
+			continue
+		}
+		section.Reset()
+		section.WriteString(sectionSeparator(config, filePath, idx+1, content))
+		writeSectionBody(&section, config, filePath, idx+1, content, newline)
+		totals[idx] = measure(config, section.Bytes())
+	}
+	return totals
+}
+
+// applyOutputBudget keeps files, in order, until the next one would take the
+// output past -max-total-size or -max-tokens; that file and all after it are
+// reported as skipped so the output stays complete for what it contains, cut
+// at a file boundary. The header and footer count too; the header is measured
+// for the full file list, which is never smaller than the final one.
+func applyOutputBudget(config *Options, files []string) ([]string, []FileInfo) {
+	if config.MaxTotalSize <= 0 && config.MaxTokens <= 0 {
+		return files, nil
+	}
+
+	total := newTotals(config)
+	if !config.Continuing {
+		total = measure(config, []byte(createDocumentHeader(config, files)+createDocumentFooter(config, files)))
+	}
+	for idx, section := range sectionTotals(config, files) {
+		reason := ""
+		next := total
+		next.add(section)
+		if config.MaxTotalSize > 0 && next.Bytes > config.MaxTotalSize {
+			reason = fmt.Sprintf("Budget exceeded (--max-total-size %s)", formatSize(config.MaxTotalSize))
+		} else if config.MaxTokens > 0 && next.tokens(config) > config.MaxTokens {
+			reason = fmt.Sprintf("Token budget exceeded (--max-tokens %d, %s estimate)", config.MaxTokens, config.TokenEstimator)
+		}
+		if reason != "" {
+			var skipped []FileInfo
+			for _, f := range files[idx:] {
+				skipped = append(skipped, FileInfo{f, reason})
+			}
+			return files[:idx], skipped
+		}
+		total = next
+	}
+	return files, nil
+}
+
+// ParseExtensionLimits parses -max-lines-per-ext values such as ".go=2000,py=500"
+// into limits, keyed by lower-case extension with its leading dot
+func ParseExtensionLimits(value string, limits map[string]int) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		ext, n, ok := strings.Cut(item, "=")
+		lines, err := strconv.Atoi(strings.TrimSpace(n))
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if !ok || err != nil || lines < 0 || ext == "" || ext == "." {
+			return fmt.Errorf("invalid extension limit %q (use .ext=LINES)", item)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		limits[ext] = lines
+	}
+	return nil
+}
+
+// contentLineCount counts the lines of rendered content, including a last
+// line without a newline
+func contentLineCount(content []byte) int {
+	lines := countLines(content)
+	if len(content) > 0 && content[len(content)-1] != '\n' && content[len(content)-1] != '\r' {
+		lines++
+	}
+	return lines
+}
+
+// applyExtensionLineBudget enforces -max-lines-per-ext: files of a limited
+// extension are kept, in order, while the lines they contribute fit in its
+// budget. The file that would exceed it and every later file with the same
+// extension are reported as skipped.
+func applyExtensionLineBudget(config *Options, files []string) ([]string, []FileInfo) {
+	if len(config.MaxLinesPerExt) == 0 {
+		return files, nil
+	}
+
+	used := make(map[string]int)
+	exhausted := make(map[string]bool)
+	var kept []string
+	var skipped []FileInfo
+	for _, file := range files {
+		ext := strings.ToLower(filepath.Ext(file))
+		limit, limited := config.MaxLinesPerExt[ext]
+		if !limited {
+			kept = append(kept, file)
+			continue
+		}
+		if !exhausted[ext] {
+			content, err := loadContent(config, file)
+			if err != nil {
+				// Reported by the writer like any other unreadable file
+				kept = append(kept, file)
+				continue
+			}
+			if lines := contentLineCount(content); used[ext]+lines <= limit {
+				used[ext] += lines
+				kept = append(kept, file)
+				continue
+			}
+			exhausted[ext] = true
+		}
+		skipped = append(skipped, FileInfo{file, fmt.Sprintf("Line budget for %s exhausted (--max-lines-per-ext %s=%d)", ext, ext, limit)})
+	}
+	return kept, skipped
+}
@@ -0,0 +1,315 @@
+package combiner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseExtensionLimits(t *testing.T) {
+	tests := []struct {
+		value string
+		want  map[string]int
+		ok    bool
+	}{
+		{".go=2000", map[string]int{".go": 2000}, true},
+		{"go=10, .PY=5,", map[string]int{".go": 10, ".py": 5}, true},
+		{".md=0", map[string]int{".md": 0}, true},
+		{".go", nil, false},
+		{".go=many", nil, false},
+		{".go=-1", nil, false},
+		{"=5", nil, false},
+		{".=5", nil, false},
+	}
+	for _, tt := range tests {
+		limits := make(map[string]int)
+		err := ParseExtensionLimits(tt.value, limits)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseExtensionLimits(%q) error = %v, want ok=%v", tt.value, err, tt.ok)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+		if len(limits) != len(tt.want) {
+			t.Errorf("ParseExtensionLimits(%q) = %v, want %v", tt.value, limits, tt.want)
+		}
+		for ext, n := range tt.want {
+			if limits[ext] != n {
+				t.Errorf("ParseExtensionLimits(%q) = %v, want %v", tt.value, limits, tt.want)
+			}
+		}
+	}
+}
+
+func TestContentLineCount(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"", 0},
+		{"one", 1},
+		{"one\n", 1},
+		{"one\ntwo", 2},
+		{"one\r\ntwo\r\n", 2},
+		{"\n\n", 2},
+	}
+	for _, tt := range tests {
+		if got := contentLineCount([]byte(tt.content)); got != tt.want {
+			t.Errorf("contentLineCount(%q) = %d, want %d", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestExtensionLineBudget(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":     strings.Repeat("a\n", 4),
+		"b.go":     strings.Repeat("b\n", 4),
+		"c.go":     "c\n", // would fit, but the budget stops at the first file that does not
+		"d.py":     strings.Repeat("d\n", 50),
+		"E.GO":     "upper\n",
+		"f.txt":    "f\n",
+		"g.rs":     "g",
+		"sub/h.rs": "h\n",
+	})
+	tests := []struct {
+		name   string
+		limits map[string]int
+		want   []string
+	}{
+		{"unlimited", nil, []string{"E.GO", "a.go", "b.go", "c.go", "d.py", "f.txt", "g.rs", "sub/h.rs"}},
+		{"go budget", map[string]int{".go": 6}, []string{"E.GO", "a.go", "d.py", "f.txt", "g.rs", "sub/h.rs"}},
+		{"exact fit", map[string]int{".go": 9}, []string{"E.GO", "a.go", "b.go", "d.py", "f.txt", "g.rs", "sub/h.rs"}},
+		{"zero drops the extension", map[string]int{".py": 0}, []string{"E.GO", "a.go", "b.go", "c.go", "f.txt", "g.rs", "sub/h.rs"}},
+		{"last line without newline", map[string]int{".rs": 1}, []string{"E.GO", "a.go", "b.go", "c.go", "d.py", "f.txt", "g.rs"}},
+		{"several", map[string]int{".go": 1, ".txt": 1}, []string{"E.GO", "d.py", "f.txt", "g.rs", "sub/h.rs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.MaxLinesPerExt = tt.limits
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := testOptions(t, root, "**/*.go")
+	config.MaxLinesPerExt = map[string]int{".go": 6}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := 0
+	for _, skip := range report.Skipped {
+		if skip.Reason == "Line budget for .go exhausted (--max-lines-per-ext .go=6)" {
+			budget++
+		}
+	}
+	if budget != 2 {
+		t.Errorf("%d files skipped for the .go budget, want b.go and c.go: %+v", budget, report.Skipped)
+	}
+}
+
+func TestOutputTotals(t *testing.T) {
+	tests := []struct {
+		chunks []string
+		bytes  int64
+		lines  int64
+		words  int64
+	}{
+		{nil, 0, 0, 0},
+		{[]string{"one two\nthree\n"}, 14, 2, 3},
+		{[]string{"  spaced\t\tout  "}, 15, 0, 2},
+		{[]string{"split wo", "rd here\n"}, 16, 1, 3}, // a word across writes counts once
+		{[]string{"a\r\nb\r\n"}, 6, 2, 2},
+	}
+	for _, tt := range tests {
+		totals := newTotals(DefaultOptions())
+		for _, chunk := range tt.chunks {
+			totals.count([]byte(chunk))
+		}
+		if totals.Bytes != tt.bytes || totals.Lines != tt.lines || totals.Words != tt.words {
+			t.Errorf("%q: got %d bytes, %d lines, %d words; want %d, %d, %d",
+				tt.chunks, totals.Bytes, totals.Lines, totals.Words, tt.bytes, tt.lines, tt.words)
+		}
+	}
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	files := map[string]string{
+		"a.txt": strings.Repeat("a", 100) + "\n",
+		"b.txt": strings.Repeat("b", 100) + "\n",
+		"c.txt": strings.Repeat("c", 100) + "\n",
+	}
+	root := writeTree(t, files)
+	full := int64(len(combine(t, testOptions(t, root, "*.txt"))))
+
+	tests := []struct {
+		name   string
+		budget int64
+		setup  func(*Options)
+		kept   int
+	}{
+		{"unlimited", 0, nil, 3},
+		{"exact fit", full, nil, 3},
+		{"one byte short", full - 1, nil, 2},
+		{"first file only", full / 2, nil, 1},
+		{"nothing fits", 10, nil, 0},
+		{"header counts", full, func(config *Options) { config.Title = "A title that takes room" }, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.MaxTotalSize = tt.budget
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Files) != tt.kept {
+				t.Fatalf("kept %d files, want %d", len(report.Files), tt.kept)
+			}
+			for _, skip := range report.Skipped {
+				if !strings.HasPrefix(skip.Reason, "Budget exceeded (--max-total-size") {
+					t.Errorf("skip reason %q", skip.Reason)
+				}
+			}
+			if tt.kept == 0 {
+				return
+			}
+
+			out := combine(t, config)
+			if tt.budget > 0 && int64(len(out)) > tt.budget {
+				t.Errorf("output is %d bytes, over the %d byte budget", len(out), tt.budget)
+			}
+			if sections := splitCombined([]byte(out)); len(sections) != tt.kept {
+				t.Errorf("output has %d sections, want %d", len(sections), tt.kept)
+			}
+		})
+	}
+}
+
+func TestDryRunRunningTotal(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "aaaa\n", "b.txt": "bbbb\n"})
+	config := testOptions(t, root, "*.txt")
+	config.DryRun = true
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() { PrintSummary(config, report) })
+
+	sections := sectionTotals(config, report.Files)
+	first, both := sections[0], sections[0]
+	both.add(sections[1])
+	for _, want := range []string{
+		fmt.Sprintf("a.txt (0.0 KB, ~%d tokens, running total %s ~%d tokens)", first.tokens(config), formatSize(first.Bytes), first.tokens(config)),
+		fmt.Sprintf("running total %s ~%d tokens)", formatSize(both.Bytes), both.tokens(config)),
+		fmt.Sprintf("Total: 2 files will be combined (%s, ~%d tokens, bytes)", formatSize(both.Bytes), both.tokens(config)),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTokenEstimators(t *testing.T) {
+	tests := []struct {
+		estimator string
+		totals    outputTotals
+		want      int64
+	}{
+		{TOKENS_BYTES, outputTotals{Bytes: 0}, 0},
+		{TOKENS_BYTES, outputTotals{Bytes: 1}, 1},
+		{TOKENS_BYTES, outputTotals{Bytes: 8}, 2},
+		{TOKENS_BYTES, outputTotals{Bytes: 9}, 3},
+		{TOKENS_WORDS, outputTotals{Words: 3}, 4},
+		{TOKENS_WORDS, outputTotals{Words: 1}, 2},
+		{TOKENS_WORDS, outputTotals{Words: 0, Bytes: 100}, 0},
+		{TOKENS_CL100K, outputTotals{Bytes: 100, Tokens: 7}, 7},
+	}
+	for _, tt := range tests {
+		if got := TokenEstimators[tt.estimator](tt.totals); got != tt.want {
+			t.Errorf("%s estimate of %+v = %d, want %d", tt.estimator, tt.totals, got, tt.want)
+		}
+	}
+}
+
+func TestMaxTokens(t *testing.T) {
+	files := map[string]string{
+		"a.txt": strings.Repeat("abc ", 100),
+		"b.txt": strings.Repeat("abc ", 100),
+		"c.txt": strings.Repeat("abc ", 100),
+	}
+	root := writeTree(t, files)
+
+	// used returns the estimated tokens of the first n sections
+	used := func(estimator string, n int) int64 {
+		config := testOptions(t, root, "*.txt")
+		config.TokenEstimator = estimator
+		total := newTotals(config)
+		for _, section := range sectionTotals(config, treeFiles(root, files)[:n]) {
+			total.add(section)
+		}
+		return total.tokens(config)
+	}
+	tests := []struct {
+		estimator string
+		maxTokens int64
+		kept      int
+	}{
+		{TOKENS_BYTES, 0, 3},
+		{TOKENS_BYTES, used(TOKENS_BYTES, 3), 3},
+		{TOKENS_BYTES, used(TOKENS_BYTES, 3) - 1, 2},
+		{TOKENS_BYTES, used(TOKENS_BYTES, 2), 2},
+		{TOKENS_BYTES, used(TOKENS_BYTES, 1), 1},
+		{TOKENS_BYTES, used(TOKENS_BYTES, 1) - 1, 0},
+		{TOKENS_WORDS, used(TOKENS_WORDS, 2), 2},
+		{TOKENS_WORDS, used(TOKENS_WORDS, 2) - 1, 1},
+		{TOKENS_WORDS, used(TOKENS_BYTES, 2), 1}, // words weigh more than bytes here
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d", tt.estimator, tt.maxTokens), func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.TokenEstimator = tt.estimator
+			config.MaxTokens = tt.maxTokens
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Files) != tt.kept {
+				t.Fatalf("kept %d files, want %d", len(report.Files), tt.kept)
+			}
+			want := fmt.Sprintf("Token budget exceeded (--max-tokens %d, %s estimate)", tt.maxTokens, tt.estimator)
+			for _, skip := range report.Skipped {
+				if skip.Reason != want {
+					t.Errorf("skip reason %q, want %q", skip.Reason, want)
+				}
+			}
+			if tt.kept == 0 || tt.maxTokens == 0 {
+				return
+			}
+
+			out := combine(t, config)
+			if tokens := measure(config, []byte(out)).tokens(config); tokens > tt.maxTokens {
+				t.Errorf("output is ~%d tokens, over the budget of %d", tokens, tt.maxTokens)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package combiner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CLONE_MIN_LINES is the default -clone-min-lines window
+const CLONE_MIN_LINES = 6
+
+// cloneLine is a non-blank source line with its 1-based line number
+type cloneLine struct {
+	Number int
+	Text   []byte
+}
+
+// cloneSpan is a line range of one file
+type cloneSpan struct {
+	File       string
+	Start, End int
+}
+
+// clone is a block of lines that appears identically in two files
+type clone struct {
+	A, B  cloneSpan
+	Lines int // non-blank lines in the block
+}
+
+// cloneLines returns a file's non-blank lines with surrounding whitespace removed
+func cloneLines(file string) []cloneLine {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var lines []cloneLine
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			lines = append(lines, cloneLine{i + 1, line})
+		}
+	}
+	return lines
+}
+
+// findClones detects blocks of at least minLines non-blank lines shared by
+// two different files. Every window of minLines consecutive lines is hashed;
+// windows with the same hash in two files are clone seeds, and seeds that
+// continue line by line in both files are merged into one longer block.
+// Lines are compared after trimming whitespace, so re-indented copies are
+// found, but renamed identifiers or small edits break a clone, and clones
+// inside a single file are not reported.
+func findClones(files []string, minLines int) []clone {
+	type location struct{ file, pos int }
+	content := make([][]cloneLine, len(files))
+	windows := make(map[[sha256.Size]byte][]location)
+	for fi, file := range files {
+		content[fi] = cloneLines(file)
+		lines := content[fi]
+		for pos := 0; pos+minLines <= len(lines); pos++ {
+			h := sha256.New()
+			for _, line := range lines[pos : pos+minLines] {
+				h.Write(line.Text)
+				h.Write([]byte{'\n'})
+			}
+			var sum [sha256.Size]byte
+			h.Sum(sum[:0])
+			windows[sum] = append(windows[sum], location{fi, pos})
+		}
+	}
+
+	// Seeds of the same file pair at the same relative offset form runs
+	type pairKey struct{ a, b, offset int }
+	seeds := make(map[pairKey][]int)
+	for _, locs := range windows {
+		for i := 0; i < len(locs); i++ {
+			for j := i + 1; j < len(locs); j++ {
+				a, b := locs[i], locs[j]
+				if a.file == b.file {
+					continue
+				}
+				if a.file > b.file {
+					a, b = b, a
+				}
+				key := pairKey{a.file, b.file, b.pos - a.pos}
+				seeds[key] = append(seeds[key], a.pos)
+			}
+		}
+	}
+
+	var clones []clone
+	for key, starts := range seeds {
+		sort.Ints(starts)
+		a, b := content[key.a], content[key.b]
+		for i := 0; i < len(starts); {
+			first := starts[i]
+			last := first
+			for i++; i < len(starts) && starts[i] <= last+1; i++ {
+				last = starts[i]
+			}
+			end := last + minLines - 1
+			clones = append(clones, clone{
+				A:     cloneSpan{files[key.a], a[first].Number, a[end].Number},
+				B:     cloneSpan{files[key.b], b[first+key.offset].Number, b[end+key.offset].Number},
+				Lines: end - first + 1,
+			})
+		}
+	}
+
+	sort.Slice(clones, func(i, j int) bool {
+		if clones[i].Lines != clones[j].Lines {
+			return clones[i].Lines > clones[j].Lines
+		}
+		if clones[i].A.File != clones[j].A.File {
+			return clones[i].A.File < clones[j].A.File
+		}
+		if clones[i].A.Start != clones[j].A.Start {
+			return clones[i].A.Start < clones[j].A.Start
+		}
+		return clones[i].B.File < clones[j].B.File
+	})
+	return clones
+}
+
+// printCloneReport lists the blocks duplicated across files, longest first
+func printCloneReport(root string, files []string, minLines int) {
+	clones := findClones(files, minLines)
+	span := func(s cloneSpan) string {
+		relPath, _ := filepath.Rel(root, s.File)
+		return fmt.Sprintf("%s:%d-%d", relPath, s.Start, s.End)
+	}
+
+	fmt.Printf("\nCLONE REPORT (blocks of %d+ identical lines across files):\n", minLines)
+	if len(clones) == 0 {
+		fmt.Println("  No duplicated blocks found")
+		return
+	}
+	for _, c := range clones {
+		fmt.Printf("  %4d lines  %s  <->  %s\n", c.Lines, span(c.A), span(c.B))
+	}
+	fmt.Printf("Total: %d duplicated blocks\n", len(clones))
+}
@@ -0,0 +1,101 @@
+package combiner
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cloneBlock is eight distinct lines shared by the files in the tests below
+const cloneBlock = "alpha()\nbeta()\ngamma()\ndelta()\nepsilon()\nzeta()\neta()\ntheta()\n"
+
+func TestFindClones(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		minLines int
+		want     []string // "a:start-end b:start-end lines"
+	}{
+		{
+			name: "shared block",
+			files: map[string]string{
+				"a.go": "package a\n\n" + cloneBlock + "tail\n",
+				"b.go": "package b\nother()\n" + cloneBlock,
+			},
+			minLines: 6,
+			want:     []string{"a.go:3-10 b.go:3-10 8"},
+		},
+		{
+			name: "reindented and blank lines",
+			files: map[string]string{
+				"a.go": cloneBlock,
+				"b.go": "\t" + strings.ReplaceAll(cloneBlock, "\n", "\n\n\t"),
+			},
+			minLines: 6,
+			want:     []string{"a.go:1-8 b.go:1-15 8"},
+		},
+		{
+			name: "below threshold",
+			files: map[string]string{
+				"a.go": "one()\ntwo()\nthree()\n",
+				"b.go": "one()\ntwo()\nthree()\n",
+			},
+			minLines: 6,
+		},
+		{
+			name: "threshold counts",
+			files: map[string]string{
+				"a.go": "one()\ntwo()\nthree()\n",
+				"b.go": "x\none()\ntwo()\nthree()\n",
+			},
+			minLines: 3,
+			want:     []string{"a.go:1-3 b.go:2-4 3"},
+		},
+		{
+			name: "edit splits the block",
+			files: map[string]string{
+				"a.go": cloneBlock,
+				"b.go": strings.Replace(cloneBlock, "delta()", "changed()", 1),
+			},
+			minLines: 3,
+			want:     []string{"a.go:5-8 b.go:5-8 4", "a.go:1-3 b.go:1-3 3"},
+		},
+		{
+			name:     "same file is not a clone",
+			files:    map[string]string{"a.go": cloneBlock + cloneBlock},
+			minLines: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			clones := findClones(treeFiles(root, tt.files), tt.minLines)
+			var got []string
+			for _, c := range clones {
+				a, _ := filepath.Rel(root, c.A.File)
+				b, _ := filepath.Rel(root, c.B.File)
+				got = append(got, fmt.Sprintf("%s:%d-%d %s:%d-%d %d", a, c.A.Start, c.A.End, b, c.B.Start, c.B.End, c.Lines))
+			}
+			if !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintCloneReport(t *testing.T) {
+	files := map[string]string{"a.go": cloneBlock, "sub/b.go": "x\n" + cloneBlock, "c.go": "unique\n"}
+	root := writeTree(t, files)
+	out := captureStdout(t, func() { printCloneReport(root, treeFiles(root, files), 6) })
+	for _, want := range []string{"blocks of 6+ identical lines", "8 lines  a.go:1-8  <->  " + filepath.Join("sub", "b.go") + ":2-9", "Total: 1 duplicated blocks"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q:\n%s", want, out)
+		}
+	}
+
+	out = captureStdout(t, func() { printCloneReport(root, treeFiles(root, files), 9) })
+	if !strings.Contains(out, "No duplicated blocks found") {
+		t.Errorf("report with a 9-line window:\n%s", out)
+	}
+}
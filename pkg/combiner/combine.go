@@ -0,0 +1,1418 @@
+package combiner
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"strconv"
+
+	"github.com/atotto/clipboard"
+)
+
+const (
+	MAX_FILE_SIZE  = 100 * 1024 * 1024 // 100MB
+	BUFFER_SIZE    = 8192
+)
+
+// CommentStyle defines how to format comments for different file types
+type CommentStyle struct {
+	SingleLine string
+	BlockStart string
+	BlockEnd   string
+}
+
+// Options configures a run; the fields mirror the command-line flags.
+// Select and Combine only read them, so one Options can serve many runs.
+type Options struct {
+	Patterns        []string
+	Output          string
+	Excludes        []string
+	Root            string
+	NoSeparator     bool
+	Encoding        string
+	NewlineType     string
+	NormalizeNewlines bool
+	MaxSize         int64
+	MinSize         int64 // smaller files are skipped
+	SkipEmpty       bool  // skip empty and whitespace-only files
+	IgnoreGitignore bool
+	DryRun          bool
+	Verbose         bool
+	Debug           bool
+	Recursive		bool
+	GlobEngine      string
+	SkipHidden      bool     // -hidden=false: leave out dotfiles and don't enter dot-directories
+	MaxDepth        int      // files at most this many levels below the root (1: the root's own); 0 for no limit
+	Roots           []string // the -root directories when there are several; Root is then their common parent
+	IgnoreCase      bool // patterns, excludes and path regexps match regardless of letter case
+	ContentPrefix   string
+	ContentSuffix   string
+	MaxMemory       int64
+	MaxFiles        int
+	LargestFirst    bool
+	Sort            string
+	Reverse         bool
+	RespectGitattributes bool
+	Title           string
+	Header          string
+	Footer          string
+	Languages       []string
+	ExcludeLanguages []string
+	SelfCheck       bool
+	ExactNewlines   bool // mark files without a final newline for -unpack; implied by SelfCheck
+	MinFiles        int
+	List            bool
+	PathStyle       string
+	NullSeparated   bool
+	PipeTo          string
+	NoLeadingSeparator bool
+	SkipMinified    bool
+	MinifiedLineLength int
+	MinifiedMinSize int64
+	Format          string
+	BOM             string
+	Jobs            int
+	ImportsOnly     bool
+	DedupHardlinks  bool
+	Dedup           bool
+	CollapseDepth   int
+	WarnMixedNewlines bool
+	TOC             bool
+	OrderNote       bool
+	Unpack          string
+	EmbedManifest   bool
+	ListExtensions  bool
+	NormalizeIndent string
+	Preset          string
+	NoTimestamp     bool
+	TreeHash        bool
+	ExcludeSubstring bool
+	PackageBanners  bool
+	LineNumbers     bool
+	ScanExtensions  bool
+	SeparatorFormat string
+	SeparatorTemplate string // text/template source of -separator-template
+	SeparatorMeta   []string // -separator-meta fields: size, lines, sha256, mtime, git
+	LineRanges      map[string]lineRange // from path:start-end patterns, by absolute path
+	FilesFrom       string
+	GitTracked      bool   // find the candidates with git ls-files instead of walking the tree
+	ChangedSince    string // git ref; only files changed since it are combined
+	NewerThan       time.Time // only files modified after this are combined
+	OlderThan       time.Time // only files modified before this are combined
+	Staged          bool   // only files with changes staged in the git index are combined
+	IncludeUntracked bool  // untracked files count as changed (ChangedSince) or tracked (GitTracked)
+	Append          bool
+	IncludeRegexps  []*regexp.Regexp // -pe: also combine files whose relative path matches
+	ExcludeRegexps  []*regexp.Regexp
+	GrepRegexps     []*regexp.Regexp // keep only files with a line matching one of these
+	GrepNotRegexps  []*regexp.Regexp // skip files with a line matching one of these
+	ExcludeNames    []string
+	MaxTotalSize    int64
+	MaxTokens       int64
+	ChunkTokens     int64
+	SplitSize       int64
+	SplitLines      int64
+	PartName        string
+	TokenEstimator  string
+	MaxLinesPerExt  map[string]int
+	Gzip            bool
+	GzipLevel       int
+	CloneReport     bool
+	CloneMinLines   int
+	IncludeBinary   bool
+	EnvExpand       bool
+	Manifest        string
+	SignKey         string
+	VerifySignature string
+	Watch           bool
+	Tree            bool
+	TreeSkipped     bool
+	WatchDebounce   time.Duration
+	Rebuilding      bool // set on the copies -watch and serve rebuild with, to keep the reports short
+	TargetIndent    *IndentStyle
+	Resume          bool
+	IndexOffset     int  // files already present in the output (-resume, -append)
+	Continuing      bool // writing after existing output: no header or BOM
+	Version         string    // tool version recorded by -manifest
+	Stdout          io.Writer // destination of -o -, -list and the -pipe-to command's output; os.Stdout when nil
+
+	part string    // "2 of 5" while writing the numbered parts of a split output
+	run  *runState // set on the copy Select and Combine work on, never on the caller's Options
+}
+
+// FileInfo holds information about processed files
+type FileInfo struct {
+	Path   string
+	Reason string
+}
+
+// Comment styles by extension
+var commentStyles = map[string]CommentStyle{
+	// # comments
+	".py":   {SingleLine: "#"},
+	".rb":   {SingleLine: "#"},
+	".sh":   {SingleLine: "#"},
+	".bash": {SingleLine: "#"},
+	".zsh":  {SingleLine: "#"},
+	".yaml": {SingleLine: "#"},
+	".yml":  {SingleLine: "#"},
+	".toml": {SingleLine: "#"},
+	".conf": {SingleLine: "#"},
+	".ini":  {SingleLine: "#"},
+	".r":    {SingleLine: "#"},
+	".pl":   {SingleLine: "#"},
+	".pm":   {SingleLine: "#"},
+
+	// // comments
+	".js":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".ts":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".jsx":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".tsx":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".java":  {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".c":     {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cpp":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cc":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".h":     {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".hpp":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".cs":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".go":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".swift": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".kt":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".scala": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".rs":    {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".dart":  {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".php":   {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+
+	// Markup
+	".html": {BlockStart: "<!--", BlockEnd: "-->"},
+	".xml":  {BlockStart: "<!--", BlockEnd: "-->"},
+	".svg":  {BlockStart: "<!--", BlockEnd: "-->"},
+	".css":  {BlockStart: "/*", BlockEnd: "*/"},
+	".scss": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+	".sass": {SingleLine: "//"},
+	".less": {SingleLine: "//", BlockStart: "/*", BlockEnd: "*/"},
+
+	// Others
+	".sql":  {SingleLine: "--", BlockStart: "/*", BlockEnd: "*/"},
+	".lisp": {SingleLine: ";"},
+	".clj":  {SingleLine: ";"},
+	".scm":  {SingleLine: ";"},
+	".lua":  {SingleLine: "--", BlockStart: "--[[", BlockEnd: "]]"},
+	".bat":  {SingleLine: "REM"},
+	".cmd":  {SingleLine: "REM"},
+	".vb":   {SingleLine: "'"},
+	".m":    {SingleLine: "%"},
+	".tex":  {SingleLine: "%"},
+	".txt":  {SingleLine: "#"},
+	".md":   {BlockStart: "<!--", BlockEnd: "-->"},
+	".rst":  {SingleLine: ".."},
+}
+
+// Binary file extensions
+var binaryExtensions = map[string]bool{
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".bin": true, ".dat": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".bmp": true, ".ico": true,
+	".mp3": true, ".mp4": true, ".wav": true, ".avi": true, ".mov": true, ".flv": true,
+	".zip": true, ".tar": true, ".gz": true, ".bz2": true, ".7z": true, ".rar": true,
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+	".ppt": true, ".pptx": true, ".pyc": true, ".pyo": true, ".class": true,
+	".o": true, ".obj": true,
+}
+
+// Known text file extensions
+var textExtensions = map[string]bool{
+	".js": true, ".ts": true, ".jsx": true, ".tsx": true, ".json": true,
+	".html": true, ".htm": true, ".xml": true, ".css": true, ".scss": true,
+	".sass": true, ".less": true, ".md": true, ".txt": true, ".csv": true,
+	".py": true, ".rb": true, ".java": true, ".c": true, ".cpp": true,
+	".h": true, ".hpp": true, ".go": true, ".rs": true, ".php": true,
+	".sh": true, ".bash": true, ".zsh": true, ".bat": true, ".cmd": true,
+	".ps1": true, ".yaml": true, ".yml": true, ".toml": true, ".ini": true,
+	".conf": true, ".cfg": true, ".sql": true, ".r": true, ".m": true,
+	".pl": true, ".pm": true, ".lua": true, ".swift": true, ".kt": true,
+	".dart": true, ".vue": true, ".svelte": true, ".astro": true,
+	".cs": true, ".vb": true, ".fs": true, ".lisp": true, ".clj": true,
+	".scm": true, ".scala": true, ".erl": true, ".ex": true, ".exs": true,
+	".dockerfile": true, ".gitignore": true, ".env": true, ".editorconfig": true,
+	".rst": true, ".adoc": true, ".textile": true, ".org": true,
+}
+
+
+// selectFiles finds the files to combine and applies the ordering, limits
+// and budgets, returning them with the files that were skipped and why
+func selectFiles(config *Options) ([]string, []FileInfo, error) {
+	files, skipped, patternOrder, err := findCandidates(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Order and cap the selection
+	files = orderFiles(config, files, patternOrder)
+	limitMemory(config, files, selectionMemoryFeatures)
+	files, duplicates := dedupFiles(config, files)
+	skipped = append(skipped, duplicates...)
+	files, overLimit := limitFiles(config, files)
+	skipped = append(skipped, overLimit...)
+	files, overLineBudget := applyExtensionLineBudget(config, files)
+	skipped = append(skipped, overLineBudget...)
+	files, overBudget := applyOutputBudget(config, files)
+	skipped = append(skipped, overBudget...)
+	return files, skipped, nil
+}
+
+// findCandidates finds the files below the root that pass the filters, with
+// the skipped ones and, for every file, the index of the pattern that found it.
+// With several -root directories, Root is their common parent: patterns,
+// excludes, .gitignore rules and -max-depth all see paths relative to it,
+// and only files below one of the roots are candidates.
+func findCandidates(config *Options) ([]string, []FileInfo, map[string]int, error) {
+	// Load gitignore rules
+	var gitignoreRules []gitignoreRule
+	if !config.IgnoreGitignore {
+		gitignoreRules = loadGitignore(config.Root, config.Verbose, func(dir string) bool {
+			return outsideRoots(config, dir)
+		})
+	}
+
+	// Find files
+	if config.Verbose {
+		fmt.Println("Searching for files...")
+	}
+
+	var files []string
+	var skipped []FileInfo
+	var patternOrder map[string]int
+	if config.FilesFrom != "" {
+		// Exactly the listed files, in the listed order
+		listed, err := readFileList(config)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Cannot read --files-from: %v", err)
+		}
+		files, skipped = filterFiles(config, listed, config.Excludes, gitignoreRules)
+	} else if config.GitTracked {
+		// What git considers part of the repository; git already applied
+		// its ignore rules
+		listed, order, err := gitTrackedFiles(config)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("Cannot list the git files: %v", err)
+		}
+		listed, negated := applyNegations(config, listed, order)
+		files, skipped = filterFiles(config, listed, config.Excludes, nil)
+		skipped = append(negated, skipped...)
+		patternOrder = order
+	} else {
+		files, skipped, patternOrder = findFiles(config, config.Excludes, gitignoreRules)
+	}
+	return files, skipped, patternOrder, nil
+}
+
+// matchExcluded reports whether path matches an exclude pattern: as a glob
+// against the relative path (or base name), as a whole path segment, or as a
+// directory prefix. Plain substrings only count with -exclude-substring.
+// With ignoreCase, letter case is not significant.
+func matchExcluded(path, root string, patterns []string, engine string, substring, ignoreCase bool) bool {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	relPath = foldCase(filepath.ToSlash(relPath), ignoreCase)
+
+	for _, pattern := range expandPatterns(patterns) {
+		pattern = foldCase(strings.TrimSpace(pattern), ignoreCase)
+		if pattern == "" {
+			continue
+		}
+
+		// Legacy substring match
+		if substring && strings.Contains(relPath, pattern) {
+			return true
+		}
+
+		// Pattern matching
+		if matchPattern(engine, pattern, relPath) {
+			return true
+		}
+
+		// Directory prefix (src/generated)
+		dir := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+		if strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+
+		// Check parent directories
+		parts := strings.Split(relPath, "/")
+		for _, part := range parts {
+			if part == dir {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// excludedDir reports whether matchExcluded excludes every file below the
+// directory at path, so a walk can skip it. Only the directory matches of
+// matchExcluded (prefix, path segment, substring) qualify: a glob that
+// matches the directory's own name, like *.log for logs.log/, says nothing
+// about the files inside it.
+func excludedDir(path, root string, patterns []string, substring, ignoreCase bool) bool {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil || relPath == "." {
+		return false
+	}
+
+	relPath = foldCase(filepath.ToSlash(relPath), ignoreCase)
+
+	for _, pattern := range expandPatterns(patterns) {
+		pattern = foldCase(strings.TrimSpace(pattern), ignoreCase)
+		if pattern == "" {
+			continue
+		}
+		if substring && strings.Contains(relPath, pattern) {
+			return true
+		}
+		dir := strings.TrimSuffix(strings.TrimPrefix(filepath.ToSlash(pattern), "./"), "/")
+		for _, part := range strings.Split(relPath, "/") {
+			if part == dir {
+				return true
+			}
+		}
+		if relPath == dir || strings.HasPrefix(relPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isBinaryFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	// Check binary extensions
+	if binaryExtensions[ext] {
+		return true
+	}
+
+	// Check text extensions
+	if textExtensions[ext] {
+		return false
+	}
+
+	// Check content
+	file, err := os.Open(path)
+	if err != nil {
+		return true
+	}
+	defer file.Close()
+
+	buffer := make([]byte, BUFFER_SIZE)
+	n, err := file.Read(buffer)
+	if err != nil && err != io.EOF {
+		return true
+	}
+
+	buffer = buffer[:n]
+
+	// Empty file is text
+	if n == 0 {
+		return false
+	}
+
+	// Check for null bytes
+	if bytes.Contains(buffer, []byte{0}) {
+		return true
+	}
+
+	// Check ratio of non-printable characters
+	nonPrintable := 0
+	for _, b := range buffer {
+		if b < 32 && b != 9 && b != 10 && b != 13 {
+			nonPrintable++
+		}
+	}
+
+	ratio := float64(nonPrintable) / float64(len(buffer))
+	return ratio > 0.3
+}
+
+// isBlankFile reports whether a file is empty or holds nothing but
+// whitespace. Reading stops at the first other byte, so real files cost
+// little more than one read.
+func isBlankFile(path string, info os.FileInfo) bool {
+	if info.Size() == 0 {
+		return true
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return err == io.EOF
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\v' {
+			return false
+		}
+	}
+}
+
+// findFiles returns the matching files in path order, the skipped ones with
+// their reasons, and for every file the index of the first pattern matching it
+func findFiles(config *Options, excludes []string, ignore []gitignoreRule) ([]string, []FileInfo, map[string]int) {
+	root, patterns, verbose := config.Root, config.Patterns, config.Verbose
+	allFiles := make(map[string]int)
+	addFile := func(path string, patternIndex int) {
+		if !inRoots(config, path) {
+			return
+		}
+		if first, seen := allFiles[path]; !seen || patternIndex < first {
+			allFiles[path] = patternIndex
+		}
+	}
+	var skipped []FileInfo
+
+	// Directories whose files would all be excluded or ignored are not entered
+	pruneDir := func(path string) bool {
+		relPath, _ := filepath.Rel(root, path)
+		relPath = filepath.ToSlash(relPath)
+		prune := outsideRoots(config, path) ||
+			excludedDir(path, root, excludes, config.ExcludeSubstring, config.IgnoreCase) ||
+			(relPath != "." && beyondMaxDepth(config, relPath+"/")) ||
+			(relPath != "." && gitignoredDir(ignore, relPath)) ||
+			(relPath != "." && filepath.Base(path) == ".git") ||
+			(relPath != "." && config.SkipHidden && isHidden(filepath.Base(path)))
+		if prune && config.Debug {
+			fmt.Printf("  Pruning directory: %s\n", relPath)
+		}
+		return prune
+	}
+
+	if config.Recursive {
+		// Walk the entire tree and match each file's root-relative path
+		// against every pattern with the -glob-engine (doublestar or
+		// segment); patterns without a "/" also match the base name
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() && pruneDir(path) {
+				return filepath.SkipDir
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			// Skip if excluded
+			relPath, _ := filepath.Rel(root, path)
+			relPath = filepath.ToSlash(relPath)
+			if matchExcluded(path, root, excludes, config.GlobEngine, config.ExcludeSubstring, config.IgnoreCase) || gitignored(ignore, relPath) {
+				return nil
+			}
+
+			// Check each pattern
+			for pi, pat := range patterns {
+				if isNegation(pat) {
+					continue
+				}
+				// Handle absolute/literal files in patterns
+				if filepath.IsAbs(pat) || (len(pat) > 0 && pat[0] == '.') {
+					absPat, _ := filepath.Abs(pat)
+					if path == absPat {
+						addFile(path, pi)
+						return nil
+					}
+				}
+
+				// Try glob match with the selected engine
+				if config.matchGlob(pat, relPath) {
+					addFile(path, pi)
+					return nil
+				}
+			}
+			if re := matchRegexps(config.IncludeRegexps, relPath); re != nil {
+				addFile(path, len(patterns)+regexpIndex(config.IncludeRegexps, re))
+			}
+			return nil
+		})
+		if err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "Warning: error during recursive walk: %v\n", err)
+		}
+	} else {
+		// Non-recursive: original glob logic. All "**" patterns share a
+		// single walk of the tree instead of one walk each.
+		walked := make(map[int][]string)
+		var walkIndexes []int
+		var walkPatterns []string
+		for pi, pattern := range patterns {
+			if !isNegation(pattern) && needsWalk(pattern) && validatePattern(config.GlobEngine, pattern) == nil {
+				walkIndexes = append(walkIndexes, pi)
+				walkPatterns = append(walkPatterns, pattern)
+			}
+		}
+		if len(walkPatterns) > 0 {
+			results, err := walkGlobs(root, walkPatterns, config.GlobEngine, config.IgnoreCase, pruneDir)
+			if err != nil && verbose {
+				fmt.Fprintf(os.Stderr, "Warning: error during walk: %v\n", err)
+			}
+			for k, pi := range walkIndexes {
+				walked[pi] = results[k]
+			}
+		}
+
+		for pi, pattern := range patterns {
+			if isNegation(pattern) {
+				continue
+			}
+			matches, done := walked[pi]
+			var err error
+			if !done {
+				matches, err = globFiles(root, pattern, config.GlobEngine, config.IgnoreCase)
+			}
+			if err != nil {
+				skipped = append(skipped, FileInfo{Path: pattern, Reason: fmt.Sprintf("Invalid pattern: %v", err)})
+				continue
+			}
+
+			if len(matches) == 0 {
+				// Check if it's a literal file
+				if info, err := os.Stat(pattern); err == nil && !info.IsDir() {
+					addFile(pattern, pi)
+					continue
+				}
+				absPath := filepath.Join(root, pattern)
+				if info, err := os.Stat(absPath); err == nil && !info.IsDir() {
+					addFile(absPath, pi)
+					continue
+				}
+				if verbose {
+					fmt.Printf("No matches for pattern: %s\n", pattern)
+				}
+				continue
+			}
+
+			for _, m := range matches {
+				addFile(m, pi)
+			}
+		}
+
+		// -pe regexps match at any depth, so they take a walk of their own
+		if len(config.IncludeRegexps) > 0 {
+			filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if info.IsDir() && pruneDir(path) {
+					return filepath.SkipDir
+				}
+				if !info.Mode().IsRegular() {
+					return nil
+				}
+				relPath, _ := filepath.Rel(root, path)
+				if re := matchRegexps(config.IncludeRegexps, filepath.ToSlash(relPath)); re != nil {
+					addFile(path, len(patterns)+regexpIndex(config.IncludeRegexps, re))
+				}
+				return nil
+			})
+		}
+	}
+
+	// Convert map to slice
+	files := make([]string, 0, len(allFiles))
+	for f := range allFiles {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	files, negated := applyNegations(config, files, allFiles)
+	skipped = append(skipped, negated...)
+
+	results, filtered := filterFiles(config, files, excludes, ignore)
+	return results, append(skipped, filtered...), allFiles
+}
+
+// filterFiles applies the exclusion, size, type and content checks to the
+// candidate files, keeping their order
+func filterFiles(config *Options, files []string, excludes []string, ignore []gitignoreRule) ([]string, []FileInfo) {
+	root, maxSize, verbose := config.Root, config.MaxSize, config.Verbose
+	var skipped []FileInfo
+
+	var attrRules []gitAttrRule
+	if config.RespectGitattributes {
+		attrRules = loadGitattributes(root, verbose)
+	}
+
+	// Hardlink identities of the files kept so far, bucketed by size
+	type keptFile struct {
+		path string
+		info os.FileInfo
+	}
+	keptBySize := make(map[int64][]keptFile)
+
+	// Final filtering: size, binary, etc.
+	var results []string
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Stat error: %v", err)})
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		if relPath, err := filepath.Rel(root, file); err == nil && config.SkipHidden && hiddenPath(filepath.ToSlash(relPath)) {
+			skipped = append(skipped, FileInfo{file, "Hidden (--hidden=false)"})
+			continue
+		}
+		if relPath, err := filepath.Rel(root, file); err == nil && beyondMaxDepth(config, filepath.ToSlash(relPath)) {
+			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Deeper than --max-depth %d", config.MaxDepth)})
+			continue
+		}
+		if reason := unchangedReason(config, file); reason != "" {
+			skipped = append(skipped, FileInfo{file, reason})
+			continue
+		}
+		if matchExcluded(file, root, excludes, config.GlobEngine, config.ExcludeSubstring, config.IgnoreCase) {
+			skipped = append(skipped, FileInfo{file, "Excluded"})
+			continue
+		}
+		if containsName(config, config.ExcludeNames, filepath.Base(file)) {
+			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Excluded name (%s)", filepath.Base(file))})
+			continue
+		}
+		if len(config.ExcludeRegexps) > 0 {
+			relPath, _ := filepath.Rel(root, file)
+			if re := matchRegexps(config.ExcludeRegexps, filepath.ToSlash(relPath)); re != nil {
+				skipped = append(skipped, FileInfo{file, fmt.Sprintf("Excluded by --ere %s", re)})
+				continue
+			}
+		}
+		if relPath, err := filepath.Rel(root, file); err == nil && gitignored(ignore, filepath.ToSlash(relPath)) {
+			skipped = append(skipped, FileInfo{file, "Ignored (.gitignore)"})
+			continue
+		}
+		if info.Size() > maxSize {
+			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Too large (%.1f MB)", float64(info.Size())/1024/1024)})
+			continue
+		}
+		if info.Size() < config.MinSize {
+			skipped = append(skipped, FileInfo{file, fmt.Sprintf("Too small (%d bytes)", info.Size())})
+			continue
+		}
+		if config.SkipEmpty && isBlankFile(file, info) {
+			skipped = append(skipped, FileInfo{file, "Empty file"})
+			continue
+		}
+		if reason := modTimeSkipReason(config, info); reason != "" {
+			skipped = append(skipped, FileInfo{file, reason})
+			continue
+		}
+		if config.Preset != "" {
+			relPath, _ := filepath.Rel(root, file)
+			if reason := presetSkipReason(config.Preset, filepath.ToSlash(relPath)); reason != "" {
+				skipped = append(skipped, FileInfo{file, reason})
+				continue
+			}
+		}
+		if len(config.Languages) > 0 || len(config.ExcludeLanguages) > 0 {
+			lang := detectLanguage(file)
+			if len(config.Languages) > 0 && !containsString(config.Languages, lang) {
+				skipped = append(skipped, FileInfo{file, "Language not selected"})
+				continue
+			}
+			if containsString(config.ExcludeLanguages, lang) {
+				skipped = append(skipped, FileInfo{file, fmt.Sprintf("Excluded language (%s)", lang)})
+				continue
+			}
+		}
+		relPath, _ := filepath.Rel(root, file)
+		binary, declared := gitattributesBinary(attrRules, filepath.ToSlash(relPath))
+		if !declared {
+			binary = isBinaryFile(file)
+		}
+		if binary && config.IncludeBinary {
+			if config.run.binary == nil {
+				config.run.binary = make(map[string]bool)
+			}
+			config.run.binary[file] = true
+		} else if binary {
+			reason := "Binary file"
+			if declared {
+				reason = "Binary file (.gitattributes)"
+			}
+			skipped = append(skipped, FileInfo{file, reason})
+			continue
+		}
+		if config.SkipMinified && !binary {
+			if minified, why := detectMinified(file, config.MinifiedLineLength, config.MinifiedMinSize); minified {
+				skipped = append(skipped, FileInfo{file, fmt.Sprintf("Minified (%s)", why)})
+				continue
+			}
+		}
+		if reason := grepSkipReason(config, file); reason != "" {
+			skipped = append(skipped, FileInfo{file, reason})
+			continue
+		}
+		if config.DedupHardlinks {
+			duplicate := ""
+			for _, kept := range keptBySize[info.Size()] {
+				if os.SameFile(kept.info, info) {
+					duplicate = kept.path
+					break
+				}
+			}
+			if duplicate != "" {
+				relDup, _ := filepath.Rel(root, duplicate)
+				skipped = append(skipped, FileInfo{file, fmt.Sprintf("Hardlink of %s", filepath.ToSlash(relDup))})
+				continue
+			}
+			keptBySize[info.Size()] = append(keptBySize[info.Size()], keptFile{file, info})
+		}
+		results = append(results, file)
+	}
+
+	return results, skipped
+}
+
+// containsName reports whether a file name is in the list, ignoring case
+// with -ignore-case
+func containsName(config *Options, list []string, name string) bool {
+	if !config.IgnoreCase {
+		return containsString(list, name)
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func getCommentStyle(path string) CommentStyle {
+	ext := strings.ToLower(filepath.Ext(path))
+	if style, ok := commentStyles[ext]; ok {
+		return style
+	}
+	return CommentStyle{SingleLine: "#"}
+}
+
+func createSeparator(config *Options, path string, index int, style CommentStyle, content []byte) string {
+	relPath, _ := filepath.Rel(config.Root, path)
+	relPath = collapsePath(filepath.ToSlash(relPath), config.CollapseDepth)
+
+	separator := "\n"
+	meta := separatorMetaLines(config, path)
+	if marksFinalNewline(config) && missingFinalNewline(content) {
+		meta = append(meta, NO_FINAL_NEWLINE)
+	}
+
+	if style.BlockStart != "" && style.BlockEnd != "" {
+		separator += fmt.Sprintf("%s\n FILE %d: %s\n", style.BlockStart, index, relPath)
+		if !config.NoTimestamp {
+			separator += fmt.Sprintf(" Combined at: %s\n", config.combineTime().Format("2006-01-02 15:04:05"))
+		}
+		for _, m := range meta {
+			separator += " " + m + "\n"
+		}
+		separator += style.BlockEnd + "\n\n"
+	} else if style.SingleLine != "" {
+		line := strings.Repeat("=", 70)
+		separator += fmt.Sprintf("%s %s\n%s FILE %d: %s\n", style.SingleLine, line, style.SingleLine, index, relPath)
+		if !config.NoTimestamp {
+			separator += fmt.Sprintf("%s Combined at: %s\n", style.SingleLine, config.combineTime().Format("2006-01-02 15:04:05"))
+		}
+		for _, m := range meta {
+			separator += style.SingleLine + " " + m + "\n"
+		}
+		separator += fmt.Sprintf("%s %s\n\n", style.SingleLine, line)
+	} else {
+		line := strings.Repeat("=", 70)
+		separator += fmt.Sprintf("%s\n FILE %d: %s\n", line, index, relPath)
+		for _, m := range meta {
+			separator += " " + m + "\n"
+		}
+		separator += line + "\n\n"
+	}
+
+	return separator
+}
+
+// combineTime is the single timestamp used for every separator of a run.
+// SOURCE_DATE_EPOCH (validated in Options.Validate) pins it for reproducible builds.
+func (config *Options) combineTime() time.Time {
+	if config.run != nil && !config.run.time.IsZero() {
+		return config.run.time
+	}
+	return runTime()
+}
+
+// runTime is the timestamp a run starts with
+func runTime() time.Time {
+	if epoch, ok := sourceDateEpoch(); ok {
+		return time.Unix(epoch, 0).UTC()
+	}
+	return time.Now()
+}
+
+// fileModTime is a file's modification time as recorded in the output.
+// Checkouts give identical content different mtimes, so -no-timestamp
+// leaves them out (ok is false), and SOURCE_DATE_EPOCH caps them at its time.
+func fileModTime(config *Options, info os.FileInfo) (time.Time, bool) {
+	if config.NoTimestamp {
+		return time.Time{}, false
+	}
+	mtime := info.ModTime()
+	if epoch, ok := sourceDateEpoch(); ok && mtime.Unix() > epoch {
+		mtime = time.Unix(epoch, 0).UTC()
+	}
+	return mtime, true
+}
+
+// sourceDateEpoch returns the SOURCE_DATE_EPOCH timestamp, if set and valid
+func sourceDateEpoch() (int64, bool) {
+	value := os.Getenv("SOURCE_DATE_EPOCH")
+	if value == "" {
+		return 0, false
+	}
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	return epoch, err == nil && epoch >= 0
+}
+
+func getNewline(newlineType string) string {
+	switch strings.ToLower(newlineType) {
+	case "crlf", "\\r\\n":
+		return "\r\n"
+	case "cr", "\\r":
+		return "\r"
+	default:
+		return "\n"
+	}
+}
+
+func combineFiles(config *Options, files []string) int {
+	// 1. Removes the output file from the input list
+	files = withoutOutput(config, files)
+
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: No files to combine after filtering")
+		return 1
+	}
+
+	// 2. Switch off what would hold the whole output in memory
+	limitMemory(config, files, outputMemoryFeatures)
+	outputIsClipboard := config.Output == "c"
+
+	// 3. Process the content and write it to the destination (Command, Clipboard or File)
+	var successCount, errorCount int
+	destination := config.Output
+
+	if config.PipeTo != "" {
+		var err error
+		destination = fmt.Sprintf("command %q", config.PipeTo)
+		successCount, errorCount, err = pipeToCommand(config, files, config.PipeTo)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else if outputIsClipboard {
+		var combinedContent bytes.Buffer
+		successCount, errorCount = writeOutput(&combinedContent, config, files)
+
+		// Output to Clipboard (Assuming package 'clipboard' is available)
+		// Need to import: import "github.com/atotto/clipboard"
+		err := clipboard.WriteAll(combinedContent.String()) // Use string for clipboard
+		if err != nil {
+			fmt.Println("Failed to write content to clipboard!")
+			return 2
+		} else {
+			fmt.Println("Content has been written to clipboard!")
+		}
+	} else if config.Output == "-" {
+		// Stream to the real stdout; the command points os.Stdout at stderr for -o -
+		destination = "stdout"
+		writer := bufio.NewWriter(config.stdout())
+		signed, sign := signOutput(config, writer)
+		out, closeOut := compressOutput(config, signed)
+		successCount, errorCount = writeOutput(out, config, files)
+		if err := closeOut(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to compress output: %v\n", err)
+			return 2
+		}
+		if err := sign(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to sign output: %v\n", err)
+			return 2
+		}
+		if err := writer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to write to stdout: %v\n", err)
+			return 2
+		}
+	} else if config.Append {
+		var err error
+		successCount, errorCount, err = appendOutput(config, files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else if config.Resume {
+		var err error
+		successCount, errorCount, err = resumeOutput(config, files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+	} else if config.splitsOutput() {
+		var parts []string
+		var err error
+		successCount, errorCount, parts, err = writeParts(config, files)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		destination = fmt.Sprintf("%d parts: %s", len(parts), strings.Join(parts, ", "))
+	} else if config.Output != "" {
+		// Output ke File, streamed as the sections are rendered
+		outFile, err := createOutputFile(config.Output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		defer outFile.Close()
+
+		writer := bufio.NewWriter(outFile)
+		defer writer.Flush()
+		signed, sign := signOutput(config, writer)
+		out, closeOut := compressOutput(config, signed)
+		successCount, errorCount = writeOutput(out, config, files)
+		if err := closeOut(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to compress output: %v\n", err)
+			return 2
+		}
+		if err := sign(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to sign output: %v\n", err)
+			return 2
+		}
+	} else {
+        // Case when config.Output is empty and not 'c'.
+		fmt.Fprintln(os.Stderr, "Error: Output target is not defined.")
+		return 2
+	}
+
+	// 4. Statistical Output (-watch rebuilds print their own line)
+	if config.Rebuilding {
+		return 0
+	}
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("SUCCESS: Combined %d files into %s\n", successCount, destination)
+	fmt.Printf("Output size: %s\n", config.run.totals.describe(config))
+	if config.Gzip {
+		level := "default"
+		if config.GzipLevel != gzip.DefaultCompression {
+			level = strconv.Itoa(config.GzipLevel)
+		}
+		fmt.Printf("Compressed : %s (gzip level %s)\n", formatSize(config.run.compressed), level)
+	}
+	if errorCount > 0 {
+		fmt.Printf("WARNING: %d files were skipped due to errors\n", errorCount)
+	}
+	var mixed []mixedNewlineFile
+	if config.WarnMixedNewlines {
+		mixed = config.run.newlines.mixed(files)
+		fmt.Printf("Mixed newlines: %d files\n", len(mixed))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	printMixedNewlines(config.Root, mixed)
+
+	if config.Debug {
+		config.run.timings.printSlowest(config.Root, 5)
+	}
+
+	return 0
+}
+
+// withoutOutput removes the output file itself (and its parts) from the
+// input list
+func withoutOutput(config *Options, files []string) []string {
+	if config.Output == "-" {
+		return files
+	}
+	absOutput, _ := filepath.Abs(config.Output)
+	var filteredFiles []string
+	for _, file := range files {
+		absFile, _ := filepath.Abs(file)
+		if config.splitsOutput() && isPartFile(config, file) {
+			continue
+		}
+		if absFile != absOutput {
+			filteredFiles = append(filteredFiles, file)
+		}
+	}
+	return filteredFiles
+}
+
+// writeCombined renders every file (separator and content) into w and
+// returns the number of files written and the number skipped due to errors
+func writeCombined(w io.Writer, config *Options, files []string) (int, int) {
+	// Document header, written once before the first file (not when appending)
+	if !config.Continuing {
+		if header := createDocumentHeader(config, files); header != "" {
+			io.WriteString(w, header)
+			config.run.regions.addBase(int64(len(header)))
+		}
+	}
+
+	// Render sections concurrently into shards when several jobs were requested
+	var successCount, errorCount int
+	if config.Jobs > 1 && len(files) > 1 {
+		successCount, errorCount = writeSharded(w, config, files)
+	} else {
+		successCount, errorCount = writeSerial(w, config, files)
+	}
+
+	io.WriteString(w, createDocumentFooter(config, files))
+	return successCount, errorCount
+}
+
+// writeSerial writes the separator and content of every file, one after another
+func writeSerial(w io.Writer, config *Options, files []string) (int, int) {
+	// Count what is written so -manifest can locate each file's content
+	out := &countingWriter{w: w}
+	w = out
+	newline := getNewline(config.newlineType())
+	successCount := 0
+	errorCount := 0
+	previous := ""
+
+	for idx, filePath := range files {
+		if config.canceled() {
+			break
+		}
+		if config.Verbose {
+			fmt.Printf("Processing [%d/%d]: %s\n", idx+1, len(files), filepath.Base(filePath))
+		}
+
+		// Read files
+		readStart := time.Now()
+		content, err := loadContent(config, filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
+			errorCount++
+			continue
+		}
+		readTime := time.Since(readStart)
+		writeStart := time.Now()
+
+		// Announce a new directory, then add the separator (optionally not before the first file)
+		io.WriteString(w, directoryBanner(config, previous, filePath))
+		previous = filePath
+		index := config.IndexOffset + idx + 1
+		if !(config.NoLeadingSeparator && successCount == 0 && !config.Continuing) {
+			io.WriteString(w, sectionSeparator(config, filePath, index, content))
+		}
+		bodyStart := out.n
+		contentStart := writeSectionBody(w, config, filePath, index, content, newline)
+		config.run.regions.record(filePath, bodyStart+contentStart, int64(len(content)))
+		if config.Debug {
+			config.run.timings.record(filePath, readTime, time.Since(writeStart))
+		}
+
+		successCount++
+	}
+
+	return successCount, errorCount
+}
+
+// loadContent reads a file and applies the configured content transformations
+func loadContent(config *Options, filePath string) ([]byte, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if config.isBinary(filePath) {
+		return encodeBinary(filePath, content), nil
+	}
+
+	// Cut path:start-end files down to their range; the marker noting the
+	// range is put in front once everything else is done
+	firstLine := 1
+	var marker []byte
+	if abs, _ := filepath.Abs(filePath); config.LineRanges != nil {
+		if r, ok := config.LineRanges[abs]; ok {
+			if marker, content, err = applyLineRange(filePath, content, r); err != nil {
+				return nil, err
+			}
+			firstLine = r.Start
+		}
+	}
+
+	if config.ImportsOnly {
+		content = extractImports(filePath, content)
+	}
+	if config.NormalizeIndent != "" {
+		if style, ok := detectIndent(content); !ok {
+			if config.Verbose {
+				fmt.Printf("  Indentation of %s unclear; left unchanged\n", filepath.Base(filePath))
+			}
+		} else if config.TargetIndent != nil {
+			if config.Verbose {
+				fmt.Printf("  Reindenting %s: %s -> %s\n", filepath.Base(filePath), style, *config.TargetIndent)
+			}
+			content = reindent(content, style, *config.TargetIndent)
+		} else if config.Verbose {
+			fmt.Printf("  Indentation of %s: %s\n", filepath.Base(filePath), style)
+		}
+	}
+	if config.LineNumbers {
+		content = numberLines(content, firstLine)
+	}
+	config.recordNewlines(filePath, content)
+	if marker != nil {
+		content = append(marker, content...)
+	}
+	if config.NormalizeNewlines {
+		content = normalizeNewlines(content, getNewline(config.newlineType()))
+	}
+	return content, nil
+}
+
+// sectionSeparator returns the separator written before a file, or "" with -no-separator
+func sectionSeparator(config *Options, filePath string, index int, content []byte) string {
+	if config.Format == FORMAT_XML {
+		return xmlFileTag(config, filePath)
+	}
+	if config.NoSeparator {
+		return ""
+	}
+	if config.separatorTemplate() != nil {
+		return renderSeparatorTemplate(config, filePath, index)
+	}
+	style := getCommentStyle(filePath)
+	if config.SeparatorFormat != "" {
+		separator, _ := renderSeparatorFormat(config.SeparatorFormat, separatorFields(filePath, config.Root, index), style)
+		return separator
+	}
+	if config.Format == FORMAT_MARKDOWN {
+		return markdownHeading(config, filePath)
+	}
+	return createSeparator(config, filePath, index, style, content)
+}
+
+// writeSectionBody writes a file's content, wrapped in the optional content
+// prefix/suffix, and returns the offset of the content within what it wrote.
+// The prefix and suffix sit right around the content, inside a Markdown
+// code block or an XML <file> element.
+func writeSectionBody(w io.Writer, config *Options, filePath string, index int, content []byte, newline string) int64 {
+	var contentStart int64
+	fields := fileFields(filePath, config.Root, index)
+	prefix, suffix := "", ""
+	if config.ContentPrefix != "" {
+		prefix, _ = expandPlaceholders(config.ContentPrefix, fields)
+		prefix += newline
+	}
+	if config.ContentSuffix != "" {
+		suffix, _ = expandPlaceholders(config.ContentSuffix, fields)
+		suffix += newline
+	}
+
+	// Markdown puts the content in a fenced code block
+	fence := ""
+	if config.Format == FORMAT_MARKDOWN {
+		fence = markdownFence([]byte(prefix + suffix))
+		if other := markdownFence(content); len(other) > len(fence) {
+			fence = other
+		}
+		opening := fence + detectLanguage(filePath) + newline
+		io.WriteString(w, opening)
+		contentStart += int64(len(opening))
+	}
+
+	io.WriteString(w, prefix)
+	contentStart += int64(len(prefix))
+
+	// Write content
+	w.Write(content)
+
+	// Ensure newline at the end
+	if missingFinalNewline(content) {
+		io.WriteString(w, newline)
+	}
+	io.WriteString(w, suffix)
+
+	if fence != "" {
+		io.WriteString(w, fence+newline)
+	}
+	if config.Format == FORMAT_XML {
+		io.WriteString(w, "</file>"+newline)
+	}
+	return contentStart
+}
+
+// createOutputFile creates (or truncates) the output file, creating its directory if necessary
+func createOutputFile(path string) (*os.File, error) {
+	outputDir := filepath.Dir(path)
+	if outputDir != "." { // Cek apakah ada direktori selain direktori saat ini
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, fmt.Errorf("Cannot create output directory: %v", err)
+		}
+	}
+
+	outFile, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create output file: %v", err)
+	}
+	return outFile, nil
+}
+
+// ParseSize parses a byte size such as 1048576, 512KB, 64MB or 2G
+func ParseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.mult
+			s = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+			break
+		}
+	}
+
+	val, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return val * multiplier, nil
+}
+
+// formatSize renders a byte count in a human friendly unit
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// PrintFileList writes the selected paths, one per line (or NUL separated), with no decoration
+func PrintFileList(config *Options, files []string) {
+	terminator := "\n"
+	if config.NullSeparated {
+		terminator = "\x00"
+	}
+
+	writer := bufio.NewWriter(config.stdout())
+	defer writer.Flush()
+	for _, file := range files {
+		path := file
+		if config.PathStyle == "absolute" {
+			path, _ = filepath.Abs(file)
+		} else if relPath, err := filepath.Rel(config.Root, file); err == nil {
+			path = relPath
+		}
+		writer.WriteString(path + terminator)
+	}
+}
+
+func PrintSummary(config *Options, report Report) {
+	files, skipped := report.Files, report.Skipped
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Println("COMBINE FILES - SUMMARY")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Root directory    : %s\n", config.Root)
+	if config.PipeTo != "" {
+		fmt.Printf("Output command    : %s\n", config.PipeTo)
+	} else {
+		fmt.Printf("Output file       : %s\n", config.Output)
+	}
+	fmt.Printf("Search patterns   : %s\n", strings.Join(config.Patterns, ", "))
+	fmt.Printf("Files found       : %d\n", len(files))
+	fmt.Printf("Files excluded    : %d\n", len(skipped))
+	if config.LargestFirst {
+		fmt.Printf("Selection         : Showing %d largest files\n", len(files))
+	}
+	if report.TreeHash != "" {
+		fmt.Printf("Tree hash         : sha256:%s\n", report.TreeHash)
+	}
+	if config.DryRun {
+		fmt.Printf("Mode    	          : DRY-RUN (no changes)\n")
+	} else {
+		fmt.Printf("Mode              : EXECUTION\n")
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	if len(skipped) > 0 {
+		fmt.Println("\nEXCLUDED FILES (showing first 15):")
+		limit := len(skipped)
+		if limit > 15 {
+			limit = 15
+		}
+		for i := 0; i < limit; i++ {
+			relPath, _ := filepath.Rel(config.Root, skipped[i].Path)
+			fmt.Printf("  × %s\n", relPath)
+			fmt.Printf("    Reason: %s\n", skipped[i].Reason)
+		}
+		if len(skipped) > 15 {
+			fmt.Printf("  ... and %d more files\n\n", len(skipped)-15)
+		}
+	}
+
+	if config.ListExtensions && len(files) > 0 {
+		printExtensionStats(files)
+	}
+
+	if config.CloneReport && len(files) > 0 {
+		printCloneReport(config.Root, files, config.CloneMinLines)
+	}
+
+	if config.DryRun && len(files) > 0 {
+		fmt.Println("\nFILES TO BE COMBINED (showing first 20):")
+		limit := len(files)
+		if limit > 20 {
+			limit = 20
+		}
+		sections := sectionTotals(config, files)
+		running := newTotals(config)
+		for i := 0; i < len(files); i++ {
+			running.add(sections[i])
+			if i >= limit {
+				continue
+			}
+			relPath, _ := filepath.Rel(config.Root, files[i])
+			info, _ := os.Stat(files[i])
+			sizeKB := float64(info.Size()) / 1024
+			fmt.Printf("  ✓ %s (%.1f KB, ~%d tokens, running total %s ~%d tokens)\n", relPath, sizeKB, sections[i].tokens(config), formatSize(running.Bytes), running.tokens(config))
+		}
+		if len(files) > 20 {
+			fmt.Printf("  ... and %d more files\n", len(files)-20)
+		}
+		fmt.Printf("\nTotal: %d files will be combined (%s, ~%d tokens, %s)\n", len(files), formatSize(running.Bytes), running.tokens(config), config.TokenEstimator)
+	}
+}
@@ -0,0 +1,354 @@
+package combiner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchExcluded(t *testing.T) {
+	root := filepath.FromSlash("/project")
+	tests := []struct {
+		name       string
+		path       string
+		patterns   []string
+		substring  bool
+		ignoreCase bool
+		want       bool
+	}{
+		{"segment does not match inside a name", "catalog.go", []string{"log"}, false, false, false},
+		{"segment matches a directory", "log/app.go", []string{"log"}, false, false, true},
+		{"segment matches a nested directory", "src/log/app.go", []string{"log"}, false, false, true},
+		{"test excludes only the test directory", "src/latest/config.go", []string{"test"}, false, false, false},
+		{"test leaves contest.py", "contest.py", []string{"test"}, false, false, false},
+		{"substring is opt-in", "catalog.go", []string{"log"}, true, false, true},
+		{"glob against the base name", "src/app_test.go", []string{"*_test.go"}, false, false, true},
+		{"glob against the relative path", "src/gen/api.go", []string{"src/gen/*.go"}, false, false, true},
+		{"double star crosses directories", "a/b/gen/api.go", []string{"**/gen/*.go"}, false, false, true},
+		{"double star keeps the suffix", "a/b/gen/api.txt", []string{"**/gen/*.go"}, false, false, false},
+		{"directory prefix", "src/generated/x.go", []string{"src/generated"}, false, false, true},
+		{"prefix with ./ and a slash", "src/generated/x.go", []string{"./src/generated/"}, false, false, true},
+		{"case matters by default", "Vendor/x.go", []string{"vendor"}, false, false, false},
+		{"ignore case", "Vendor/x.go", []string{"vendor"}, false, true, true},
+		{"brace groups", "a.log", []string{"*.{log,tmp}"}, false, false, true},
+	}
+	for _, engine := range []string{GLOB_STANDARD, GLOB_DOUBLESTAR} {
+		for _, tt := range tests {
+			t.Run(engine+"/"+tt.name, func(t *testing.T) {
+				path := filepath.Join(root, filepath.FromSlash(tt.path))
+				got := matchExcluded(path, root, tt.patterns, engine, tt.substring, tt.ignoreCase)
+				if got != tt.want {
+					t.Errorf("matchExcluded(%q, %q) = %v, want %v", tt.path, tt.patterns, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestExcludedDir(t *testing.T) {
+	root := filepath.FromSlash("/project")
+	tests := []struct {
+		dir      string
+		patterns []string
+		want     bool
+	}{
+		{"node_modules", []string{"node_modules"}, true},
+		{"src/vendor", []string{"vendor"}, true},
+		{"src/generated", []string{"src/generated"}, true},
+		{"logs.log", []string{"*.log"}, false}, // says nothing about the files inside
+		{"catalog", []string{"log"}, false},
+		{"web/node_modules/pkg", []string{"node_modules"}, true},
+		{"node_modules_extra", []string{"node_modules"}, false},
+		{"src/generated", []string{"./src/generated/"}, true},
+		{"src", []string{"src/generated"}, false}, // files in src may still be wanted
+		{".", []string{"."}, false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(root, filepath.FromSlash(tt.dir))
+		if got := excludedDir(path, root, tt.patterns, false, false); got != tt.want {
+			t.Errorf("excludedDir(%q, %q) = %v, want %v", tt.dir, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestIsBlankFile(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"", true},
+		{"\n", true},
+		{" \t\r\n\f\v\n\n", true},
+		{"x", false},
+		{"\n\n  x\n", false},
+		{"\u00a0", false}, // a no-break space is content
+		{"\x00", false},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		path := filepath.Join(dir, string(rune('a'+i)))
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isBlankFile(path, info); got != tt.want {
+			t.Errorf("isBlankFile(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestSizeFilters(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"empty.txt":  "",
+		"blank.txt":  "  \n\n",
+		"stub.txt":   "x\n",
+		"medium.txt": "medium sized\n",
+		"large.txt":  "a larger file than the others\n",
+	})
+	tests := []struct {
+		name        string
+		minSize     int64
+		maxSize     int64
+		skipEmpty   bool
+		want        []string
+		wantSkipped map[string]string
+	}{
+		{name: "defaults", want: []string{"blank.txt", "empty.txt", "large.txt", "medium.txt", "stub.txt"}},
+		{
+			name:        "skip empty",
+			skipEmpty:   true,
+			want:        []string{"large.txt", "medium.txt", "stub.txt"},
+			wantSkipped: map[string]string{"empty.txt": "Empty file", "blank.txt": "Empty file"},
+		},
+		{
+			name:        "min size",
+			minSize:     3,
+			want:        []string{"blank.txt", "large.txt", "medium.txt"},
+			wantSkipped: map[string]string{"empty.txt": "Too small (0 bytes)", "stub.txt": "Too small (2 bytes)"},
+		},
+		{
+			name:        "min size is inclusive",
+			minSize:     13,
+			want:        []string{"large.txt", "medium.txt"},
+			wantSkipped: map[string]string{"empty.txt": "Too small (0 bytes)", "blank.txt": "Too small (4 bytes)", "stub.txt": "Too small (2 bytes)"},
+		},
+		{
+			name:      "min and max size",
+			minSize:   3,
+			maxSize:   13,
+			skipEmpty: true,
+			want:      []string{"medium.txt"},
+			wantSkipped: map[string]string{
+				"empty.txt": "Too small (0 bytes)",
+				"blank.txt": "Empty file",
+				"stub.txt":  "Too small (2 bytes)",
+				"large.txt": "Too large (0.0 MB)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.MinSize = tt.minSize
+			config.SkipEmpty = tt.skipEmpty
+			if tt.maxSize > 0 {
+				config.MaxSize = tt.maxSize
+			}
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if got := relFiles(t, root, report.Files); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+			if len(report.Skipped) != len(tt.wantSkipped) {
+				t.Errorf("skipped %v, want %v", report.Skipped, tt.wantSkipped)
+			}
+			for _, file := range report.Skipped {
+				name := filepath.Base(file.Path)
+				if want := tt.wantSkipped[name]; file.Reason != want {
+					t.Errorf("%s skipped with %q, want %q", name, file.Reason, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStdoutOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n", "sub/b.md": "# b\n"})
+	tests := []struct {
+		name  string
+		setup func(*Options)
+	}{
+		{"text", nil},
+		{"markdown", func(config *Options) { config.Format = FORMAT_MARKDOWN }},
+		{"json", func(config *Options) { config.Format = FORMAT_JSON }},
+		{"title and toc", func(config *Options) { config.Title = "T"; config.TOC = true }},
+		{"jobs", func(config *Options) { config.Jobs = 2 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileConfig := testOptions(t, root, "**/*")
+			if tt.setup != nil {
+				tt.setup(fileConfig)
+			}
+			want := combine(t, fileConfig)
+
+			// Nothing named "-" is created, and the summary stays off the data
+			t.Chdir(t.TempDir())
+			var stdout bytes.Buffer
+			config := testOptions(t, root, "**/*")
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			config.Output = "-"
+			config.Stdout = &stdout
+			config.Rebuilding = false
+			summary := captureStdout(t, func() {
+				if _, err := New(config).Run(context.Background()); err != nil {
+					t.Errorf("Run: %v", err)
+				}
+			})
+			if stdout.String() != want {
+				t.Errorf("stdout:\n%s\nwant:\n%s", stdout.String(), want)
+			}
+			if !strings.Contains(summary, "stdout") {
+				t.Errorf("summary = %q", summary)
+			}
+			if _, err := os.Stat("-"); !os.IsNotExist(err) {
+				t.Errorf("a file named - exists: %v", err)
+			}
+		})
+	}
+}
+
+func TestStdoutNeedsFileOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*Options)
+		wantErr string
+	}{
+		{"append", func(config *Options) { config.Append = true }, "--append needs a file output"},
+		{"resume", func(config *Options) { config.Resume = true }, "--resume needs a file output"},
+		{"split", func(config *Options) { config.SplitLines = 10 }, "they need -o FILE"},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, t.TempDir(), "*")
+		config.Output = "-"
+		tt.setup(config)
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Validate = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReproducibleOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n", "sub/b.txt": "b\n"})
+	tests := []struct {
+		name        string
+		noTimestamp bool
+		epoch       string
+		want        []string // in the output
+		wantNot     []string
+		identical   bool
+	}{
+		{
+			name:      "timestamps",
+			want:      []string{"Combined at: ", "Modified: "},
+			identical: false,
+		},
+		{
+			name:        "no timestamps",
+			noTimestamp: true,
+			wantNot:     []string{"Combined at: ", "Modified: "},
+			identical:   true,
+		},
+		{
+			name:      "SOURCE_DATE_EPOCH",
+			epoch:     "1700000000",
+			want:      []string{"Combined at: 2023-11-14 22:13:20", "Modified: 2023-11-14 22:13:20"},
+			identical: true,
+		},
+		{
+			name:        "no timestamps wins over SOURCE_DATE_EPOCH",
+			noTimestamp: true,
+			epoch:       "1700000000",
+			wantNot:     []string{"Combined at: ", "Modified: "},
+			identical:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+			dir := t.TempDir()
+			var outputs, manifests []string
+			for run := 0; run < 2; run++ {
+				// Each run sees a newer mtime and a later clock
+				now := time.Now().Add(time.Duration(run) * time.Hour)
+				for _, name := range []string{"a.go", "sub/b.txt"} {
+					if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(name)), now, now); err != nil {
+						t.Fatal(err)
+					}
+				}
+				config := testOptions(t, root, "**/*")
+				config.NoTimestamp = tt.noTimestamp
+				config.SeparatorMeta = []string{META_MTIME}
+				config.Output = filepath.Join(dir, "out.txt")
+				config.Manifest = filepath.Join(dir, "manifest.json")
+				outputs = append(outputs, combine(t, config))
+				data, err := os.ReadFile(config.Manifest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				manifests = append(manifests, string(data))
+				if !tt.identical && run == 0 {
+					time.Sleep(1100 * time.Millisecond) // the separators show whole seconds
+				}
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(outputs[0], want) {
+					t.Errorf("output lacks %q:\n%s", want, outputs[0])
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(outputs[0], unwanted) {
+					t.Errorf("output has %q:\n%s", unwanted, outputs[0])
+				}
+			}
+			if identical := outputs[0] == outputs[1] && manifests[0] == manifests[1]; identical != tt.identical {
+				t.Errorf("identical runs = %v, want %v:\n%s\n%s", identical, tt.identical, outputs[0], outputs[1])
+			}
+		})
+	}
+}
+
+func TestSourceDateEpochValidation(t *testing.T) {
+	tests := []struct {
+		epoch string
+		valid bool
+	}{
+		{"", true},
+		{"0", true},
+		{"1700000000", true},
+		{"-1", false},
+		{"1.5", false},
+		{"yesterday", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+		err := testOptions(t, t.TempDir(), "*").Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("SOURCE_DATE_EPOCH=%q: Validate = %v", tt.epoch, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "invalid SOURCE_DATE_EPOCH") {
+			t.Errorf("SOURCE_DATE_EPOCH=%q: error %v", tt.epoch, err)
+		}
+	}
+}
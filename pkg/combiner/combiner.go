@@ -0,0 +1,482 @@
+// Package combiner merges the files matching a set of glob patterns into a
+// single document, with a separator naming each file. It is the engine
+// behind the combine command; Options mirrors the command's flags.
+package combiner
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Combiner runs a combine with a fixed set of options
+type Combiner struct {
+	Options *Options
+
+	mu   sync.Mutex // one run at a time when serving
+	last *runState  // what the latest Combine recorded, for the serve and -watch reports
+}
+
+// runState is what one Select or Combine works out and records on its copy
+// of the options: the timestamp every separator shares, what the selection
+// found, and the figures for the report and the -manifest. Keeping it off
+// the caller's Options lets a Combiner run again from a clean slate.
+type runState struct {
+	ctx        context.Context // stops the writers between files
+	time       time.Time
+	totals     outputTotals
+	compressed int64 // size of the -gzip stream
+	regions    regionLog
+	timings    timingLog
+	newlines   newlineLog // what -warn-mixed-newlines found while reading
+
+	newlineType       string               // the output newline, with "auto" resolved
+	skipped           []FileInfo           // the excluded files, for -tree-skipped
+	binary            map[string]bool      // files kept by -include-binary, written as base64
+	treeHash          string               // computed by -tree-hash
+	separatorTemplate *template.Template   // parsed SeparatorTemplate
+	gitCommits        map[string]gitCommit // last commit per file, for -separator-meta git
+	changed           map[string]bool      // files changed since ChangedSince or staged, by absolute path
+}
+
+// Report describes the files a run selected and the ones it left out
+type Report struct {
+	Files    []string
+	Skipped  []FileInfo
+	TreeHash string // computed by -tree-hash
+
+	// what Select found out that Combine needs too
+	binary     map[string]bool
+	gitCommits map[string]gitCommit
+}
+
+// ExitError is returned when a run fails, carrying the exit code the
+// command uses for it. Err is nil when the failure was already reported
+// on stderr.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("combine failed with exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultOptions returns the options the command starts from before its
+// flags are applied
+func DefaultOptions() *Options {
+	return &Options{
+		Root:               ".",
+		Encoding:           "utf-8",
+		NewlineType:        "lf",
+		MaxSize:            MAX_FILE_SIZE,
+		GlobEngine:         GLOB_DOUBLESTAR,
+		PathStyle:          "relative",
+		Sort:               SORT_PATH,
+		MinifiedLineLength: MINIFIED_LINE_LENGTH,
+		MinifiedMinSize:    MINIFIED_MIN_SIZE,
+		Format:             FORMAT_TEXT,
+		BOM:                "auto",
+		GzipLevel:          gzip.DefaultCompression,
+		TokenEstimator:     TOKENS_BYTES,
+		CloneMinLines:      CLONE_MIN_LINES,
+		WatchDebounce:      WATCH_DEBOUNCE,
+	}
+}
+
+// New returns a Combiner for the given options
+func New(options *Options) *Combiner {
+	return &Combiner{Options: options}
+}
+
+// Run selects the files and writes the combined output
+func (c *Combiner) Run(ctx context.Context) (Report, error) {
+	report, err := c.Select(ctx)
+	if err != nil {
+		return report, err
+	}
+	return report, c.Combine(ctx, report)
+}
+
+// runOptions returns the copy of the options one Select or Combine works
+// on, with fresh run state, so the caller's Options are only ever read
+func (c *Combiner) runOptions(ctx context.Context) *Options {
+	options := *c.Options
+	options.run = &runState{ctx: ctx}
+	return &options
+}
+
+// loadGitCommits looks up the last commits for -separator-meta git, unless
+// the selection already did
+func loadGitCommits(config *Options, commits map[string]gitCommit) {
+	if !wantsSeparatorMeta(config, META_GIT) {
+		return
+	}
+	if commits == nil {
+		var err error
+		if commits, err = gitLastCommits(config.Root); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: No git metadata for the separators: %v\n", err)
+			commits = map[string]gitCommit{}
+		}
+	}
+	config.run.gitCommits = commits
+}
+
+// Select finds the files to combine and applies the ordering, limits and
+// budgets, computing the tree hash when -tree-hash is set
+func (c *Combiner) Select(ctx context.Context) (Report, error) {
+	config := c.runOptions(ctx)
+	rootInfo, err := os.Stat(config.Root)
+	if err != nil {
+		return Report{}, &ExitError{Code: 1, Err: fmt.Errorf("Root directory does not exist: %s", config.Root)}
+	}
+	if !rootInfo.IsDir() {
+		return Report{}, &ExitError{Code: 1, Err: fmt.Errorf("Root path is not a directory: %s", config.Root)}
+	}
+
+	if err := loadTokenizer(config.TokenEstimator); err != nil {
+		return Report{}, &ExitError{Code: 1, Err: err}
+	}
+	if err := parseSeparatorTemplate(config); err != nil {
+		return Report{}, &ExitError{Code: 1, Err: err}
+	}
+	loadGitCommits(config, nil)
+	if config.ChangedSince != "" {
+		changed, err := gitChangedFiles(config.Root, config.ChangedSince, config.IncludeUntracked)
+		if err != nil {
+			return Report{}, &ExitError{Code: 1, Err: fmt.Errorf("Cannot list the changes since %s: %v", config.ChangedSince, err)}
+		}
+		config.run.changed = changed
+	}
+	if config.Staged {
+		staged, partial, err := gitStagedFiles(config.Root)
+		if err != nil {
+			return Report{}, &ExitError{Code: 1, Err: fmt.Errorf("Cannot list the staged files: %v", err)}
+		}
+		for _, name := range partial {
+			fmt.Fprintf(os.Stderr, "Warning: %s has changes that are not staged; its working copy is combined\n", name)
+		}
+		config.run.changed = staged
+	}
+	files, skipped, err := selectFiles(config)
+	if err != nil {
+		return Report{}, &ExitError{Code: 1, Err: err}
+	}
+	report := Report{Files: files, Skipped: skipped, binary: config.run.binary, gitCommits: config.run.gitCommits}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+
+	// Fingerprint the selected inputs
+	if config.TreeHash {
+		hash, err := treeHash(config.Root, files)
+		if err != nil {
+			return report, &ExitError{Code: 1, Err: fmt.Errorf("Cannot compute tree hash: %v", err)}
+		}
+		report.TreeHash = hash
+	}
+	return report, nil
+}
+
+// Combine writes the output for a selection made by Select, then writes the
+// -manifest and runs the -self-check. With DryRun set it stops before
+// writing anything.
+func (c *Combiner) Combine(ctx context.Context, report Report) error {
+	config := c.runOptions(ctx)
+	run := config.run
+	files := report.Files
+	if len(files) < config.MinFiles {
+		return &ExitError{Code: 4, Err: fmt.Errorf("Only %d files matched, but --min-files requires at least %d", len(files), config.MinFiles)}
+	}
+	if len(files) == 0 {
+		return &ExitError{Code: 1, Err: fmt.Errorf("No files found matching the patterns")}
+	}
+
+	// Pick the output newline from the inputs' dominant convention
+	if config.NewlineType == "auto" {
+		run.newlineType = detectNewline(files, config.Verbose)
+	}
+	if config.DryRun {
+		return nil
+	}
+
+	if config.Verbose {
+		fmt.Println("Combining files...")
+	}
+
+	run.time = runTime()
+	run.skipped = report.Skipped
+	run.binary = report.binary
+	run.treeHash = report.TreeHash
+	if err := parseSeparatorTemplate(config); err != nil {
+		return &ExitError{Code: 1, Err: err}
+	}
+	loadGitCommits(config, report.gitCommits)
+	c.last = run
+	if code := combineFiles(config, files); code != 0 {
+		return &ExitError{Code: code}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if config.Manifest != "" {
+		if err := writeJSONManifest(config, files, report.Skipped); err != nil {
+			return &ExitError{Code: 2, Err: err}
+		}
+	}
+
+	// Verify the output splits back into the original files
+	if config.SelfCheck && selfCheck(config, files) > 0 {
+		return &ExitError{Code: 3}
+	}
+	return nil
+}
+
+// stdout is where the output meant for standard output goes
+func (config *Options) stdout() io.Writer {
+	if config.Stdout != nil {
+		return config.Stdout
+	}
+	return os.Stdout
+}
+
+// Validate reports options that cannot be used together, or templates and
+// settings that would fail halfway through a run
+func (config *Options) Validate() error {
+	// Reject templates with unknown placeholders up front
+	for _, tmpl := range []string{config.ContentPrefix, config.ContentSuffix} {
+		if _, err := expandPlaceholders(tmpl, fileFields(config.Root, config.Root, 1)); err != nil {
+			return fmt.Errorf("invalid content template: %v", err)
+		}
+	}
+
+	if config.SeparatorFormat != "" {
+		if _, err := expandPlaceholders(config.SeparatorFormat, separatorFields(config.Root, config.Root, 1)); err != nil {
+			return fmt.Errorf("invalid --separator-format: %v", err)
+		}
+	}
+	if config.SeparatorTemplate != "" {
+		if config.SeparatorFormat != "" || config.NoSeparator {
+			return errors.New("--separator-template cannot be combined with --separator-format or --no-separator")
+		}
+		if err := parseSeparatorTemplate(config); err != nil {
+			return err
+		}
+	}
+	if len(config.SeparatorMeta) > 0 {
+		if config.SeparatorFormat != "" || config.SeparatorTemplate != "" || config.NoSeparator || config.Format != FORMAT_TEXT {
+			return errors.New("--separator-meta extends the default separators of the text format")
+		}
+	}
+	if (config.SeparatorFormat != "" || config.SeparatorTemplate != "") && (config.SelfCheck || config.Resume) {
+		return errors.New("--self-check and --resume need the default separators, not --separator-format or --separator-template")
+	}
+
+	if config.SelfCheck && config.Format != FORMAT_TEXT {
+		return errors.New("--self-check only supports the text format")
+	}
+	if config.splitsOutput() {
+		switch {
+		case config.Output == "-" || config.Output == "c" || config.PipeTo != "":
+			return errors.New("--chunk-tokens, --split-size and --split-lines write numbered files; they need -o FILE")
+		case config.Append || config.Resume:
+			return errors.New("--chunk-tokens, --split-size and --split-lines cannot be combined with --append or --resume")
+		case config.Manifest != "" || config.SelfCheck:
+			return errors.New("--chunk-tokens, --split-size and --split-lines cannot be combined with --manifest or --self-check")
+		}
+	}
+	if config.PartName != "" {
+		if !config.splitsOutput() {
+			return errors.New("--part-name needs --chunk-tokens, --split-size or --split-lines")
+		}
+		if err := validatePartName(config.PartName); err != nil {
+			return err
+		}
+	}
+	if err := ValidEncoding(config.Encoding); err != nil {
+		return err
+	}
+	if config.GzipLevel < gzip.DefaultCompression || config.GzipLevel > gzip.BestCompression {
+		return fmt.Errorf("--gzip-level must be between 0 and 9: %d", config.GzipLevel)
+	}
+	if enc, _ := lookupEncoding(config.Encoding); !enc.isUTF8() && config.Output == "c" {
+		return errors.New("--encoding cannot be used with clipboard output")
+	}
+	if config.TOC && config.Format != FORMAT_TEXT {
+		return errors.New("--toc only supports the text format")
+	}
+	if config.Format == FORMAT_XML && (config.NoSeparator || config.SeparatorFormat != "" || config.SeparatorTemplate != "") {
+		return errors.New("--format xml writes its own <file> elements; drop --no-separator, --separator-format and --separator-template")
+	}
+	if config.Tree && (config.Format == FORMAT_CSV || config.Format == FORMAT_JSON) {
+		return errors.New("--tree needs text, markdown or xml output")
+	}
+	if config.TreeSkipped && !config.Tree {
+		return errors.New("--tree-skipped needs --tree")
+	}
+	if len(config.Patterns) > 0 && len(config.IncludeRegexps) == 0 {
+		negations := 0
+		for _, pattern := range config.Patterns {
+			if isNegation(pattern) {
+				negations++
+			}
+		}
+		if negations == len(config.Patterns) {
+			return errors.New("every pattern is a !negation; a negation only takes files out of an earlier pattern's matches")
+		}
+	}
+	if len(config.Roots) > 1 && config.Unpack != "" {
+		return errors.New("--unpack restores into a single --root")
+	}
+	if config.IncludeUntracked && config.ChangedSince == "" && !config.GitTracked {
+		return errors.New("--include-untracked needs --changed-since or --git-tracked")
+	}
+	if !config.NewerThan.IsZero() && !config.OlderThan.IsZero() && !config.NewerThan.Before(config.OlderThan) {
+		return errors.New("--newer-than must be earlier than --older-than; no file can be in the window")
+	}
+	if config.Staged && config.ChangedSince != "" {
+		return errors.New("--staged and --changed-since both limit the files to git changes; use one")
+	}
+	if config.GitTracked && config.FilesFrom != "" {
+		return errors.New("--git-tracked and --files-from both choose the files; use one")
+	}
+	if config.Format == FORMAT_JSON {
+		if enc, _ := lookupEncoding(config.Encoding); !enc.isUTF8() {
+			return errors.New("--format json needs UTF-8 output")
+		}
+		if config.Header != "" || config.Footer != "" {
+			return errors.New("--header and --footer cannot be used with --format json")
+		}
+	}
+	if config.Append {
+		enc, _ := lookupEncoding(config.Encoding)
+		switch {
+		case config.Output == "" || config.Output == "c" || config.Output == "-" || config.PipeTo != "":
+			return errors.New("--append needs a file output")
+		case config.Resume || config.SelfCheck:
+			return errors.New("--append cannot be combined with --resume or --self-check")
+		case config.Format != FORMAT_TEXT || !enc.isUTF8():
+			return errors.New("--append needs text output in UTF-8")
+		}
+	}
+	if config.Resume {
+		enc, _ := lookupEncoding(config.Encoding)
+		switch {
+		case config.Output == "" || config.Output == "c" || config.Output == "-" || config.PipeTo != "":
+			return errors.New("--resume needs a file output")
+		case config.PackageBanners:
+			return errors.New("--resume cannot be combined with --package-banners")
+		case config.Format != FORMAT_TEXT || config.NoSeparator || !enc.isUTF8():
+			return errors.New("--resume needs text output with separators in UTF-8")
+		}
+	}
+	if config.Gzip {
+		switch {
+		case config.Output == "" || config.Output == "c" || config.PipeTo != "":
+			return errors.New("--gzip needs a file or stdout output")
+		case config.Append || config.Resume || config.SelfCheck:
+			return errors.New("--gzip cannot be combined with --append, --resume or --self-check")
+		}
+	}
+	if config.Manifest != "" {
+		enc, _ := lookupEncoding(config.Encoding)
+		switch {
+		case config.Format == FORMAT_CSV || config.Format == FORMAT_JSON || !enc.isUTF8():
+			return errors.New("--manifest needs text, markdown or xml output in UTF-8")
+		case config.Gzip || config.Append || config.Resume:
+			return errors.New("--manifest cannot be combined with --gzip, --append or --resume")
+		}
+	}
+	if config.Watch {
+		switch {
+		case config.DryRun || config.List || config.ScanExtensions:
+			return errors.New("--watch cannot be combined with --dry-run, --list or --scan-extensions")
+		case config.Append || config.Resume:
+			return errors.New("--watch cannot be combined with --append or --resume")
+		case config.FilesFrom == "-":
+			return errors.New("--watch needs a --files-from file it can read again, not stdin")
+		}
+	}
+	if config.Footer != "" && (config.Append || config.Resume || config.SelfCheck) {
+		return errors.New("--footer cannot be combined with --append, --resume or --self-check")
+	}
+	if err := validateDocumentTemplates(config); err != nil {
+		return err
+	}
+	if config.TargetIndent != nil && config.NormalizeIndent == "" {
+		return errors.New("--target-indent requires --normalize-indent auto")
+	}
+	if config.SelfCheck && config.Output == "-" {
+		return errors.New("--self-check needs a file output, not stdout")
+	}
+	if value := os.Getenv("SOURCE_DATE_EPOCH"); value != "" {
+		if _, ok := sourceDateEpoch(); !ok {
+			return fmt.Errorf("invalid SOURCE_DATE_EPOCH: %s", value)
+		}
+	}
+	if config.SelfCheck && config.LineRanges != nil {
+		return errors.New("--self-check cannot be combined with path:start-end line ranges")
+	}
+	if config.SelfCheck && config.NormalizeNewlines {
+		return errors.New("--self-check cannot be combined with --normalize-newlines")
+	}
+	if config.SelfCheck && config.LineNumbers {
+		return errors.New("--self-check cannot be combined with --line-numbers")
+	}
+	if config.SelfCheck && config.CollapseDepth > 0 {
+		return errors.New("--self-check cannot be combined with --collapse-path-depth")
+	}
+	if config.SignKey != "" && config.Unpack == "" && config.VerifySignature == "" {
+		enc, _ := lookupEncoding(config.Encoding)
+		switch {
+		case config.Output == "c" || config.PipeTo != "":
+			return errors.New("--sign-key needs a file or stdout output")
+		case config.Gzip || config.Append || config.Resume:
+			return errors.New("--sign-key cannot be combined with --gzip, --append or --resume")
+		case !enc.isUTF8():
+			return errors.New("--sign-key needs UTF-8 output")
+		}
+	}
+	return nil
+}
+
+// canceled reports whether the run's context was canceled, so the writers
+// can stop between files
+func (config *Options) canceled() bool {
+	return config.run != nil && config.run.ctx != nil && config.run.ctx.Err() != nil
+}
+
+// newlineType is the output newline's name, with "auto" resolved once
+// Combine has looked at the inputs
+func (config *Options) newlineType() string {
+	if config.run != nil && config.run.newlineType != "" {
+		return config.run.newlineType
+	}
+	return config.NewlineType
+}
+
+// isBinary reports whether -include-binary keeps the file, base64 encoded
+func (config *Options) isBinary(path string) bool {
+	return config.run != nil && config.run.binary[path]
+}
+
+// separatorTemplate is the run's parsed -separator-template, or nil
+func (config *Options) separatorTemplate() *template.Template {
+	if config.run == nil {
+		return nil
+	}
+	return config.run.separatorTemplate
+}
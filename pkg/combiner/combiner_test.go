@@ -0,0 +1,242 @@
+package combiner
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates the files, given by slash-separated path, below a new
+// temporary directory and returns it
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// testOptions returns the default options for combining the files below
+// root that match patterns into a file in a temporary directory
+func testOptions(t testing.TB, root string, patterns ...string) *Options {
+	t.Helper()
+	config := DefaultOptions()
+	config.Root = root
+	config.Patterns = patterns
+	config.Output = filepath.Join(t.TempDir(), "out.txt")
+	config.NoTimestamp = true
+	config.Rebuilding = true // no summary on stdout
+	return config
+}
+
+// relFiles returns the files of a report relative to root, slash-separated
+func relFiles(t testing.TB, root string, files []string) []string {
+	t.Helper()
+	rel := make([]string, len(files))
+	for i, file := range files {
+		path, err := filepath.Rel(root, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rel[i] = filepath.ToSlash(path)
+	}
+	return rel
+}
+
+// selectRel runs Select and returns the selected files relative to the root
+func selectRel(t testing.TB, config *Options) []string {
+	t.Helper()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	return relFiles(t, config.Root, report.Files)
+}
+
+// combine runs the options through Select and Combine and returns the output
+func combine(t testing.TB, config *Options) string {
+	t.Helper()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if _, err := New(config).Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	data, err := os.ReadFile(config.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// captureStdout returns what fn prints on stdout
+func captureStdout(t testing.TB, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureStderr returns what fn prints on stderr
+func captureStderr(t testing.TB, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+func captureFile(t testing.TB, file **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := *file
+	*file = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		*file = saved
+	}()
+	fn()
+	w.Close()
+	return string(<-done)
+}
+
+func sameStrings(a, b []string) bool {
+	return strings.Join(a, "\x00") == strings.Join(b, "\x00")
+}
+
+func TestCombinerRunOrderAndSeparators(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"b.go":     "package b\n",
+		"a.go":     "package a\n",
+		"notes.md": "# notes\n",
+	})
+	out := combine(t, testOptions(t, root, "*.go"))
+
+	a := strings.Index(out, "package a")
+	b := strings.Index(out, "package b")
+	if a < 0 || b < 0 || a > b {
+		t.Fatalf("want a.go before b.go, got:\n%s", out)
+	}
+	if strings.Contains(out, "# notes") {
+		t.Errorf("notes.md does not match *.go but was combined")
+	}
+	if got := strings.Count(out, "FILE 1:"); got != 1 {
+		t.Errorf("FILE 1 separators = %d, want 1", got)
+	}
+}
+
+func TestCombinerMinFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n"})
+	config := testOptions(t, root, "*.go")
+	config.MinFiles = 2
+
+	c := New(config)
+	report, err := c.Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Combine(context.Background(), report)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 4 {
+		t.Fatalf("Combine with too few files = %v, want exit code 4", err)
+	}
+	if _, err := os.Stat(config.Output); !os.IsNotExist(err) {
+		t.Errorf("output written although --min-files failed")
+	}
+}
+
+func TestCombinerRunsIndependently(t *testing.T) {
+	rootA := writeTree(t, map[string]string{"a.txt": strings.Repeat("a", 100) + "\n"})
+	rootB := writeTree(t, map[string]string{"b.txt": "b\n"})
+	a := New(testOptions(t, rootA, "*.txt"))
+	b := New(testOptions(t, rootB, "*.txt"))
+
+	done := make(chan error, 2)
+	for _, c := range []*Combiner{a, b} {
+		go func(c *Combiner) {
+			_, err := c.Run(context.Background())
+			done <- err
+		}(c)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if a.last.totals.Bytes <= b.last.totals.Bytes {
+		t.Errorf("totals mixed up: a wrote %d bytes, b %d", a.last.totals.Bytes, b.last.totals.Bytes)
+	}
+}
+
+func TestCombinerRunsAgain(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(*Options)
+		first, second string
+		want, notWant [2]string // in the first and second output
+	}{
+		{
+			name:    "auto newline",
+			setup:   func(config *Options) { config.NewlineType = "auto" },
+			first:   "1\r\n2\r\n",
+			second:  "1\n2\n",
+			want:    [2]string{"1\r\n2\r\n", "1\n2\n"},
+			notWant: [2]string{"", "\r\n"},
+		},
+		{
+			name:    "binary file turned text",
+			setup:   func(config *Options) { config.IncludeBinary = true },
+			first:   "\x00\x01\x02",
+			second:  "plain text\n",
+			want:    [2]string{"BEGIN BASE64", "plain text"},
+			notWant: [2]string{"", "BEGIN BASE64"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, map[string]string{"data": tt.first})
+			config := testOptions(t, root, "data")
+			tt.setup(config)
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			before := *config
+			c := New(config)
+			for i, content := range []string{tt.first, tt.second} {
+				if err := os.WriteFile(filepath.Join(root, "data"), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := c.Run(context.Background()); err != nil {
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+				data, err := os.ReadFile(config.Output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if out := string(data); !strings.Contains(out, tt.want[i]) || tt.notWant[i] != "" && strings.Contains(out, tt.notWant[i]) {
+					t.Errorf("run %d output:\n%q\nwant %q and not %q", i+1, out, tt.want[i], tt.notWant[i])
+				}
+			}
+			if !reflect.DeepEqual(*config, before) {
+				t.Errorf("the runs changed the options:\n%+v\nwas\n%+v", *config, before)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// outputEncoding describes how the UTF-8 output is transcoded before it is written
+type outputEncoding struct {
+	Encoding encoding.Encoding // nil means plain UTF-8
+	BOM      []byte            // byte order mark for this encoding, if it has one
+	WantBOM  bool              // whether the BOM is written under -bom auto
+}
+
+// lookupEncoding resolves an -encoding name. The Unicode variants are handled
+// here so their BOMs can be controlled with -bom; any other name is looked up
+// in the WHATWG encoding index (latin1, windows-1252, shift_jis, gbk, ...).
+func lookupEncoding(name string) (outputEncoding, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "_", "-")) {
+	case "", "utf-8", "utf8":
+		return outputEncoding{BOM: utf8BOM}, nil
+	case "utf-8-bom", "utf8-bom", "utf-8-sig", "utf8-sig":
+		return outputEncoding{BOM: utf8BOM, WantBOM: true}, nil
+	case "utf-16", "utf16", "utf-16le", "utf16le":
+		return outputEncoding{
+			Encoding: unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+			BOM:      []byte{0xFF, 0xFE},
+			WantBOM:  true,
+		}, nil
+	case "utf-16be", "utf16be":
+		return outputEncoding{
+			Encoding: unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+			BOM:      []byte{0xFE, 0xFF},
+			WantBOM:  true,
+		}, nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return outputEncoding{Encoding: charmap.ISO8859_1}, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return outputEncoding{}, fmt.Errorf("unsupported encoding: %s", name)
+	}
+	return outputEncoding{Encoding: enc}, nil
+}
+
+// ValidEncoding reports an error for an -encoding name that cannot be used
+func ValidEncoding(name string) error {
+	_, err := lookupEncoding(name)
+	return err
+}
+
+// isUTF8 reports whether the output is written as UTF-8, with or without a BOM
+func (e outputEncoding) isUTF8() bool {
+	return e.Encoding == nil
+}
+
+// writeBOM writes the encoding's byte order mark as requested by the -bom
+// mode and returns the number of bytes written
+func (e outputEncoding) writeBOM(w io.Writer, mode string) int {
+	if len(e.BOM) == 0 || mode == "never" {
+		return 0
+	}
+	if mode == "always" || e.WantBOM {
+		n, _ := w.Write(e.BOM)
+		return n
+	}
+	return 0
+}
+
+// encodeWriter wraps w so that UTF-8 written to it is transcoded into the
+// target charset. Characters the charset cannot represent are replaced.
+// The returned writer must be closed to flush the last bytes.
+func (e outputEncoding) encodeWriter(w io.Writer) io.WriteCloser {
+	if e.isUTF8() {
+		return nopWriteCloser{w}
+	}
+	return transform.NewWriter(w, encoding.ReplaceUnsupported(e.Encoding.NewEncoder()))
+}
+
+// decode turns output written with this encoding back into UTF-8
+func (e outputEncoding) decode(data []byte) ([]byte, error) {
+	if len(e.BOM) > 0 {
+		data = bytes.TrimPrefix(data, e.BOM)
+	}
+	if e.isUTF8() {
+		return data, nil
+	}
+	return e.Encoding.NewDecoder().Bytes(data)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
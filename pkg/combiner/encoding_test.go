@@ -0,0 +1,98 @@
+package combiner
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputEncodings(t *testing.T) {
+	const text = "café €\n"
+	tests := []struct {
+		encoding string
+		bom      string
+		wantBOM  []byte
+		wantText []byte // how "café" is encoded
+	}{
+		{"utf-8", "auto", nil, []byte("café")},
+		{"utf-8", "always", utf8BOM, []byte("café")},
+		{"utf-8-bom", "auto", utf8BOM, []byte("café")},
+		{"utf-8-bom", "never", nil, []byte("café")},
+		{"utf-16le", "auto", []byte{0xFF, 0xFE}, []byte{'c', 0, 'a', 0, 'f', 0, 0xE9, 0}},
+		{"utf-16be", "never", nil, []byte{0, 'c', 0, 'a', 0, 'f', 0, 0xE9}},
+		{"latin1", "auto", nil, []byte{'c', 'a', 'f', 0xE9}},
+		{"windows-1252", "always", nil, []byte{'c', 'a', 'f', 0xE9}},
+		{"shift_jis", "auto", nil, []byte("caf")},
+	}
+	root := writeTree(t, map[string]string{"a.txt": text})
+	for _, tt := range tests {
+		t.Run(tt.encoding+"/"+tt.bom, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.Encoding = tt.encoding
+			config.BOM = tt.bom
+			out := []byte(combine(t, config))
+
+			enc, err := lookupEncoding(tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantBOM != nil && !bytes.HasPrefix(out, tt.wantBOM) {
+				t.Errorf("output starts % x, want the BOM % x", out[:4], tt.wantBOM)
+			}
+			if tt.wantBOM == nil && len(enc.BOM) > 0 && bytes.HasPrefix(out, enc.BOM) {
+				t.Errorf("output starts with a BOM under -bom %s", tt.bom)
+			}
+			if !bytes.Contains(out, tt.wantText) {
+				t.Errorf("output does not contain % x", tt.wantText)
+			}
+			decoded, err := enc.decode(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enc.isUTF8() || tt.encoding == "utf-16le" || tt.encoding == "utf-16be" {
+				if !bytes.Contains(decoded, []byte(text)) {
+					t.Errorf("decoded output lost %q", text)
+				}
+			}
+		})
+	}
+}
+
+func TestUnpackEncodedOutput(t *testing.T) {
+	files := map[string]string{"a.txt": "café\n", "b.go": "package b\n"}
+	for _, encoding := range []string{"utf-16le", "latin1", "utf-8-bom"} {
+		t.Run(encoding, func(t *testing.T) {
+			config := testOptions(t, writeTree(t, files), "*")
+			config.Encoding = encoding
+			combine(t, config)
+
+			unpack := DefaultOptions()
+			unpack.Root = t.TempDir()
+			unpack.Unpack = config.Output
+			unpack.Encoding = encoding
+			if code := UnpackCombined(unpack); code != 0 {
+				t.Fatalf("UnpackCombined = %d", code)
+			}
+			for name, want := range files {
+				got, err := os.ReadFile(filepath.Join(unpack.Root, name))
+				if err != nil || string(got) != want {
+					t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidEncoding(t *testing.T) {
+	for _, name := range []string{"utf-8", "UTF_8", "utf8-sig", "utf-16", "latin-1", "gbk", "shift_jis"} {
+		if err := ValidEncoding(name); err != nil {
+			t.Errorf("ValidEncoding(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"utf-9", "klingon"} {
+		if err := ValidEncoding(name); err == nil {
+			t.Errorf("ValidEncoding(%q) accepted", name)
+		}
+	}
+}
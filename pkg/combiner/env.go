@@ -0,0 +1,30 @@
+package combiner
+
+import "os"
+
+// expandEnv replaces $VAR and ${VAR} with the environment's value; "$$"
+// stands for a literal "$"
+func expandEnv(s string) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		return os.Getenv(name)
+	})
+}
+
+// ExpandConfigEnv applies expandEnv to the patterns, excludes, output and
+// root for -env-expand-in-patterns
+func ExpandConfigEnv(config *Options) {
+	for i, p := range config.Patterns {
+		config.Patterns[i] = expandEnv(p)
+	}
+	for i, p := range config.Excludes {
+		config.Excludes[i] = expandEnv(p)
+	}
+	config.Output = expandEnv(config.Output)
+	config.Root = expandEnv(config.Root)
+	for i, root := range config.Roots {
+		config.Roots[i] = expandEnv(root)
+	}
+}
@@ -0,0 +1,53 @@
+package combiner
+
+import "testing"
+
+func TestExpandConfigEnv(t *testing.T) {
+	t.Setenv("COMBINE_SRC", "src")
+	t.Setenv("COMBINE_OUT", "build")
+	config := DefaultOptions()
+	config.Patterns = []string{"$COMBINE_SRC/**/*.go", "${COMBINE_SRC}/*.md", "cost$$.txt", "$COMBINE_UNSET/x"}
+	config.Excludes = []string{"$COMBINE_SRC/gen"}
+	config.Output = "${COMBINE_OUT}/all.txt"
+	config.Root = "$COMBINE_SRC"
+	ExpandConfigEnv(config)
+
+	if want := []string{"src/**/*.go", "src/*.md", "cost$.txt", "/x"}; !sameStrings(config.Patterns, want) {
+		t.Errorf("patterns = %q, want %q", config.Patterns, want)
+	}
+	if !sameStrings(config.Excludes, []string{"src/gen"}) || config.Output != "build/all.txt" || config.Root != "src" {
+		t.Errorf("excludes %q, output %q, root %q", config.Excludes, config.Output, config.Root)
+	}
+}
+
+func TestExpandedPatternsMatch(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"src/a.go":      "package a\n",
+		"src/gen/b.go":  "package gen\n",
+		"lib/c.go":      "package lib\n",
+		"cost$.txt":     "literal dollar\n",
+		"src/notes.txt": "notes\n",
+	})
+	t.Setenv("COMBINE_SRC", "src")
+	tests := []struct {
+		name     string
+		patterns []string
+		excludes []string
+		want     []string
+	}{
+		{"variable", []string{"$COMBINE_SRC/**/*.go"}, nil, []string{"src/a.go", "src/gen/b.go"}},
+		{"braces", []string{"${COMBINE_SRC}/*.txt"}, nil, []string{"src/notes.txt"}},
+		{"exclude", []string{"**/*.go"}, []string{"$COMBINE_SRC/gen"}, []string{"lib/c.go", "src/a.go"}},
+		{"escaped dollar", []string{"cost$$.txt"}, nil, []string{"cost$.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, tt.patterns...)
+			config.Excludes = tt.excludes
+			ExpandConfigEnv(config)
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package combiner
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestExcludeNames(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"log":           "exact\n",
+		"catalog":       "contains log\n",
+		"sub/log":       "nested\n",
+		"log.txt":       "has an extension\n",
+		"TODO":          "todo\n",
+		"docs/todo":     "lower case\n",
+		"logs/keep.txt": "a directory named like it\n",
+	})
+	tests := []struct {
+		name       string
+		names      []string
+		ignoreCase bool
+		want       []string
+	}{
+		{"none", nil, false, []string{"TODO", "catalog", "docs/todo", "log", "log.txt", "logs/keep.txt", "sub/log"}},
+		{"exact basename", []string{"log"}, false, []string{"TODO", "catalog", "docs/todo", "log.txt", "logs/keep.txt"}},
+		{"several", []string{"log", "TODO"}, false, []string{"catalog", "docs/todo", "log.txt", "logs/keep.txt"}},
+		{"case sensitive", []string{"todo"}, false, []string{"TODO", "catalog", "log", "log.txt", "logs/keep.txt", "sub/log"}},
+		{"ignore case", []string{"todo"}, true, []string{"catalog", "log", "log.txt", "logs/keep.txt", "sub/log"}},
+		{"paths are not names", []string{"sub/log"}, false, []string{"TODO", "catalog", "docs/todo", "log", "log.txt", "logs/keep.txt", "sub/log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.ExcludeNames = tt.names
+			config.IgnoreCase = tt.ignoreCase
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := testOptions(t, root, "**/*")
+	config.ExcludeNames = []string{"log"}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := 0
+	for _, skip := range report.Skipped {
+		if skip.Reason == "Excluded name (log)" {
+			reasons++
+		}
+	}
+	if reasons != 2 {
+		t.Errorf("%d files skipped as \"Excluded name (log)\", want 2: %+v", reasons, report.Skipped)
+	}
+}
+
+func TestExcludeRegexps(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".gitignore":      "ignored.go\n",
+		"main.go":         "package main\n",
+		"main_test.go":    "package main\n",
+		"api.gen.go":      "package main\n",
+		"sub/x_test.go":   "package sub\n",
+		"sub/keep.go":     "package sub\n",
+		"ignored.go":      "package main\n",
+		"vendor/v.go":     "package v\n",
+		"generated.go":    "package main\n",
+		"sub/Big_Test.go": "package sub\n",
+	})
+	tests := []struct {
+		name     string
+		exprs    []string
+		excludes []string
+		want     []string
+	}{
+		{"none", nil, nil, []string{"api.gen.go", "generated.go", "main.go", "main_test.go", "sub/Big_Test.go", "sub/keep.go", "sub/x_test.go", "vendor/v.go"}},
+		{"suffix", []string{`_test\.go$`}, nil, []string{"api.gen.go", "generated.go", "main.go", "sub/Big_Test.go", "sub/keep.go", "vendor/v.go"}},
+		{"several", []string{`_test\.go$`, `\.gen\.`}, nil, []string{"generated.go", "main.go", "sub/Big_Test.go", "sub/keep.go", "vendor/v.go"}},
+		{"slash paths", []string{`^sub/`}, nil, []string{"api.gen.go", "generated.go", "main.go", "main_test.go", "vendor/v.go"}},
+		{"with globs", []string{`\.gen\.`}, []string{"vendor"}, []string{"generated.go", "main.go", "main_test.go", "sub/Big_Test.go", "sub/keep.go", "sub/x_test.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.go")
+			config.Excludes = tt.excludes
+			for _, expr := range tt.exprs {
+				config.ExcludeRegexps = append(config.ExcludeRegexps, regexp.MustCompile(expr))
+			}
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := testOptions(t, root, "**/*.go")
+	config.ExcludeRegexps = []*regexp.Regexp{regexp.MustCompile(`\.gen\.`), regexp.MustCompile(`_test\.go$`)}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reasons := make(map[string]string)
+	for _, skip := range report.Skipped {
+		reasons[relFiles(t, root, []string{skip.Path})[0]] = skip.Reason
+	}
+	for path, want := range map[string]string{
+		"api.gen.go":    `Excluded by --ere \.gen\.`,
+		"sub/x_test.go": `Excluded by --ere _test\.go$`,
+	} {
+		if reasons[path] != want {
+			t.Errorf("%s skipped as %q, want %q", path, reasons[path], want)
+		}
+	}
+}
+
+func TestPruneExcludedDirs(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.js":                      "main\n",
+		"node_modules/a/index.js":      "a\n",
+		"node_modules/a/deep/index.js": "deep\n",
+		"web/node_modules/b/index.js":  "b\n",
+		"logs.log/keep.js":             "kept\n",
+		"logs.log/inside.log":          "log\n",
+		"src/generated/gen.js":         "gen\n",
+		"src/handwritten.js":           "hand\n",
+	})
+	config := testOptions(t, root, "**/*.js")
+	config.Excludes = []string{"node_modules", "*.log", "src/generated"}
+	config.Debug = true
+	var got []string
+	out := captureStdout(t, func() { got = selectRel(t, config) })
+
+	if want := []string{"logs.log/keep.js", "main.js", "src/handwritten.js"}; !sameStrings(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	for _, dir := range []string{"node_modules", "web/node_modules", "src/generated"} {
+		if strings.Count(out, "Pruning directory: "+dir+"\n") != 1 {
+			t.Errorf("%s not pruned once:\n%s", dir, out)
+		}
+	}
+	for _, notPruned := range []string{"node_modules/a", "logs.log", "src\n"} {
+		if strings.Contains(out, "Pruning directory: "+notPruned) {
+			t.Errorf("walk reached or pruned %s:\n%s", notPruned, out)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package combiner
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readFileList reads newline-separated paths for -files-from ("-" is stdin).
+// The list is NUL-separated instead with -null or when it contains a NUL
+// (git ls-files -z, find -print0). Paths are taken relative to the working
+// directory, falling back to the root; blank entries and repeated paths are
+// dropped, the order is kept.
+func readFileList(config *Options) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if config.FilesFrom != "-" {
+		file, err := os.Open(config.FilesFrom)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var entries []string
+	if config.NullSeparated || bytes.IndexByte(data, 0) >= 0 {
+		entries = strings.Split(string(data), "\x00")
+	} else {
+		for _, line := range strings.Split(string(data), "\n") {
+			entries = append(entries, strings.TrimRight(line, "\r"))
+		}
+	}
+
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range entries {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) {
+			if _, err := os.Stat(filepath.Join(config.Root, path)); err == nil {
+				path = filepath.Join(config.Root, path)
+			}
+		}
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
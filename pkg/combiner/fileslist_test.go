@@ -0,0 +1,141 @@
+package combiner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeList writes a -files-from list and returns its path
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "files.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFilesFromOrder(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"z.txt":        "z\n",
+		"a.txt":        "a\n",
+		"m/b.go":       "package m\n",
+		"image.dat":    "\x00\x01",
+		"skip.log":     "log\n",
+		"unlisted.txt": "not in the list\n",
+	})
+	tests := []struct {
+		name    string
+		list    string
+		reverse bool
+		want    []string
+	}{
+		{"listed order", "z.txt\nm/b.go\na.txt\n", false, []string{"z.txt", "m/b.go", "a.txt"}},
+		{"reversed", "z.txt\nm/b.go\na.txt\n", true, []string{"a.txt", "m/b.go", "z.txt"}},
+		{"filters still apply", "image.dat\nskip.log\na.txt\nz.txt\n", false, []string{"a.txt", "z.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root)
+			config.FilesFrom = writeList(t, tt.list)
+			config.Excludes = []string{"*.log"}
+			config.Reverse = tt.reverse
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := relFiles(t, root, report.Files); !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			for _, skip := range report.Skipped {
+				switch filepath.Base(skip.Path) {
+				case "image.dat":
+					if skip.Reason != "Binary file" {
+						t.Errorf("image.dat skipped as %q", skip.Reason)
+					}
+				case "skip.log":
+					if skip.Reason != "Excluded" {
+						t.Errorf("skip.log skipped as %q", skip.Reason)
+					}
+				default:
+					t.Errorf("unexpected skip %+v", skip)
+				}
+			}
+		})
+	}
+
+	config := testOptions(t, root)
+	config.FilesFrom = writeList(t, "z.txt\na.txt\n")
+	out := combine(t, config)
+	if !strings.Contains(out, "FILE 1: z.txt") || !strings.Contains(out, "FILE 2: a.txt") {
+		t.Errorf("output not in the listed order:\n%s", out)
+	}
+}
+
+func TestReadFileList(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n", "b c.txt": "b\n", "sub/d.txt": "d\n"})
+	a, bc, d := filepath.Join(root, "a.txt"), filepath.Join(root, "b c.txt"), filepath.Join(root, "sub", "d.txt")
+	tests := []struct {
+		name string
+		list string
+		null bool
+		want []string
+	}{
+		{"newlines", "a.txt\nb c.txt\nsub/d.txt\n", false, []string{a, bc, d}},
+		{"crlf", "a.txt\r\nsub/d.txt\r\n", false, []string{a, d}},
+		{"no final newline", "sub/d.txt\na.txt", false, []string{d, a}},
+		{"blank lines and repeats", "\na.txt\n\n  \na.txt\nsub/d.txt\n", false, []string{a, d}},
+		{"nul detected", "b c.txt\x00a.txt\x00", false, []string{bc, a}},
+		{"nul requested", "a.txt\x00", true, []string{a}},
+		{"no final nul", "a.txt\x00sub/d.txt", true, []string{a, d}},
+		{"absolute", d + "\n", false, []string{d}},
+		{"missing files are kept for the filters", "gone.txt\n", false, []string{"gone.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root)
+			config.FilesFrom = writeList(t, tt.list)
+			config.NullSeparated = tt.null
+			got, err := readFileList(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := testOptions(t, root)
+	config.FilesFrom = filepath.Join(root, "no-such-list")
+	if _, err := readFileList(config); err == nil {
+		t.Error("a missing list did not fail")
+	}
+}
+
+func TestFilesFromStdin(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+	go func() {
+		w.WriteString("b.txt\x00a.txt\x00") // git ls-files -z
+		w.Close()
+	}()
+
+	config := testOptions(t, root)
+	config.FilesFrom = "-"
+	if got, want := selectRel(t, config), []string{"b.txt", "a.txt"}; !sameStrings(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
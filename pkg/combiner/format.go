@@ -0,0 +1,122 @@
+package combiner
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Output formats selectable with -format
+const (
+	FORMAT_TEXT     = "text"
+	FORMAT_CSV      = "csv"
+	FORMAT_MARKDOWN = "markdown"
+	FORMAT_JSON     = "json"
+	FORMAT_XML      = "xml"
+)
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+func ValidFormat(format string) bool {
+	return format == FORMAT_TEXT || format == FORMAT_CSV || format == FORMAT_MARKDOWN || format == FORMAT_JSON || format == FORMAT_XML
+}
+
+// formatExtensions maps output file extensions to the format they imply
+var formatExtensions = map[string]string{
+	".csv":      FORMAT_CSV,
+	".md":       FORMAT_MARKDOWN,
+	".markdown": FORMAT_MARKDOWN,
+	".json":     FORMAT_JSON,
+	".xml":      FORMAT_XML,
+}
+
+// FormatForOutput infers the format from the -o file name when -format is
+// not given; a trailing .gz is looked through, and unknown extensions
+// (and stdout or the clipboard) mean text
+func FormatForOutput(output string) string {
+	name := strings.ToLower(output)
+	name = strings.TrimSuffix(name, ".gz")
+	if format, ok := formatExtensions[filepath.Ext(name)]; ok {
+		return format
+	}
+	return FORMAT_TEXT
+}
+
+// writeOutput renders the combined output in the configured format and
+// encoding, preceded by a byte order mark when -bom asks for one
+func writeOutput(w io.Writer, config *Options, files []string) (int, int) {
+	enc, _ := lookupEncoding(config.Encoding) // validated in Options.Validate
+	bom := 0
+	if !config.Continuing {
+		bom = enc.writeBOM(w, config.BOM)
+	}
+	if config.Manifest != "" {
+		config.run.regions.reset(int64(bom))
+	}
+	ew := enc.encodeWriter(w)
+	defer ew.Close()
+	config.run.totals.encoding = tokenizer(config)
+	out := &totalsWriter{w: ew, totals: &config.run.totals}
+
+	switch config.Format {
+	case FORMAT_CSV:
+		return writeCSVInventory(out, config, files)
+	case FORMAT_JSON:
+		return writeJSONDocument(out, config, files)
+	default:
+		return writeCombined(out, config, files)
+	}
+}
+
+// writeCSVInventory writes one CSV row per file instead of the file contents
+func writeCSVInventory(w io.Writer, config *Options, files []string) (int, int) {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = getNewline(config.newlineType()) == "\r\n"
+	writer.Write([]string{"index", "path", "size", "lines", "language", "modified"})
+
+	successCount := 0
+	errorCount := 0
+	for idx, filePath := range files {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
+			errorCount++
+			continue
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
+			errorCount++
+			continue
+		}
+
+		config.recordNewlines(filePath, content)
+		lines := bytes.Count(content, []byte{'\n'})
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			lines++
+		}
+
+		modified := ""
+		if mtime, ok := fileModTime(config, info); ok {
+			modified = mtime.Format("2006-01-02 15:04:05")
+		}
+		relPath, _ := filepath.Rel(config.Root, filePath)
+		writer.Write([]string{
+			strconv.Itoa(idx + 1),
+			filepath.ToSlash(relPath),
+			strconv.FormatInt(info.Size(), 10),
+			strconv.Itoa(lines),
+			detectLanguage(filePath),
+			modified,
+		})
+		successCount++
+	}
+
+	writer.Flush()
+	return successCount, errorCount
+}
@@ -0,0 +1,87 @@
+package combiner
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+func TestFormatForOutput(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"bundle.txt", FORMAT_TEXT},
+		{"bundle.csv", FORMAT_CSV},
+		{"BUNDLE.CSV", FORMAT_CSV},
+		{"docs/all.md", FORMAT_MARKDOWN},
+		{"all.markdown", FORMAT_MARKDOWN},
+		{"all.json", FORMAT_JSON},
+		{"all.xml.gz", FORMAT_XML},
+		{"all.gz", FORMAT_TEXT},
+		{"all.go", FORMAT_TEXT},
+		{"-", FORMAT_TEXT},
+		{"c", FORMAT_TEXT},
+	}
+	for _, tt := range tests {
+		if got := FormatForOutput(tt.output); got != tt.want {
+			t.Errorf("FormatForOutput(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestCSVInventory(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":         "package a\n\nfunc A() {}\n",
+		"sub/b.txt":    "no final newline",
+		"c, quoted.md": "# \"c\"\n",
+	})
+	tests := []struct {
+		name    string
+		bom     string
+		newline string
+		wantBOM bool
+	}{
+		{"default", "auto", "lf", false},
+		{"never", "never", "lf", false},
+		{"always for excel", "always", "lf", true},
+		{"excel line endings", "always", "crlf", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.Format = FORMAT_CSV
+			config.BOM = tt.bom
+			config.NewlineType = tt.newline
+			out := []byte(combine(t, config))
+
+			if got := bytes.HasPrefix(out, utf8BOM); got != tt.wantBOM {
+				t.Errorf("leading BOM = %v, want %v (starts %q)", got, tt.wantBOM, out[:min(len(out), 8)])
+			}
+			out = bytes.TrimPrefix(out, utf8BOM)
+			if crlf := bytes.Contains(out, []byte("\r\n")); crlf != (tt.newline == "crlf") {
+				t.Errorf("CRLF rows = %v with -newline %s", crlf, tt.newline)
+			}
+
+			records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := [][]string{
+				{"index", "path", "size", "lines", "language"},
+				{"1", "a.go", "23", "3", "go"},
+				{"2", "c, quoted.md", "6", "1", "markdown"},
+				{"3", "sub/b.txt", "16", "1", "text"},
+			}
+			if len(records) != len(want) {
+				t.Fatalf("got %d rows, want %d:\n%s", len(records), len(want), out)
+			}
+			for i, row := range records {
+				if got := strings.Join(row[:5], "|"); got != strings.Join(want[i], "|") {
+					t.Errorf("row %d = %q, want %q", i, got, strings.Join(want[i], "|"))
+				}
+			}
+		})
+	}
+}
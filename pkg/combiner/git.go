@@ -0,0 +1,242 @@
+package combiner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// gitCommit is the last commit that touched a file
+type gitCommit struct {
+	Hash   string
+	Author string
+	Date   time.Time
+}
+
+// gitCommand prepares git with args, run in dir; paths are printed as they
+// are, not quoted
+func gitCommand(dir string, args ...string) *exec.Cmd {
+	cmd := exec.Command("git", append([]string{"-c", "core.quotePath=false"}, args...)...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// gitOutput runs git with args in dir and returns its standard output; a
+// failure is reported with git's own message
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := gitCommand(dir, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return out, nil
+}
+
+// gitLastCommits finds the last commit of every tracked file below root,
+// keyed by absolute path. The history is read newest first and only as far
+// back as needed to see each tracked file once.
+func gitLastCommits(root string) (map[string]gitCommit, error) {
+	tracked, err := gitOutput(root, "ls-files", "-z")
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]bool)
+	for _, name := range strings.Split(string(tracked), "\x00") {
+		if name != "" {
+			remaining[name] = true
+		}
+	}
+
+	commits := make(map[string]gitCommit)
+	if len(remaining) == 0 {
+		return commits, nil
+	}
+	// A commit line starts with \x01; the files it touched follow, one per line
+	cmd := gitCommand(root, "log", "--format=%x01%H%x00%an%x00%aI", "--name-only", "--no-renames", "--relative", "--", ".")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git log: %v", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	absRoot, _ := filepath.Abs(root)
+	var current gitCommit
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() && len(remaining) > 0 {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\x01") {
+			fields := strings.SplitN(line[1:], "\x00", 3)
+			if len(fields) == 3 {
+				date, _ := time.Parse(time.RFC3339, fields[2])
+				current = gitCommit{Hash: fields[0], Author: fields[1], Date: date}
+			}
+			continue
+		}
+		if line == "" || !remaining[line] {
+			continue
+		}
+		delete(remaining, line)
+		commits[filepath.Join(absRoot, filepath.FromSlash(line))] = current
+	}
+	return commits, nil
+}
+
+// lastCommitLine describes the last commit of a file for -separator-meta
+// git, or "" for a file git doesn't track
+func lastCommitLine(config *Options, path string) string {
+	abs, _ := filepath.Abs(path)
+	if config.run == nil {
+		return ""
+	}
+	commit, ok := config.run.gitCommits[abs]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%s by %s on %s", commit.Hash, commit.Author, commit.Date.Format("2006-01-02 15:04:05 -0700"))
+}
+
+// gitChangedFiles lists the files below root that differ between ref and
+// the working tree, staged or not, keyed by absolute path. With untracked
+// set, files git doesn't track yet (and doesn't ignore) count as changed.
+func gitChangedFiles(root, ref string, untracked bool) (map[string]bool, error) {
+	// A ref starting with "-" would be read by git as an option
+	if strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("unknown git revision: %s", ref)
+	}
+	if _, err := gitOutput(root, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("unknown git revision: %s", ref)
+	}
+	names, err := gitOutput(root, "diff", "--name-only", "-z", "--no-renames", "--relative", ref, "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	if untracked {
+		others, err := gitOutput(root, "ls-files", "-z", "--others", "--exclude-standard", "--", ".")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, others...)
+	}
+
+	absRoot, _ := filepath.Abs(root)
+	changed := make(map[string]bool)
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" {
+			changed[filepath.Join(absRoot, filepath.FromSlash(name))] = true
+		}
+	}
+	return changed, nil
+}
+
+// gitStagedFiles lists the files below root with changes staged in the
+// index, keyed by absolute path, and those of them with further changes
+// not staged, whose working copy is not what will be committed
+func gitStagedFiles(root string) (map[string]bool, []string, error) {
+	staged, err := gitOutput(root, "diff", "--cached", "--name-only", "-z", "--no-renames", "--relative", "--", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+	unstaged, err := gitOutput(root, "diff", "--name-only", "-z", "--no-renames", "--relative", "--", ".")
+	if err != nil {
+		return nil, nil, err
+	}
+	modified := make(map[string]bool)
+	for _, name := range strings.Split(string(unstaged), "\x00") {
+		modified[name] = name != ""
+	}
+
+	absRoot, _ := filepath.Abs(root)
+	files := make(map[string]bool)
+	var partial []string
+	for _, name := range strings.Split(string(staged), "\x00") {
+		if name == "" {
+			continue
+		}
+		files[filepath.Join(absRoot, filepath.FromSlash(name))] = true
+		if modified[name] {
+			partial = append(partial, name)
+		}
+	}
+	return files, partial, nil
+}
+
+// unchangedReason says why -changed-since or -staged leaves out the file,
+// or returns "" when it is kept
+func unchangedReason(config *Options, path string) string {
+	if config.run == nil || config.run.changed == nil {
+		return ""
+	}
+	abs, _ := filepath.Abs(path)
+	switch {
+	case config.run.changed[abs]:
+		return ""
+	case config.Staged:
+		return "Not staged"
+	default:
+		return "Unchanged since " + config.ChangedSince
+	}
+}
+
+// gitTrackedFiles lists the candidates of -git-tracked: the files below the
+// root (or each -root) that git tracks (and, with -include-untracked, the
+// untracked ones it doesn't ignore) matching a pattern, with the index of
+// the first pattern matching each. Patterns match the path relative to the
+// root or the file name, as with -r, since git lists the whole tree.
+func gitTrackedFiles(config *Options) ([]string, map[string]int, error) {
+	args := []string{"ls-files", "-z", "--cached"}
+	if config.IncludeUntracked {
+		args = append(args, "--others", "--exclude-standard")
+	}
+	var paths []string
+	for _, dir := range searchRoots(config) {
+		listed, err := gitOutput(dir, append(args, "--", ".")...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, name := range strings.Split(string(listed), "\x00") {
+			if name != "" {
+				paths = append(paths, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+		}
+	}
+
+	patternOrder := make(map[string]int)
+	var files []string
+	for _, path := range paths {
+		if _, seen := patternOrder[path]; seen {
+			continue
+		}
+		relPath, _ := filepath.Rel(config.Root, path)
+		name := filepath.ToSlash(relPath)
+		for pi, pattern := range config.Patterns {
+			if !isNegation(pattern) && config.matchGlob(pattern, name) {
+				patternOrder[path] = pi
+				files = append(files, path)
+				break
+			}
+		}
+		if _, matched := patternOrder[path]; !matched {
+			if re := matchRegexps(config.IncludeRegexps, name); re != nil {
+				patternOrder[path] = len(config.Patterns) + regexpIndex(config.IncludeRegexps, re)
+				files = append(files, path)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, patternOrder, nil
+}
@@ -0,0 +1,339 @@
+package combiner
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a git repository holding the files, committed as
+// "Ann <ann@example.com>" on 2024-01-01, isolated from the user's git
+// configuration, and returns its directory
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	root := writeTree(t, files)
+	runGit(t, root, "init", "-q", "-b", "main")
+	runGit(t, root, "add", "-A")
+	commitAs(t, root, "Ann", "2024-01-01T10:00:00+00:00", "initial")
+	return root
+}
+
+// runGit runs git with args in dir and returns its output
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitAs commits the index with the author and date and returns the
+// commit's hash
+func commitAs(t *testing.T, dir, author, date, message string) string {
+	t.Helper()
+	email := strings.ToLower(author) + "@example.com"
+	for _, kind := range []string{"AUTHOR", "COMMITTER"} {
+		t.Setenv("GIT_"+kind+"_NAME", author)
+		t.Setenv("GIT_"+kind+"_EMAIL", email)
+		t.Setenv("GIT_"+kind+"_DATE", date)
+	}
+	runGit(t, dir, "commit", "-q", "-m", message)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+// writeFiles writes (or overwrites) files below root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestGitLastCommits(t *testing.T) {
+	root := gitRepo(t, map[string]string{
+		"a.txt":     "a\n",
+		"sub/b.txt": "b\n",
+		"sub/c.txt": "c\n",
+	})
+	first := runGit(t, root, "rev-parse", "HEAD")
+	writeFiles(t, root, map[string]string{"sub/b.txt": "b2\n", "untracked.txt": "u\n"})
+	runGit(t, root, "add", "sub/b.txt")
+	second := commitAs(t, root, "Bob", "2024-02-01T12:30:00+02:00", "change b")
+	runGit(t, root, "rm", "-q", "sub/c.txt")
+	commitAs(t, root, "Cy", "2024-03-01T00:00:00+00:00", "remove c")
+
+	tests := []struct {
+		name string
+		dir  string
+		want map[string]string // relative to the repository: hash and author
+	}{
+		{"repository", root, map[string]string{"a.txt": first + " Ann", "sub/b.txt": second + " Bob"}},
+		{"subdirectory", filepath.Join(root, "sub"), map[string]string{"sub/b.txt": second + " Bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commits, err := gitLastCommits(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for path, commit := range commits {
+				rel, _ := filepath.Rel(root, path)
+				got[filepath.ToSlash(rel)] = commit.Hash + " " + commit.Author
+				if commit.Date.IsZero() {
+					t.Errorf("%s: no commit date", rel)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("commits = %q, want %q", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s: last commit %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+
+	if _, err := gitLastCommits(t.TempDir()); err == nil {
+		t.Error("gitLastCommits outside a repository succeeded")
+	}
+}
+
+func TestGitSeparatorMeta(t *testing.T) {
+	root := gitRepo(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n"})
+	writeFiles(t, root, map[string]string{"b.txt": "b2\n", "new.txt": "new\n"})
+	runGit(t, root, "add", "b.txt")
+	hash := commitAs(t, root, "Bob", "2024-02-01T12:30:00+02:00", "change b")
+	first := runGit(t, root, "rev-parse", "HEAD~1")
+
+	config := testOptions(t, root, "*.txt")
+	config.SeparatorMeta = []string{META_GIT}
+	out := combine(t, config)
+	for _, want := range []string{
+		"# FILE 1: a.txt\n# Last commit: " + first + " by Ann on 2024-01-01 10:00:00 +0000\n",
+		"# FILE 2: b.txt\n# Last commit: " + hash + " by Bob on 2024-02-01 12:30:00 +0200\n",
+		"# FILE 3: new.txt\n# ===", // untracked: no commit line
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	// Outside a repository the separators go without, after a warning
+	plain := testOptions(t, writeTree(t, map[string]string{"a.txt": "a\n"}), "*.txt")
+	plain.SeparatorMeta = []string{META_GIT}
+	var out2 string
+	stderr := captureStderr(t, func() { out2 = combine(t, plain) })
+	if !strings.Contains(stderr, "Warning: No git metadata for the separators") {
+		t.Errorf("stderr = %q", stderr)
+	}
+	if strings.Contains(out2, "Last commit") {
+		t.Errorf("output has a commit line:\n%s", out2)
+	}
+}
+
+func TestChangedSince(t *testing.T) {
+	root := gitRepo(t, map[string]string{
+		".gitignore":  "*.log\n",
+		"a.txt":       "a\n",
+		"b.txt":       "b\n",
+		"c.txt":       "c\n",
+		"sub/d.txt":   "d\n",
+		"sub/old.txt": "old\n",
+	})
+	runGit(t, root, "tag", "v1")
+	writeFiles(t, root, map[string]string{"b.txt": "b2\n", "sub/d.txt": "d2\n"})
+	runGit(t, root, "add", "-A")
+	commitAs(t, root, "Bob", "2024-02-01T00:00:00+00:00", "change b and d")
+	writeFiles(t, root, map[string]string{
+		"c.txt":         "c, not staged\n",
+		"staged.txt":    "new and staged\n",
+		"untracked.txt": "untracked\n",
+		"debug.log":     "ignored\n",
+	})
+	runGit(t, root, "add", "staged.txt")
+	runGit(t, root, "rm", "-q", "sub/old.txt")
+
+	tests := []struct {
+		name      string
+		root      string
+		ref       string
+		untracked bool
+		want      []string
+		wantErr   string
+	}{
+		{name: "since a tag", root: root, ref: "v1", want: []string{"b.txt", "c.txt", "staged.txt", "sub/d.txt"}},
+		{name: "since HEAD", root: root, ref: "HEAD", want: []string{"c.txt", "staged.txt"}},
+		{name: "with untracked files", root: root, ref: "HEAD", untracked: true, want: []string{"c.txt", "staged.txt", "untracked.txt"}},
+		{name: "below a subdirectory", root: filepath.Join(root, "sub"), ref: "v1", want: []string{"d.txt"}},
+		{name: "unknown ref", root: root, ref: "nope", wantErr: "Cannot list the changes since nope: unknown git revision: nope"},
+		{name: "option-like ref", root: root, ref: "--output=x", wantErr: "unknown git revision: --output=x"},
+		{name: "not a repository", root: t.TempDir(), ref: "HEAD", wantErr: "Cannot list the changes since HEAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, tt.root, "**/*")
+			config.ChangedSince = tt.ref
+			config.IncludeUntracked = tt.untracked
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			report, err := New(config).Select(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Select error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if got := relFiles(t, tt.root, report.Files); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+			for _, file := range report.Skipped {
+				if strings.HasSuffix(file.Path, "a.txt") && file.Reason != "Unchanged since "+tt.ref {
+					t.Errorf("a.txt skipped with %q", file.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestIncludeUntrackedNeedsGit(t *testing.T) {
+	config := testOptions(t, t.TempDir(), "*")
+	config.IncludeUntracked = true
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "--include-untracked needs --changed-since or --git-tracked") {
+		t.Errorf("Validate = %v", err)
+	}
+}
+
+func TestGitTracked(t *testing.T) {
+	root := gitRepo(t, map[string]string{
+		".gitignore": "*.log\n",
+		"a.go":       "package a\n",
+		"sub/b.go":   "package sub\n",
+	})
+	writeFiles(t, root, map[string]string{"build.log": "tracked anyway\n"})
+	runGit(t, root, "add", "-f", "build.log")
+	commitAs(t, root, "Ann", "2024-01-02T00:00:00+00:00", "add the log")
+	writeFiles(t, root, map[string]string{"c.go": "package c\n", "debug.log": "ignored\n"})
+
+	tests := []struct {
+		name      string
+		patterns  []string
+		excludes  []string
+		untracked bool
+		want      []string
+	}{
+		{name: "tracked files", patterns: []string{"*.go", "*.log"}, want: []string{"a.go", "build.log", "sub/b.go"}},
+		{name: "with untracked files", patterns: []string{"*.go", "*.log"}, untracked: true, want: []string{"a.go", "build.log", "c.go", "sub/b.go"}},
+		{name: "relative path pattern", patterns: []string{"sub/*.go"}, want: []string{"sub/b.go"}},
+		{name: "negation", patterns: []string{"*.go", "!sub/**"}, want: []string{"a.go"}},
+		{name: "excludes still apply", patterns: []string{"*.go", "*.log"}, excludes: []string{"sub", "*.log"}, want: []string{"a.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, tt.patterns...)
+			config.GitTracked = true
+			config.IncludeUntracked = tt.untracked
+			config.Excludes = tt.excludes
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	config := testOptions(t, t.TempDir(), "*")
+	config.GitTracked = true
+	if _, err := New(config).Select(context.Background()); err == nil || !strings.Contains(err.Error(), "Cannot list the git files") {
+		t.Errorf("Select outside a repository = %v", err)
+	}
+	config.FilesFrom = "list.txt"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "--git-tracked and --files-from") {
+		t.Errorf("Validate with --files-from = %v", err)
+	}
+}
+
+func TestStaged(t *testing.T) {
+	root := gitRepo(t, map[string]string{
+		"a.txt":     "a\n",
+		"b.txt":     "b\n",
+		"sub/c.txt": "c\n",
+		"gone.txt":  "gone\n",
+	})
+	writeFiles(t, root, map[string]string{
+		"a.txt":         "a, staged\n",
+		"b.txt":         "b, not staged\n",
+		"sub/c.txt":     "c, staged\n",
+		"new.txt":       "new, staged\n",
+		"untracked.txt": "untracked\n",
+	})
+	runGit(t, root, "add", "a.txt", "sub/c.txt", "new.txt")
+	runGit(t, root, "rm", "-q", "gone.txt")
+	writeFiles(t, root, map[string]string{"sub/c.txt": "c, staged and changed again\n"})
+
+	tests := []struct {
+		name        string
+		root        string
+		want        []string
+		wantWarning string
+	}{
+		{"repository", root, []string{"a.txt", "new.txt", "sub/c.txt"}, "Warning: sub/c.txt has changes that are not staged"},
+		{"subdirectory", filepath.Join(root, "sub"), []string{"c.txt"}, "Warning: c.txt has changes that are not staged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, tt.root, "**/*.txt")
+			config.Staged = true
+			var report Report
+			var err error
+			stderr := captureStderr(t, func() { report, err = New(config).Select(context.Background()) })
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if got := relFiles(t, tt.root, report.Files); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(stderr, tt.wantWarning) {
+				t.Errorf("stderr = %q, want %q", stderr, tt.wantWarning)
+			}
+			for _, file := range report.Skipped {
+				if file.Reason != "Not staged" {
+					t.Errorf("%s skipped with %q", file.Path, file.Reason)
+				}
+			}
+		})
+	}
+
+	// Nothing staged: every file is left out
+	runGit(t, root, "commit", "-q", "-m", "staged changes")
+	config := testOptions(t, root, "**/*.txt")
+	config.Staged = true
+	if got := selectRel(t, config); len(got) != 0 {
+		t.Errorf("files after the commit = %q", got)
+	}
+
+	config.ChangedSince = "HEAD"
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "--staged and --changed-since") {
+		t.Errorf("Validate with --changed-since = %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// gitAttrRule is a single .gitattributes line that declares a file as text or binary
+type gitAttrRule struct {
+	Pattern string
+	Binary  bool
+}
+
+// loadGitattributes reads the text/binary declarations from <root>/.gitattributes.
+// Lines that don't touch the text attribute (e.g. only "diff" or "eol") are ignored.
+func loadGitattributes(root string, verbose bool) []gitAttrRule {
+	var rules []gitAttrRule
+
+	file, err := os.Open(filepath.Join(root, ".gitattributes"))
+	if err != nil {
+		return rules
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		decided, binary := false, false
+		for _, attr := range fields[1:] {
+			switch {
+			case attr == "binary", attr == "-text":
+				decided, binary = true, true
+			case attr == "text", strings.HasPrefix(attr, "text=") && attr != "text=auto":
+				decided, binary = true, false
+			}
+		}
+		if decided {
+			rules = append(rules, gitAttrRule{Pattern: fields[0], Binary: binary})
+		}
+	}
+
+	if verbose {
+		fmt.Printf("Loaded %d text/binary rules from .gitattributes\n", len(rules))
+	}
+
+	return rules
+}
+
+// gitattributesBinary reports whether .gitattributes classifies relPath as
+// binary. The second result is false when no rule applies. As in git, the
+// last matching line wins.
+func gitattributesBinary(rules []gitAttrRule, relPath string) (bool, bool) {
+	binary, decided := false, false
+	base := relPath
+	if idx := strings.LastIndex(relPath, "/"); idx >= 0 {
+		base = relPath[idx+1:]
+	}
+
+	for _, rule := range rules {
+		var matched bool
+		if strings.Contains(rule.Pattern, "/") {
+			matched, _ = doublestar.Match(strings.TrimPrefix(rule.Pattern, "/"), relPath)
+		} else {
+			matched, _ = doublestar.Match(rule.Pattern, base)
+		}
+		if matched {
+			binary, decided = rule.Binary, true
+		}
+	}
+	return binary, decided
+}
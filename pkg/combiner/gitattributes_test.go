@@ -0,0 +1,87 @@
+package combiner
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGitattributesBinary(t *testing.T) {
+	root := writeTree(t, map[string]string{".gitattributes": "# comment\n" +
+		"*.bin binary\n" +
+		"*.dat -text\n" +
+		"docs/*.dat text\n" +
+		"*.svg text=auto\n" +
+		"*.txt eol=lf\n" +
+		"/top.raw binary\n" +
+		"*.ini text eol=crlf\n",
+	})
+	rules := loadGitattributes(root, false)
+	if len(rules) != 5 {
+		t.Fatalf("loaded %d rules, want 5: %+v", len(rules), rules)
+	}
+
+	tests := []struct {
+		path     string
+		binary   bool
+		declared bool
+	}{
+		{"a.bin", true, true},
+		{"deep/dir/a.bin", true, true},
+		{"a.dat", true, true},
+		{"docs/a.dat", false, true}, // the later line wins
+		{"other/docs/a.dat", true, true},
+		{"logo.svg", false, false},
+		{"notes.txt", false, false},
+		{"top.raw", true, true},
+		{"sub/top.raw", false, false},
+		{"app.ini", false, true},
+	}
+	for _, tt := range tests {
+		binary, declared := gitattributesBinary(rules, tt.path)
+		if binary != tt.binary || declared != tt.declared {
+			t.Errorf("%s: got binary=%v declared=%v, want %v %v", tt.path, binary, declared, tt.binary, tt.declared)
+		}
+	}
+
+	if rules := loadGitattributes(t.TempDir(), false); len(rules) != 0 {
+		t.Errorf("missing .gitattributes loaded %+v", rules)
+	}
+}
+
+func TestRespectGitattributes(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".gitattributes": "*.fixture binary\n*.pak text\n",
+		"a.fixture":      "looks like text\n",
+		"b.pak":          "has a \x00 byte\n",
+		"c.pak.bak":      "has a \x00 byte\n",
+		"d.txt":          "text\n",
+	})
+	tests := []struct {
+		respect bool
+		want    []string
+	}{
+		{false, []string{"a.fixture", "d.txt"}},
+		{true, []string{"b.pak", "d.txt"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*.*")
+		config.Excludes = []string{".gitattributes"}
+		config.RespectGitattributes = tt.respect
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("respect=%v: got %q, want %q", tt.respect, got, tt.want)
+		}
+	}
+
+	config := testOptions(t, root, "a.fixture")
+	config.RespectGitattributes = true
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Reason != "Binary file (.gitattributes)" {
+		t.Errorf("skipped %+v, want a.fixture as a .gitattributes binary", report.Skipped)
+	}
+}
@@ -0,0 +1,160 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreRule is one pattern line of a .gitignore file
+type gitignoreRule struct {
+	Pattern  string
+	Negate   bool   // "!pattern" re-includes what earlier rules ignored
+	DirOnly  bool   // "pattern/" only matches directories
+	Anchored bool   // a slash before the end ties the pattern to Base
+	Base     string // directory of the .gitignore, relative to the root ("" for the root)
+}
+
+// parseGitignoreLine turns a .gitignore line into a rule; ok is false for
+// blank lines and comments
+func parseGitignoreLine(line string) (rule gitignoreRule, ok bool) {
+	// Trailing spaces are dropped unless escaped ("name\ ")
+	line = strings.TrimRight(line, "\r")
+	for n := len(line); n > 0 && (line[n-1] == ' ' || line[n-1] == '\t'); n-- {
+		if n >= 2 && line[n-2] == '\\' {
+			break
+		}
+		line = line[:n-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.Negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.DirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.Anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.Pattern = line
+	return rule, true
+}
+
+// loadGitignore reads the .gitignore in root and those in its subdirectories.
+// A directory's rules come after its parents' rules, so deeper files override
+// shallower ones, and each rule only applies inside its own directory.
+// Directories that are already ignored are not descended into, like git.
+func loadGitignore(root string, verbose bool, prune func(dir string) bool) []gitignoreRule {
+	var rules []gitignoreRule
+	var sources int
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		relPath, _ := filepath.Rel(root, path)
+		relPath = filepath.ToSlash(relPath)
+		if relPath == "." {
+			relPath = ""
+		} else if d.Name() == ".git" || ignoredPath(rules, relPath, true) || (prune != nil && prune(path)) {
+			return filepath.SkipDir
+		}
+
+		dirRules := readGitignore(filepath.Join(path, ".gitignore"), relPath)
+		if len(dirRules) > 0 {
+			rules = append(rules, dirRules...)
+			sources++
+		}
+		return nil
+	})
+
+	if verbose {
+		fmt.Printf("Loaded %d patterns from %d .gitignore files\n", len(rules), sources)
+	}
+	return rules
+}
+
+// readGitignore parses one .gitignore file, in file order, scoping its rules to base
+func readGitignore(filename, base string) []gitignoreRule {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if rule, ok := parseGitignoreLine(scanner.Text()); ok {
+			rule.Base = base
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// matches reports whether the rule matches relPath, a slash-separated path
+// relative to the root that names a directory when isDir is set
+func (r gitignoreRule) matches(relPath string, isDir bool) bool {
+	if r.DirOnly && !isDir {
+		return false
+	}
+	if r.Base != "" {
+		if !strings.HasPrefix(relPath, r.Base+"/") {
+			return false
+		}
+		relPath = relPath[len(r.Base)+1:]
+	}
+	if r.Anchored {
+		return matchSegments(strings.Split(r.Pattern, "/"), strings.Split(relPath, "/"))
+	}
+	matched, _ := path.Match(r.Pattern, path.Base(relPath))
+	return matched
+}
+
+// gitignored applies rules in order to a file and its parent directories.
+// The last rule that matches decides, so a later "!" rule re-includes a
+// file even when an earlier rule ignored its whole directory.
+func gitignored(rules []gitignoreRule, relPath string) bool {
+	return ignoredPath(rules, relPath, false)
+}
+
+// gitignoredDir reports whether every file below the directory relPath is
+// ignored. A "!" rule may re-include files inside an ignored directory, so
+// this is only decided when there are no negated rules.
+func gitignoredDir(rules []gitignoreRule, relPath string) bool {
+	for _, rule := range rules {
+		if rule.Negate {
+			return false
+		}
+	}
+	return ignoredPath(rules, relPath, true)
+}
+
+// ignoredPath is gitignored for a path that may itself be a directory
+func ignoredPath(rules []gitignoreRule, relPath string, isDir bool) bool {
+	ignored := false
+	parts := strings.Split(relPath, "/")
+	for _, rule := range rules {
+		matched := rule.matches(relPath, isDir)
+		for i := 1; i < len(parts) && !matched; i++ {
+			matched = rule.matches(strings.Join(parts[:i], "/"), true)
+		}
+		if matched {
+			ignored = !rule.Negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,110 @@
+package combiner
+
+import "testing"
+
+func TestGitignoreNegation(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".gitignore":     "*.log\n!keep.log\nbuild/\n!build/\nbuild/*\n!build/dist.txt\n",
+		"a.log":          "a\n",
+		"keep.log":       "keep\n",
+		"sub/keep.log":   "keep\n",
+		"sub/b.log":      "b\n",
+		"build/dist.txt": "dist\n",
+		"build/tmp.txt":  "tmp\n",
+		"main.go":        "package main\n",
+	})
+	config := testOptions(t, root, "**/*")
+	config.Excludes = []string{".gitignore"}
+	want := []string{"build/dist.txt", "keep.log", "main.go", "sub/keep.log"}
+	if got := selectRel(t, config); !sameStrings(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	config = testOptions(t, root, "**/*.log")
+	config.IgnoreGitignore = true
+	if got := selectRel(t, config); len(got) != 4 {
+		t.Errorf("-ignore-gitignore kept %q, want all 4 logs", got)
+	}
+}
+
+func TestNestedGitignore(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".gitignore":           "*.tmp\n",
+		"a.tmp":                "a\n",
+		"a.txt":                "a\n",
+		"web/.gitignore":       "dist/\n*.map\n!important.tmp\n",
+		"web/app.js":           "app\n",
+		"web/app.js.map":       "map\n",
+		"web/dist/bundle.js":   "bundle\n",
+		"web/important.tmp":    "kept\n",
+		"lib/app.js.map":       "map\n", // web's rule does not reach here
+		"lib/dist/x.js":        "x\n",
+		"ignored/.gitignore":   "*\n",
+		"ignored/anything.txt": "x\n",
+	})
+	config := testOptions(t, root, "**/*")
+	config.Excludes = []string{".gitignore"}
+	want := []string{"a.txt", "lib/app.js.map", "lib/dist/x.js", "web/app.js", "web/important.tmp"}
+	if got := selectRel(t, config); !sameStrings(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestParseGitignoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		rule gitignoreRule
+		ok   bool
+	}{
+		{"", gitignoreRule{}, false},
+		{"# comment", gitignoreRule{}, false},
+		{"*.log", gitignoreRule{Pattern: "*.log"}, true},
+		{"*.log  \r", gitignoreRule{Pattern: "*.log"}, true},
+		{`name\ `, gitignoreRule{Pattern: `name\ `}, true},
+		{"!keep.log", gitignoreRule{Pattern: "keep.log", Negate: true}, true},
+		{`\!bang`, gitignoreRule{Pattern: "!bang"}, true},
+		{`\#hash`, gitignoreRule{Pattern: "#hash"}, true},
+		{"build/", gitignoreRule{Pattern: "build", DirOnly: true}, true},
+		{"/root.txt", gitignoreRule{Pattern: "root.txt", Anchored: true}, true},
+		{"docs/*.md", gitignoreRule{Pattern: "docs/*.md", Anchored: true}, true},
+		{"/", gitignoreRule{}, false},
+	}
+	for _, tt := range tests {
+		rule, ok := parseGitignoreLine(tt.line)
+		if ok != tt.ok || (ok && rule != tt.rule) {
+			t.Errorf("parseGitignoreLine(%q) = %+v, %v; want %+v, %v", tt.line, rule, ok, tt.rule, tt.ok)
+		}
+	}
+}
+
+func TestGitignoreRuleMatches(t *testing.T) {
+	tests := []struct {
+		line  string
+		base  string
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"*.log", "", "a.log", false, true},
+		{"*.log", "", "deep/dir/a.log", false, true},
+		{"/root.txt", "", "root.txt", false, true},
+		{"/root.txt", "", "sub/root.txt", false, false},
+		{"docs/*.md", "", "docs/a.md", false, true},
+		{"docs/*.md", "", "x/docs/a.md", false, false},
+		{"**/gen", "", "a/b/gen", true, true},
+		{"a/**/z.txt", "", "a/b/c/z.txt", false, true},
+		{"build/", "", "build", false, false},
+		{"build/", "", "build", true, true},
+		{"*.map", "web", "web/app.js.map", false, true},
+		{"*.map", "web", "lib/app.js.map", false, false},
+		{"/dist", "web", "web/dist", true, true},
+		{"/dist", "web", "web/x/dist", true, false},
+	}
+	for _, tt := range tests {
+		rule, _ := parseGitignoreLine(tt.line)
+		rule.Base = tt.base
+		if got := rule.matches(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("%q (in %q) matches %q = %v, want %v", tt.line, tt.base, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,481 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// Glob engines selectable with -glob-engine
+const (
+	GLOB_STANDARD   = "standard"
+	GLOB_DOUBLESTAR = "doublestar"
+)
+
+func ValidGlobEngine(engine string) bool {
+	return engine == GLOB_STANDARD || engine == GLOB_DOUBLESTAR
+}
+
+// matchPattern reports whether a slash-separated path relative to root
+// matches pattern. Patterns without a directory component are matched
+// against the base name; patterns with one are matched against the full
+// relative path, where ** stands for any number of directories. The
+// {a,b} alternatives work with both engines.
+func matchPattern(engine, pattern, relPath string) bool {
+	base := relPath
+	if idx := strings.LastIndex(relPath, "/"); idx >= 0 {
+		base = relPath[idx+1:]
+	}
+
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if engine == GLOB_DOUBLESTAR {
+		if matched, _ := doublestar.Match(pattern, relPath); matched {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			matched, _ := doublestar.Match(pattern, base)
+			return matched
+		}
+		return false
+	}
+
+	// The standard matchers know no {a,b}; try each alternative in turn
+	if alternatives := expandBraces(pattern); len(alternatives) > 1 {
+		for _, alternative := range alternatives {
+			if matchPattern(engine, alternative, relPath) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if strings.Contains(pattern, "/") || pattern == "**" {
+		return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+	}
+
+	matched, _ := filepath.Match(pattern, base)
+	return matched
+}
+
+// matchGlob matches a -p pattern against a relative path with the
+// -glob-engine, ignoring case with -ignore-case
+func (config *Options) matchGlob(pattern, relPath string) bool {
+	return matchPattern(config.GlobEngine, foldCase(pattern, config.IgnoreCase), foldCase(relPath, config.IgnoreCase))
+}
+
+// foldCase lowercases s when letter case is to be ignored
+func foldCase(s string, ignoreCase bool) string {
+	if ignoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// matchSegments matches path segments one by one with path.Match, letting a
+// ** segment consume zero or more directories
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// globFiles expands a single pattern relative to root using the selected engine.
+// Absolute patterns and patterns escaping root are always handled by
+// filepath.Glob, and match case-sensitively even with ignoreCase.
+func globFiles(root, pattern, engine string, ignoreCase bool) ([]string, error) {
+	if filepath.IsAbs(pattern) || strings.HasPrefix(filepath.ToSlash(pattern), "../") {
+		return filepath.Glob(filepath.Join(root, pattern))
+	}
+	if needsWalk(pattern) {
+		if err := validatePattern(engine, pattern); err != nil {
+			return nil, err
+		}
+		matches, err := walkGlobs(root, []string{pattern}, engine, ignoreCase, nil)
+		return matches[0], err
+	}
+	if ignoreCase {
+		return globFolded(root, pattern, engine)
+	}
+	if engine != GLOB_DOUBLESTAR {
+		var matches []string
+		seen := make(map[string]bool)
+		for _, alternative := range expandBraces(pattern) {
+			found, err := filepath.Glob(filepath.Join(root, alternative))
+			if err != nil {
+				return nil, err
+			}
+			for _, m := range found {
+				if !seen[m] {
+					seen[m] = true
+					matches = append(matches, m)
+				}
+			}
+		}
+		return matches, nil
+	}
+
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	if err := validatePattern(engine, pattern); err != nil {
+		return nil, err
+	}
+
+	matches, err := doublestar.Glob(os.DirFS(root), pattern, doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, err
+	}
+
+	for i, m := range matches {
+		matches[i] = filepath.Join(root, filepath.FromSlash(m))
+	}
+	return matches, nil
+}
+
+// matchRegexps returns the first expression matching relPath, or nil
+func matchRegexps(exprs []*regexp.Regexp, relPath string) *regexp.Regexp {
+	for _, re := range exprs {
+		if re.MatchString(relPath) {
+			return re
+		}
+	}
+	return nil
+}
+
+// isNegation reports whether a -p pattern is a !pattern that takes files
+// back out of the earlier patterns' matches
+func isNegation(pattern string) bool {
+	return len(pattern) > 1 && pattern[0] == '!'
+}
+
+// applyNegations drops the files that a !pattern takes out, gitignore-style:
+// going through the patterns after the first one that found a file, a
+// !pattern matching it removes it and a later plain pattern matching it
+// brings it back. patternOrder holds that first pattern's index for every
+// file.
+func applyNegations(config *Options, files []string, patternOrder map[string]int) ([]string, []FileInfo) {
+	negations := false
+	for _, pattern := range config.Patterns {
+		negations = negations || isNegation(pattern)
+	}
+	if !negations {
+		return files, nil
+	}
+
+	var kept []string
+	var skipped []FileInfo
+	for _, file := range files {
+		relPath, _ := filepath.Rel(config.Root, file)
+		relPath = filepath.ToSlash(relPath)
+		negatedBy := ""
+		for j := patternOrder[file] + 1; j < len(config.Patterns); j++ {
+			pattern := config.Patterns[j]
+			if isNegation(pattern) && config.matchGlob(pattern[1:], relPath) {
+				negatedBy = pattern
+			} else if !isNegation(pattern) && config.matchGlob(pattern, relPath) {
+				negatedBy = ""
+			}
+		}
+		if negatedBy != "" {
+			skipped = append(skipped, FileInfo{file, "Negated by " + negatedBy})
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, skipped
+}
+
+// REGEX_PREFIX marks a -p or -e pattern as a Go regexp (re:_mock\.go$)
+const REGEX_PREFIX = "re:"
+
+// ExtractRegexPatterns moves the re: patterns of -p and -e to the include
+// and exclude regexps (-pe, -ee), which match the slash-separated path
+// relative to the root. With -ignore-case all of them are made
+// case-insensitive.
+func ExtractRegexPatterns(config *Options) error {
+	var err error
+	if config.Patterns, config.IncludeRegexps, err = splitRegexPatterns(config.Patterns, config.IncludeRegexps); err != nil {
+		return err
+	}
+	if config.Excludes, config.ExcludeRegexps, err = splitRegexPatterns(config.Excludes, config.ExcludeRegexps); err != nil {
+		return err
+	}
+	if config.IgnoreCase {
+		config.IncludeRegexps = foldRegexps(config.IncludeRegexps)
+		config.ExcludeRegexps = foldRegexps(config.ExcludeRegexps)
+	}
+	return nil
+}
+
+// foldRegexps returns case-insensitive versions of the expressions
+func foldRegexps(exprs []*regexp.Regexp) []*regexp.Regexp {
+	folded := make([]*regexp.Regexp, len(exprs))
+	for i, re := range exprs {
+		folded[i] = regexp.MustCompile("(?i)" + re.String())
+	}
+	return folded
+}
+
+// splitRegexPatterns compiles the re: patterns, appending them to exprs, and
+// returns the other patterns
+func splitRegexPatterns(patterns []string, exprs []*regexp.Regexp) ([]string, []*regexp.Regexp, error) {
+	var globs []string
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, REGEX_PREFIX) {
+			globs = append(globs, pattern)
+			continue
+		}
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, REGEX_PREFIX))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid regex pattern %q: %v", pattern, err)
+		}
+		exprs = append(exprs, re)
+	}
+	return globs, exprs, nil
+}
+
+// regexpIndex returns the position of re in exprs, so files matched by a
+// -pe regexp sort after those of the -p patterns, in -pe order
+func regexpIndex(exprs []*regexp.Regexp, re *regexp.Regexp) int {
+	for i, e := range exprs {
+		if e == re {
+			return i
+		}
+	}
+	return len(exprs)
+}
+
+// needsWalk reports whether pattern can match at any depth below root ("**"),
+// so expanding it means walking the whole tree
+func needsWalk(pattern string) bool {
+	return strings.Contains(pattern, "**") && !filepath.IsAbs(pattern) &&
+		!strings.HasPrefix(filepath.ToSlash(pattern), "../")
+}
+
+// globFolded expands a relative pattern without "**" regardless of letter
+// case: the tree is walked only as deep as the pattern reaches, and each
+// relative path is compared lowercased, as a whole, like filepath.Glob does
+func globFolded(root, pattern, engine string) ([]string, error) {
+	pattern = strings.ToLower(strings.TrimPrefix(filepath.ToSlash(pattern), "./"))
+	if err := validatePattern(engine, pattern); err != nil {
+		return nil, err
+	}
+	depth := strings.Count(pattern, "/") + 1
+
+	var matches []string
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		relPath = strings.ToLower(filepath.ToSlash(relPath))
+		if info.IsDir() {
+			if relPath != "." && strings.Count(relPath, "/")+1 >= depth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		if engine == GLOB_DOUBLESTAR {
+			if matched, _ := doublestar.Match(pattern, relPath); matched {
+				matches = append(matches, p)
+			}
+			return nil
+		}
+		for _, alternative := range expandBraces(pattern) {
+			if matched, _ := path.Match(alternative, relPath); matched {
+				matches = append(matches, p)
+				break
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// validatePattern checks the syntax of a relative pattern for the engine
+func validatePattern(engine, pattern string) error {
+	if engine == GLOB_DOUBLESTAR {
+		if !doublestar.ValidatePattern(strings.TrimPrefix(filepath.ToSlash(pattern), "./")) {
+			return fmt.Errorf("syntax error in pattern")
+		}
+		return nil
+	}
+	for _, alternative := range expandBraces(pattern) {
+		if _, err := path.Match(strings.ReplaceAll(alternative, "**", "*"), ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SplitPatterns splits a comma-separated -p or -e list, leaving the commas
+// of {a,b} groups alone: "*.md,src/**/*.{go,mod}" is two patterns. A comma
+// written \, belongs to the pattern, for file names containing one.
+func SplitPatterns(list string) []string {
+	var patterns []string
+	depth, start := 0, 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) {
+			switch list[i] {
+			case '\\':
+				if i+1 < len(list) {
+					i++
+				}
+				continue
+			case '{':
+				depth++
+				continue
+			case '}':
+				if depth > 0 {
+					depth--
+				}
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if p := strings.TrimSpace(list[start:i]); p != "" {
+			patterns = append(patterns, strings.ReplaceAll(p, `\,`, ","))
+		}
+		start = i + 1
+	}
+	return patterns
+}
+
+// expandBraces expands the {a,b} groups of a pattern, nested ones included,
+// into the patterns they stand for: "*.{go,mod}" gives *.go and *.mod.
+// Braces without a comma inside are kept as they are.
+func expandBraces(pattern string) []string {
+	open := -1
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			alternatives := splitAlternatives(pattern[open+1 : i])
+			if len(alternatives) < 2 {
+				continue
+			}
+			var expanded []string
+			for _, alternative := range alternatives {
+				expanded = append(expanded, expandBraces(pattern[:open]+alternative+pattern[i+1:])...)
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// expandPatterns expands the {a,b} groups of every pattern, so that each
+// alternative of an exclude also counts as a directory name or prefix
+func expandPatterns(patterns []string) []string {
+	var expanded []string
+	for _, pattern := range patterns {
+		expanded = append(expanded, expandBraces(pattern)...)
+	}
+	return expanded
+}
+
+// splitAlternatives splits the inside of a {a,b} group at its top-level
+// commas
+func splitAlternatives(group string) []string {
+	var alternatives []string
+	depth, start := 0, 0
+	for i := 0; i < len(group); i++ {
+		switch group[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				alternatives = append(alternatives, group[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(alternatives, group[start:])
+}
+
+// walkGlobs walks root once and returns, for each of the (validated)
+// patterns, every regular file whose relative path it matches. Directories
+// for which prune returns true are skipped. With ignoreCase, letter case is
+// not significant.
+func walkGlobs(root string, patterns []string, engine string, ignoreCase bool, prune func(dir string) bool) ([][]string, error) {
+	matches := make([][]string, len(patterns))
+	for i, pattern := range patterns {
+		if engine == GLOB_DOUBLESTAR {
+			pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		}
+		patterns[i] = foldCase(pattern, ignoreCase)
+	}
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() && prune != nil && prune(p) {
+			return filepath.SkipDir
+		}
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		relPath = foldCase(filepath.ToSlash(relPath), ignoreCase)
+		for i, pattern := range patterns {
+			if engine == GLOB_DOUBLESTAR {
+				// Like doublestar.Glob: the whole relative path must match
+				if matched, _ := doublestar.Match(pattern, relPath); matched {
+					matches[i] = append(matches[i], p)
+				}
+			} else if matchPattern(engine, pattern, relPath) {
+				matches[i] = append(matches[i], p)
+			}
+		}
+		return nil
+	})
+	return matches, err
+}
@@ -0,0 +1,218 @@
+package combiner
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatchPatternEngines(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "pkg/main.go", true}, // no directory part: the base name
+		{"*.go", "main.txt", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "a/b/c.go", true},
+		{"src/**/*.go", "src/a.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"src/**/*.go", "lib/src/a.go", false},
+		{"a/b/*.txt", "a/b/c.txt", true},
+		{"a/b/*.txt", "a/b/c/d.txt", false},
+		{"a/*/c.txt", "a/b/c.txt", true},
+		{"./a/b/*.txt", "a/b/c.txt", true},
+		{"*.{go,mod}", "go.mod", true},
+		{"*.{go,mod}", "go.sum", false},
+		{"src/{a,b}/*.go", "src/b/x.go", true},
+		{"src/{a,b}/*.go", "src/c/x.go", false},
+		{"file?.txt", "file1.txt", true},
+		{"[ab].txt", "c.txt", false},
+	}
+	// The two engines must agree on all of these
+	for _, engine := range []string{GLOB_STANDARD, GLOB_DOUBLESTAR} {
+		for _, tt := range tests {
+			if got := matchPattern(engine, tt.pattern, tt.path); got != tt.want {
+				t.Errorf("%s: matchPattern(%q, %q) = %v, want %v", engine, tt.pattern, tt.path, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestGlobDiscoveryMatrix(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":        "package main\n",
+		"src/a.go":       "package src\n",
+		"src/sub/b.go":   "package sub\n",
+		"a/b/c.txt":      "c\n",
+		"a/b/deep/d.txt": "d\n",
+		"a/e.txt":        "e\n",
+	})
+	tests := []struct {
+		pattern   string
+		recursive bool
+		want      []string
+	}{
+		{"*.go", false, []string{"main.go"}},
+		{"*.go", true, []string{"main.go", "src/a.go", "src/sub/b.go"}},
+		{"**/*.go", false, []string{"main.go", "src/a.go", "src/sub/b.go"}},
+		{"src/**/*.go", false, []string{"src/a.go", "src/sub/b.go"}},
+		{"src/*.go", false, []string{"src/a.go"}},
+		{"a/b/*.txt", false, []string{"a/b/c.txt"}},
+		{"a/b/*.txt", true, []string{"a/b/c.txt"}},
+		{"a/**/*.txt", false, []string{"a/b/c.txt", "a/b/deep/d.txt", "a/e.txt"}},
+	}
+	for _, engine := range []string{GLOB_STANDARD, GLOB_DOUBLESTAR} {
+		for _, tt := range tests {
+			name := fmt.Sprintf("%s/%s/recursive=%v", engine, tt.pattern, tt.recursive)
+			t.Run(name, func(t *testing.T) {
+				config := testOptions(t, root, tt.pattern)
+				config.GlobEngine = engine
+				config.Recursive = tt.recursive
+				if got := selectRel(t, config); !sameStrings(got, tt.want) {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSplitPatterns(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"*.go,*.md", []string{"*.go", "*.md"}},
+		{" *.go , *.md ,", []string{"*.go", "*.md"}},
+		{"*.md,src/**/*.{go,mod}", []string{"*.md", "src/**/*.{go,mod}"}},
+		{"{a,{b,c}}.txt,d", []string{"{a,{b,c}}.txt", "d"}},
+		{`a\,b.txt,c`, []string{"a,b.txt", "c"}},
+		{`trailing\`, []string{`trailing\`}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitPatterns(tt.list); !sameStrings(got, tt.want) {
+			t.Errorf("SplitPatterns(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"*.go", []string{"*.go"}},
+		{"*.{go,mod}", []string{"*.go", "*.mod"}},
+		{"{src,lib}/*.{c,h}", []string{"src/*.c", "src/*.h", "lib/*.c", "lib/*.h"}},
+		{"{a,{b,c}}", []string{"a", "b", "c"}},
+		{"{single}", []string{"{single}"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+	}
+	for _, tt := range tests {
+		if got := expandBraces(tt.pattern); !sameStrings(got, tt.want) {
+			t.Errorf("expandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestNegationPatterns(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":          "package a\n",
+		"a_test.go":     "package a\n",
+		"sub/b.go":      "package sub\n",
+		"sub/b_test.go": "package sub\n",
+		"keep_test.go":  "package a\n",
+	})
+	tests := []struct {
+		patterns []string
+		want     []string
+	}{
+		{[]string{"**/*.go", "!**/*_test.go"}, []string{"a.go", "sub/b.go"}},
+		{[]string{"**/*.go", "!**/*_test.go", "keep_test.go"}, []string{"a.go", "keep_test.go", "sub/b.go"}},
+		{[]string{"!**/*_test.go", "**/*.go"}, []string{"a.go", "a_test.go", "keep_test.go", "sub/b.go", "sub/b_test.go"}},
+		{[]string{"**/*.go", "!sub/**"}, []string{"a.go", "a_test.go", "keep_test.go"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, tt.patterns...)
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreCase(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"README.MD":     "# readme\n",
+		"docs/Guide.md": "# guide\n",
+		"Vendor/x.md":   "# vendored\n",
+	})
+	config := testOptions(t, root, "*.md")
+	config.Recursive = true
+	config.Excludes = []string{"vendor"}
+	if got, want := selectRel(t, config), []string{"Vendor/x.md", "docs/Guide.md"}; !sameStrings(got, want) {
+		t.Errorf("case-sensitive: got %q, want %q", got, want)
+	}
+
+	config = testOptions(t, root, "*.md")
+	config.Recursive = true
+	config.Excludes = []string{"vendor"}
+	config.IgnoreCase = true
+	if got, want := selectRel(t, config), []string{"README.MD", "docs/Guide.md"}; !sameStrings(got, want) {
+		t.Errorf("-ignore-case: got %q, want %q", got, want)
+	}
+}
+
+func TestExtractRegexPatterns(t *testing.T) {
+	config := DefaultOptions()
+	config.Patterns = []string{"*.go", `re:_mock\.go$`}
+	config.Excludes = []string{"vendor", `re:\.gen\.`}
+	config.IgnoreCase = true
+	if err := ExtractRegexPatterns(config); err != nil {
+		t.Fatal(err)
+	}
+	if !sameStrings(config.Patterns, []string{"*.go"}) || !sameStrings(config.Excludes, []string{"vendor"}) {
+		t.Errorf("globs left: %q and %q", config.Patterns, config.Excludes)
+	}
+	if len(config.IncludeRegexps) != 1 || !config.IncludeRegexps[0].MatchString("db_MOCK.go") {
+		t.Errorf("include regexps = %v, want a case-insensitive _mock\\.go$", config.IncludeRegexps)
+	}
+	if len(config.ExcludeRegexps) != 1 || !config.ExcludeRegexps[0].MatchString("api.gen.go") {
+		t.Errorf("exclude regexps = %v", config.ExcludeRegexps)
+	}
+
+	config.Patterns = []string{"re:("}
+	if err := ExtractRegexPatterns(config); err == nil {
+		t.Error("invalid regexp accepted")
+	}
+}
+
+// benchmarkTree creates dirs directories of files files each
+func benchmarkTree(b *testing.B, dirs, files int) string {
+	tree := make(map[string]string)
+	for d := 0; d < dirs; d++ {
+		for f := 0; f < files; f++ {
+			ext := []string{".go", ".md", ".txt", ".json"}[f%4]
+			tree[fmt.Sprintf("d%02d/sub/f%03d%s", d, f, ext)] = "x\n"
+		}
+	}
+	return writeTree(b, tree)
+}
+
+// BenchmarkWalkGlobs measures the single walk shared by all ** patterns
+// against the number of patterns; the cost should grow with the tree, not
+// with the pattern count
+func BenchmarkWalkGlobs(b *testing.B) {
+	root := benchmarkTree(b, 20, 50)
+	all := []string{"**/*.go", "**/*.md", "**/*.txt", "**/*.json", "d0*/**/*.go", "**/sub/*.md", "**/f00*", "**/*.{go,md}"}
+	for _, n := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("patterns=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := walkGlobs(root, append([]string(nil), all[:n]...), GLOB_DOUBLESTAR, false, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// grepSkipReason reads a file line by line against the -grep and -grep-not
+// expressions and says why they leave it out, or returns "" when it is kept.
+// Reading stops as soon as the outcome is known: at the first -grep-not
+// match, or at the first -grep match when there are no -grep-not
+// expressions left to look for.
+func grepSkipReason(config *Options, path string) string {
+	if len(config.GrepRegexps) == 0 && len(config.GrepNotRegexps) == 0 {
+		return ""
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Sprintf("Cannot read: %v", err)
+	}
+	defer file.Close()
+
+	found := len(config.GrepRegexps) == 0
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if re := matchContent(config.GrepNotRegexps, line); re != nil {
+				return fmt.Sprintf("Contains --grep-not %s", re)
+			}
+			if !found && matchContent(config.GrepRegexps, line) != nil {
+				found = true
+				if len(config.GrepNotRegexps) == 0 {
+					return ""
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Sprintf("Cannot read: %v", err)
+		}
+	}
+	if !found {
+		return "No --grep match"
+	}
+	return ""
+}
+
+// matchContent returns the first expression matching a line, or nil
+func matchContent(exprs []*regexp.Regexp, line []byte) *regexp.Regexp {
+	for _, re := range exprs {
+		if re.Match(line) {
+			return re
+		}
+	}
+	return nil
+}
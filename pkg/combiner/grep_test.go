@@ -0,0 +1,89 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestGrepSkipReason(t *testing.T) {
+	compile := func(exprs ...string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, expr := range exprs {
+			res = append(res, regexp.MustCompile(expr))
+		}
+		return res
+	}
+	tests := []struct {
+		name    string
+		content string
+		grep    []string
+		grepNot []string
+		want    string
+	}{
+		{"no expressions", "anything\n", nil, nil, ""},
+		{"grep match", "a\n// TODO: fix\n", []string{"TODO"}, nil, ""},
+		{"grep on the last line without newline", "a\nb TODO", []string{"TODO"}, nil, ""},
+		{"grep without match", "a\nb\n", []string{"TODO"}, nil, "No --grep match"},
+		{"any grep matches", "FIXME\n", []string{"TODO", "FIXME"}, nil, ""},
+		{"empty file", "", []string{"."}, nil, "No --grep match"},
+		{"grep-not match", "// Code generated. DO NOT EDIT.\n", nil, []string{"DO NOT EDIT"}, "Contains --grep-not DO NOT EDIT"},
+		{"grep-not without match", "package a\n", nil, []string{"DO NOT EDIT"}, ""},
+		{"first grep-not reported", "x y\n", nil, []string{"y", "x"}, "Contains --grep-not y"},
+		{"grep-not wins over grep", "TODO\nDO NOT EDIT\n", []string{"TODO"}, []string{"DO NOT EDIT"}, "Contains --grep-not DO NOT EDIT"},
+		{"grep-not before grep", "DO NOT EDIT\nTODO\n", []string{"TODO"}, []string{"DO NOT EDIT"}, "Contains --grep-not DO NOT EDIT"},
+		{"grep and no grep-not", "TODO\n", []string{"TODO"}, []string{"DO NOT EDIT"}, ""},
+		{"anchors apply per line", "package a\nfunc main() {}\n", []string{"^func main"}, nil, ""},
+		{"no match across lines", "TO\nDO\n", []string{"TO\\s*DO"}, nil, "No --grep match"},
+		{"case sensitive", "todo\n", []string{"TODO"}, nil, "No --grep match"},
+		{"case-insensitive flag", "todo\n", []string{"(?i)TODO"}, nil, ""},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "file.txt")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			config := DefaultOptions()
+			config.GrepRegexps = compile(tt.grep...)
+			config.GrepNotRegexps = compile(tt.grepNot...)
+			if got := grepSkipReason(config, path); got != tt.want {
+				t.Errorf("grepSkipReason = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrepSelection(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"todo.go":      "package a\n\n// TODO: split\n",
+		"generated.go": "// Code generated by tool. DO NOT EDIT.\n\n// TODO: never\n",
+		"plain.go":     "package a\n",
+	})
+	tests := []struct {
+		name    string
+		grep    []string
+		grepNot []string
+		want    []string
+	}{
+		{"grep", []string{"TODO"}, nil, []string{"generated.go", "todo.go"}},
+		{"grep-not", nil, []string{"DO NOT EDIT"}, []string{"plain.go", "todo.go"}},
+		{"both", []string{"TODO"}, []string{"DO NOT EDIT"}, []string{"todo.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.go")
+			for _, expr := range tt.grep {
+				config.GrepRegexps = append(config.GrepRegexps, regexp.MustCompile(expr))
+			}
+			for _, expr := range tt.grepNot {
+				config.GrepNotRegexps = append(config.GrepNotRegexps, regexp.MustCompile(expr))
+			}
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
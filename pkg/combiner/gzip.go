@@ -0,0 +1,41 @@
+package combiner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// decompressInput returns data unchanged unless it is a gzip stream, as
+// written by -gzip, in which case it is decompressed
+func decompressInput(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	return io.ReadAll(gz)
+}
+
+// compressOutput wraps w in a gzip writer at config.GzipLevel when -gzip is
+// set. The returned close function must be called before w is flushed.
+func compressOutput(config *Options, w io.Writer) (io.Writer, func() error) {
+	if !config.Gzip {
+		return w, func() error { return nil }
+	}
+
+	counter := &countingWriter{w: w}
+	// The level was validated in Options.Validate, so this cannot fail
+	gz, _ := gzip.NewWriterLevel(counter, config.GzipLevel)
+	return gz, func() error {
+		err := gz.Close()
+		config.run.compressed = counter.n
+		return err
+	}
+}
@@ -0,0 +1,57 @@
+package combiner
+
+import (
+	"compress/gzip"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGzipLevels(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": strings.Repeat("compressible line\n", 2000)})
+	plain := testOptions(t, root, "*.txt")
+	want := combine(t, plain)
+
+	sizes := make(map[int]int)
+	for _, level := range []int{gzip.NoCompression, gzip.BestSpeed, gzip.DefaultCompression, gzip.BestCompression} {
+		t.Run(fmt.Sprint(level), func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.Output += ".gz"
+			config.Gzip = true
+			config.GzipLevel = level
+			data := []byte(combine(t, config))
+			if !strings.HasPrefix(string(data), string(gzipMagic)) {
+				t.Fatal("output is not gzip")
+			}
+			got, err := decompressInput(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Error("decompressed output differs from the plain output")
+			}
+			sizes[level] = len(data)
+		})
+	}
+	if sizes[gzip.BestCompression] >= sizes[gzip.NoCompression] {
+		t.Errorf("level 9 wrote %d bytes, level 0 %d", sizes[gzip.BestCompression], sizes[gzip.NoCompression])
+	}
+}
+
+func TestGzipLevelValidation(t *testing.T) {
+	for _, level := range []int{-3, 10} {
+		config := testOptions(t, t.TempDir(), "*")
+		config.Gzip = true
+		config.GzipLevel = level
+		if err := config.Validate(); err == nil {
+			t.Errorf("-gzip-level %d accepted", level)
+		}
+	}
+}
+
+func TestDecompressInputPassesPlainData(t *testing.T) {
+	data := []byte("plain text\n")
+	if got, err := decompressInput(data); err != nil || string(got) != string(data) {
+		t.Errorf("decompressInput = %q, %v", got, err)
+	}
+}
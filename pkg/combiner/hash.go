@@ -0,0 +1,113 @@
+package combiner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Domain prefixes of the tree hash's leaves and internal nodes
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// treeHash computes a Merkle root over files. Each leaf hashes a file's
+// root-relative path and content; leaves are sorted by path and paired up
+// level by level (an odd node is carried up unchanged), so the root only
+// depends on the set of paths and their contents.
+//
+// Leaves and internal nodes are hashed in separate domains, as in RFC 6962:
+//
+//	leaf = SHA-256(0x00 || path || 0x00 || hex SHA-256 of content)
+//	node = SHA-256(0x01 || left || right)
+//
+// so no internal node can be passed off as a leaf. An empty set hashes to
+// SHA-256 of nothing.
+func treeHash(root string, files []string) (string, error) {
+	type leaf struct {
+		path string
+		hash []byte
+	}
+	leaves := make([]leaf, 0, len(files))
+	for _, file := range files {
+		relPath, _ := filepath.Rel(root, file)
+		relPath = filepath.ToSlash(relPath)
+		content, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(append([]byte{leafPrefix}, relPath+"\x00"+content...))
+		leaves = append(leaves, leaf{relPath, sum[:]})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].path < leaves[j].path })
+
+	level := make([][]byte, len(leaves))
+	for i, l := range leaves {
+		level[i] = l.hash
+	}
+	if len(level) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	for len(level) > 1 {
+		var next [][]byte
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			sum := sha256.Sum256(append(append([]byte{nodePrefix}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0]), nil
+}
+
+// dedupFiles keeps the first of several files with byte-identical content,
+// in output order, and reports the later copies as skipped. The hash covers
+// the raw bytes on disk, before any transformation of the content.
+func dedupFiles(config *Options, files []string) ([]string, []FileInfo) {
+	if !config.Dedup {
+		return files, nil
+	}
+
+	firstByHash := make(map[string]string)
+	var kept []string
+	var skipped []FileInfo
+	for _, file := range files {
+		hash, err := hashFile(file)
+		if err != nil {
+			// Reported by the writer like any other unreadable file
+			kept = append(kept, file)
+			continue
+		}
+		if first, seen := firstByHash[hash]; seen {
+			relPath, _ := filepath.Rel(config.Root, first)
+			skipped = append(skipped, FileInfo{file, "Duplicate of " + filepath.ToSlash(relPath)})
+			continue
+		}
+		firstByHash[hash] = file
+		kept = append(kept, file)
+	}
+	return kept, skipped
+}
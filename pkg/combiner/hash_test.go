@@ -0,0 +1,148 @@
+package combiner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTreeHash(t *testing.T) {
+	files := map[string]string{"a.go": "package a\n", "sub/b.go": "package b\n", "c.txt": "c\n"}
+	root := writeTree(t, files)
+	paths := treeFiles(root, files)
+	base, err := treeHash(root, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reversed := []string{paths[2], paths[1], paths[0]}
+	if again, _ := treeHash(root, reversed); again != base {
+		t.Errorf("hash depends on the order of the files: %s != %s", again, base)
+	}
+	copyRoot := writeTree(t, files)
+	if copied, _ := treeHash(copyRoot, treeFiles(copyRoot, files)); copied != base {
+		t.Errorf("identical trees hash differently: %s != %s", copied, base)
+	}
+
+	tests := []struct {
+		name   string
+		change func(t *testing.T) []string
+	}{
+		{"content changed", func(t *testing.T) []string {
+			if err := os.WriteFile(paths[0], []byte("package a // changed\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return paths
+		}},
+		{"file dropped", func(t *testing.T) []string { return paths[:2] }},
+		{"file renamed", func(t *testing.T) []string {
+			renamed := filepath.Join(root, "d.txt")
+			if err := os.Rename(paths[1], renamed); err != nil {
+				t.Fatal(err)
+			}
+			return []string{paths[0], renamed, paths[2]}
+		}},
+	}
+	for _, tt := range tests {
+		root = writeTree(t, files)
+		paths = treeFiles(root, files)
+		changed, err := treeHash(root, tt.change(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed == base {
+			t.Errorf("%s: hash unchanged", tt.name)
+		}
+	}
+}
+
+func TestDedup(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":        "same\n",
+		"vendor/a.txt": "same\n",
+		"crlf.txt":     "same\r\n",
+		"b.txt":        "other\n",
+	})
+	config := testOptions(t, root, "**/*.txt")
+	config.Dedup = true
+	config.NormalizeNewlines = true // the hash covers the bytes on disk
+	if got, want := selectRel(t, config), []string{"a.txt", "b.txt", "crlf.txt"}; !sameStrings(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, skip := range report.Skipped {
+		if filepath.Base(filepath.Dir(skip.Path)) == "vendor" {
+			found = skip.Reason == "Duplicate of a.txt"
+		}
+	}
+	if !found {
+		t.Errorf("vendor/a.txt not reported as a duplicate of a.txt: %+v", report.Skipped)
+	}
+}
+
+func TestDedupHardlinks(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "linked\n", "c.txt": "linked\n"})
+	if err := os.Link(filepath.Join(root, "a.txt"), filepath.Join(root, "b.txt")); err != nil {
+		t.Skipf("no hardlinks here: %v", err)
+	}
+	tests := []struct {
+		dedup bool
+		want  []string
+	}{
+		{false, []string{"a.txt", "b.txt", "c.txt"}},
+		{true, []string{"a.txt", "c.txt"}}, // c.txt has the same content but is another file
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*.txt")
+		config.DedupHardlinks = tt.dedup
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("DedupHardlinks=%v: got %q, want %q", tt.dedup, got, tt.want)
+		}
+	}
+}
+
+func TestTreeHashDomains(t *testing.T) {
+	files := map[string]string{"a.go": "package a\n", "b.go": "package b\n", "c.go": "package c\n"}
+	root := writeTree(t, files)
+	leaf := func(path string) []byte {
+		content := sha256.Sum256([]byte(files[path]))
+		sum := sha256.Sum256([]byte("\x00" + path + "\x00" + hex.EncodeToString(content[:])))
+		return sum[:]
+	}
+	node := func(left, right []byte) []byte {
+		sum := sha256.Sum256(append(append([]byte{0x01}, left...), right...))
+		return sum[:]
+	}
+	tests := []struct {
+		name  string
+		paths []string
+		want  []byte
+	}{
+		{"single leaf", []string{"a.go"}, leaf("a.go")},
+		{"two leaves", []string{"a.go", "b.go"}, node(leaf("a.go"), leaf("b.go"))},
+		{"odd leaf carried up", []string{"a.go", "b.go", "c.go"}, node(node(leaf("a.go"), leaf("b.go")), leaf("c.go"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, path := range tt.paths {
+				paths = append(paths, filepath.Join(root, path))
+			}
+			got, err := treeHash(root, paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := hex.EncodeToString(tt.want); got != want {
+				t.Errorf("treeHash = %s, want %s", got, want)
+			}
+		})
+	}
+}
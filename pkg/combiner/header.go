@@ -0,0 +1,169 @@
+package combiner
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// documentTitle returns the -title value, defaulting to the root directory's name
+func documentTitle(config *Options) string {
+	if config.Title != "" {
+		return config.Title
+	}
+	return documentRootName(config)
+}
+
+// documentRootName is the name of the root directory
+func documentRootName(config *Options) string {
+	absRoot, err := filepath.Abs(config.Root)
+	if err != nil {
+		return config.Root
+	}
+	return filepath.Base(absRoot)
+}
+
+// outputCommentStyle is the comment style of the header and banners: the
+// format's own for markdown and xml, else the one the -o extension implies
+func outputCommentStyle(config *Options) CommentStyle {
+	switch config.Format {
+	case FORMAT_MARKDOWN:
+		return commentStyles[".md"]
+	case FORMAT_XML:
+		return commentStyles[".xml"]
+	}
+	return getCommentStyle(config.Output)
+}
+
+// createBanner renders lines as a comment block in the given style, framed by rules
+func createBanner(lines []string, style CommentStyle) string {
+	rule := strings.Repeat("=", 70)
+	var sb strings.Builder
+
+	if style.BlockStart != "" && style.BlockEnd != "" {
+		sb.WriteString(style.BlockStart + "\n")
+		sb.WriteString(" " + rule + "\n")
+		for _, line := range lines {
+			sb.WriteString(" " + line + "\n")
+		}
+		sb.WriteString(" " + rule + "\n")
+		sb.WriteString(style.BlockEnd + "\n")
+	} else {
+		prefix := style.SingleLine
+		if prefix == "" {
+			prefix = "#"
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", prefix, rule))
+		for _, line := range lines {
+			sb.WriteString(fmt.Sprintf("%s %s\n", prefix, line))
+		}
+		sb.WriteString(fmt.Sprintf("%s %s\n", prefix, rule))
+	}
+
+	return sb.String()
+}
+
+// createDocumentHeader renders the block written once at the top of the
+// combined output (the -header text, then the banner), or "" when no header
+// content was requested
+func createDocumentHeader(config *Options, files []string) string {
+	prologue := injectedText(config, "header", config.Header, files)
+	if config.Format == FORMAT_XML {
+		return prologue + renderDocumentHeader(config, files, nil, tocShift{}) + xmlPreamble(config, files)
+	}
+	if !config.TOC {
+		return prologue + renderDocumentHeader(config, files, nil, tocShift{})
+	}
+
+	// The manifest has one line per file, so shifting its line numbers by
+	// the header's own height doesn't change that height. Its byte offsets
+	// can make the header longer; that only ever grows the offsets, so
+	// re-rendering until the length holds settles in a few rounds.
+	entries := buildTOC(config, files)
+	enc, _ := lookupEncoding(config.Encoding)
+	bom := int64(enc.writeBOM(io.Discard, config.BOM))
+	header := prologue + renderDocumentHeader(config, files, entries, tocShift{})
+	shift := tocShift{Lines: countLines([]byte(header))}
+	for {
+		header = prologue + renderDocumentHeader(config, files, entries, shift)
+		if length := bom + int64(len(header)); length != shift.Bytes {
+			shift.Bytes = length
+			continue
+		}
+		return header
+	}
+}
+
+// createDocumentFooter renders the -footer text written after the last file
+func createDocumentFooter(config *Options, files []string) string {
+	if config.Format == FORMAT_XML {
+		return "\n</files>\n" + injectedText(config, "footer", config.Footer, files)
+	}
+	return injectedText(config, "footer", config.Footer, files)
+}
+
+// injectedText prepares -header/-footer text for the output: its template
+// is executed, its line endings are converted to the -newline style and it
+// always ends with one
+func injectedText(config *Options, name, text string, files []string) string {
+	if text == "" {
+		return ""
+	}
+	text = renderDocumentTemplate(config, name, text, files)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return strings.ReplaceAll(text, "\n", getNewline(config.newlineType()))
+}
+
+// renderDocumentHeader builds the header from the title, the order note, the
+// embedded file manifest and the -toc table of contents
+func renderDocumentHeader(config *Options, files []string, entries []tocEntry, shift tocShift) string {
+	var lines []string
+	title := ""
+	if config.Format == FORMAT_MARKDOWN {
+		// A Markdown document always gets a heading, the root's name by default
+		title = markdownTitle(config)
+	} else if config.Title != "" {
+		lines = append(lines, documentTitle(config))
+	}
+	if config.part != "" {
+		lines = append(lines, "Part "+config.part)
+	}
+	if config.OrderNote {
+		lines = append(lines, "Order: "+orderDescription(config))
+	}
+	if config.run != nil && config.run.treeHash != "" {
+		lines = append(lines, "Tree hash: sha256:"+config.run.treeHash)
+	}
+	if config.EmbedManifest {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, manifestLines(config, files)...)
+	}
+	if config.Tree && config.Format != FORMAT_XML {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, treeLines(config, files)...)
+	}
+	if config.TOC {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, tocLines(config, entries, shift)...)
+	}
+	if len(lines) == 0 {
+		return title
+	}
+
+	header := title + createBanner(lines, outputCommentStyle(config))
+	if config.NoSeparator || config.NoLeadingSeparator {
+		header += "\n"
+	}
+	return header
+}
@@ -0,0 +1,169 @@
+package combiner
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestHeaderFooter(t *testing.T) {
+	files := map[string]string{"a.txt": "a\n", "b.txt": "b\n"}
+	tests := []struct {
+		name   string
+		header string
+		footer string
+		setup  func(*Options)
+		prefix string
+		suffix string
+	}{
+		{"verbatim", "Project: foo\ngenerated by CI\n", "-- end --\n", nil,
+			"Project: foo\ngenerated by CI\n\n# ====", "\nb\n-- end --\n"},
+		{"trailing newline added", "top", "bottom", nil,
+			"top\n\n# ====", "\nb\nbottom\n"},
+		{"crlf output", "one\ntwo", "end\r\n", func(config *Options) { config.NewlineType = "crlf" },
+			"one\r\ntwo\r\n", "end\r\n"},
+		{"lone cr", "one\rtwo", "", nil, "one\ntwo\n", "\nb\n"},
+		{"before the title", "intro", "", func(config *Options) { config.Title = "T" },
+			"intro\n# ====", "\nb\n"},
+		{"markdown", "intro", "outro", func(config *Options) { config.Format = FORMAT_MARKDOWN },
+			"intro\n# ", "```\noutro\n"},
+		{"jobs", "top", "bottom", func(config *Options) { config.Jobs = 2 },
+			"top\n\n# ====", "\nb\nbottom\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, writeTree(t, files), "*.txt")
+			config.Header = tt.header
+			config.Footer = tt.footer
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+			if !strings.HasPrefix(out, tt.prefix) {
+				t.Errorf("output starts %q, want %q", out[:min(len(out), len(tt.prefix)+10)], tt.prefix)
+			}
+			if !strings.HasSuffix(out, tt.suffix) {
+				t.Errorf("output ends %q, want %q", out[max(0, len(out)-len(tt.suffix)-10):], tt.suffix)
+			}
+		})
+	}
+}
+
+func TestInjectedText(t *testing.T) {
+	tests := []struct {
+		text    string
+		newline string
+		want    string
+	}{
+		{"", "lf", ""},
+		{"x", "lf", "x\n"},
+		{"x\n", "lf", "x\n"},
+		{"x\r\ny\r\n", "lf", "x\ny\n"},
+		{"x\ny", "crlf", "x\r\ny\r\n"},
+		{"x\r\n", "cr", "x\r"},
+	}
+	for _, tt := range tests {
+		config := DefaultOptions()
+		config.NewlineType = tt.newline
+		if got := injectedText(config, "header", tt.text, nil); got != tt.want {
+			t.Errorf("injectedText(%q, %s) = %q, want %q", tt.text, tt.newline, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentTemplates(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "aaaa\n", "sub/b.txt": "bb\n"})
+	tests := []struct {
+		name   string
+		header string
+		footer string
+		want   []string
+	}{
+		{"counts", "{{.FileCount}} files, {{.Size}} bytes", "", []string{"2 files, 8 bytes\n"}},
+		{"file list", "{{range .Files}}- {{.}}\n{{end}}", "", []string{"- a.txt\n- sub/b.txt\n"}},
+		{"title and version", "{{.Title}} {{.Version}}", "", []string{"Bundle v1.2.3\n"}},
+		{"size function", "", "total {{size .Size}}", []string{"total 8 B\n"}},
+		{"tokens", "", "~{{.Tokens}} tokens", []string{" tokens\n"}},
+		{"not a template", "literal {braces}", "", []string{"literal {braces}\n"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.txt")
+			config.Title = "Bundle"
+			config.Version = "v1.2.3"
+			config.Header = tt.header
+			config.Footer = tt.footer
+			out := combine(t, config)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output missing %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+
+	config := testOptions(t, root, "**/*.txt")
+	config.Footer = "~{{.Tokens}} tokens"
+	out := combine(t, config)
+	config.Footer = ""
+	body := measure(config, []byte(combine(t, config))).tokens(config)
+	if !strings.HasSuffix(out, "~"+strconv.FormatInt(body, 10)+" tokens\n") {
+		t.Errorf("footer does not count the %d tokens of the sections:\n%s", body, out)
+	}
+
+	for _, bad := range []string{"{{.Bogus}}", "{{.FileCount", "{{nosuchfunc .Size}}"} {
+		config := testOptions(t, root, "**/*.txt")
+		config.Header = bad
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "invalid --header template") {
+			t.Errorf("header %q: err = %v, want invalid --header template", bad, err)
+		}
+	}
+}
+
+func TestTitleOnce(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n", "b.txt": "My Project is mentioned here\n"})
+	rule := strings.Repeat("=", 70)
+	tests := []struct {
+		name   string
+		format string
+		setup  func(*Options)
+		prefix string
+	}{
+		{"text", FORMAT_TEXT, nil, "# " + rule + "\n# My Project\n# " + rule + "\n"},
+		{"markdown", FORMAT_MARKDOWN, nil, "# My Project\n\n### a.go\n"},
+		{"xml", FORMAT_XML, nil, "<!--\n " + rule + "\n My Project\n " + rule + "\n-->\n<files>\n"},
+		{"json", FORMAT_JSON, nil, "{\n  \"tool\": {"},
+		{"text with jobs", FORMAT_TEXT, func(config *Options) { config.Jobs = 2 }, "# " + rule + "\n# My Project\n# " + rule + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "a.go", "b.txt")
+			config.Format = tt.format
+			config.Title = "My Project"
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+			if !strings.HasPrefix(out, tt.prefix) {
+				t.Errorf("output starts %q, want %q", out[:min(len(out), len(tt.prefix))], tt.prefix)
+			}
+			// Once as the title, once in b.txt
+			if n := strings.Count(out, "My Project"); n != 2 {
+				t.Errorf("title appears %d times, want once:\n%s", n-1, out)
+			}
+		})
+	}
+
+	// Appending to the output leaves the single title at the top
+	config := testOptions(t, root, "a.go")
+	config.Title = "My Project"
+	combine(t, config)
+	second := testOptions(t, root, "b.txt")
+	second.Output = config.Output
+	second.Title = "My Project"
+	second.Append = true
+	out := combine(t, second)
+	if !strings.HasPrefix(out, "# "+rule+"\n# My Project\n") || strings.Count(out, "My Project") != 2 {
+		t.Errorf("appended output:\n%s", out)
+	}
+}
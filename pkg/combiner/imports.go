@@ -0,0 +1,105 @@
+package combiner
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// extractImports keeps only the import/require declarations of Go,
+// JavaScript/TypeScript and Python sources. Files in other languages
+// produce no content.
+func extractImports(path string, content []byte) []byte {
+	var lines []string
+	switch detectLanguage(path) {
+	case "go":
+		lines = goImports(content)
+	case "javascript", "typescript":
+		lines = jsImports(content)
+	case "python":
+		lines = pythonImports(content)
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+func scanLines(content []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, BUFFER_SIZE), MAX_FILE_SIZE)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	return lines
+}
+
+func goImports(content []byte) []string {
+	var out []string
+	inBlock := false
+	for _, line := range scanLines(content) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inBlock:
+			out = append(out, line)
+			if trimmed == ")" {
+				inBlock = false
+			}
+		case strings.HasPrefix(trimmed, "import ("):
+			out = append(out, line)
+			inBlock = !strings.HasSuffix(trimmed, ")")
+		case strings.HasPrefix(trimmed, "import "):
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func jsImports(content []byte) []string {
+	var out []string
+	inStatement := false
+	for _, line := range scanLines(content) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inStatement:
+			out = append(out, line)
+			if strings.Contains(trimmed, "from ") {
+				inStatement = false
+			}
+		case strings.HasPrefix(trimmed, "import ") || strings.HasPrefix(trimmed, "import{"):
+			out = append(out, line)
+			// Multi-line named imports: import {\n a,\n b\n} from "x"
+			if strings.Contains(trimmed, "{") && !strings.Contains(trimmed, "}") {
+				inStatement = true
+			}
+		case strings.HasPrefix(trimmed, "export ") && strings.Contains(trimmed, " from "):
+			out = append(out, line)
+		case strings.Contains(trimmed, "require("):
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func pythonImports(content []byte) []string {
+	var out []string
+	inParens := false
+	for _, line := range scanLines(content) {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inParens:
+			out = append(out, line)
+			if strings.Contains(trimmed, ")") {
+				inParens = false
+			}
+		case strings.HasPrefix(trimmed, "import "):
+			out = append(out, line)
+		case strings.HasPrefix(trimmed, "from ") && strings.Contains(trimmed, " import "):
+			out = append(out, line)
+			inParens = strings.Contains(trimmed, "(") && !strings.Contains(trimmed, ")")
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package combiner
+
+import "testing"
+
+func TestExtractImports(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		want    string
+	}{
+		{"main.go", "package main\n\nimport \"fmt\"\n\nfunc main() {}\n", "import \"fmt\"\n"},
+		{"main.go", "package main\n\nimport (\n\t\"fmt\"\n\t\"os\"\n)\n\nvar x = 1\n", "import (\n\t\"fmt\"\n\t\"os\"\n)\n"},
+		{"app.ts", "import { a,\n  b } from \"./ab\";\nconst c = require('c');\nexport { d } from \"./d\";\nlet e = 1;\n",
+			"import { a,\n  b } from \"./ab\";\nconst c = require('c');\nexport { d } from \"./d\";\n"},
+		{"tool.py", "import os\nfrom x import (\n    a,\n    b,\n)\nfrom y import z\n\nprint(os)\n",
+			"import os\nfrom x import (\n    a,\n    b,\n)\nfrom y import z\n"},
+		{"win.py", "import os\r\nx = 1\r\n", "import os\n"},
+		{"notes.md", "import this\n", ""},
+		{"empty.go", "package empty\n", ""},
+	}
+	for _, tt := range tests {
+		if got := string(extractImports(tt.path, []byte(tt.content))); got != tt.want {
+			t.Errorf("extractImports(%s, %q) = %q, want %q", tt.path, tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// IndentStyle is the indentation a file uses: tabs, or spaces of a given width
+type IndentStyle struct {
+	Tabs  bool
+	Width int // spaces per level; unused for tabs
+}
+
+func (s IndentStyle) String() string {
+	if s.Tabs {
+		return "tabs"
+	}
+	return fmt.Sprintf("%d spaces", s.Width)
+}
+
+// ParseIndentStyle parses a -target-indent value: "tab" or a number of spaces
+func ParseIndentStyle(value string) (IndentStyle, error) {
+	switch strings.ToLower(value) {
+	case "tab", "tabs":
+		return IndentStyle{Tabs: true}, nil
+	}
+	width, err := strconv.Atoi(value)
+	if err != nil || width < 1 || width > 16 {
+		return IndentStyle{}, fmt.Errorf("invalid --target-indent: %s (use tab or 1-16 spaces)", value)
+	}
+	return IndentStyle{Width: width}, nil
+}
+
+// detectIndent infers a file's dominant indentation. Tab- and space-indented
+// lines are counted; for spaces the width is the most common step between
+// the indentation of consecutive lines. ok is false when there is no clear
+// indentation to go by.
+func detectIndent(content []byte) (style IndentStyle, ok bool) {
+	tabLines, spaceLines := 0, 0
+	steps := make(map[int]int)
+	previous := 0
+
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		switch line[0] {
+		case '\t':
+			tabLines++
+		case ' ':
+			spaceLines++
+		}
+
+		spaces := len(line) - len(bytes.TrimLeft(line, " "))
+		if spaces > 0 && spaces < len(line) && line[spaces] == '\t' {
+			spaces = 0 // spaces followed by tabs are alignment, not a level
+		}
+		if step := spaces - previous; step > 0 {
+			steps[step]++
+		}
+		previous = spaces
+	}
+
+	if tabLines == 0 && spaceLines == 0 {
+		return style, false
+	}
+	if tabLines > spaceLines {
+		return IndentStyle{Tabs: true}, true
+	}
+
+	best := 0
+	for step, count := range steps {
+		if step <= 8 && (count > steps[best] || count == steps[best] && step < best) {
+			best = step
+		}
+	}
+	if best == 0 {
+		return style, false
+	}
+	return IndentStyle{Width: best}, true
+}
+
+// reindent rewrites the leading whitespace of every line from one indentation
+// style to another. Columns that don't make up a whole level are kept as
+// spaces, so alignment inside a level survives.
+func reindent(content []byte, from, to IndentStyle) []byte {
+	if from == to {
+		return content
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	for i, line := range lines {
+		lead := len(line) - len(bytes.TrimLeft(line, " \t"))
+		if lead == 0 || lead == len(bytes.TrimRight(line, "\r")) {
+			continue
+		}
+
+		levels, rest := 0, 0
+		if from.Tabs {
+			tabs := len(line[:lead]) - len(bytes.TrimLeft(line[:lead], "\t"))
+			levels, rest = tabs, lead-tabs
+		} else {
+			columns := 0
+			for _, c := range line[:lead] {
+				if c == '\t' {
+					columns += from.Width
+				} else {
+					columns++
+				}
+			}
+			levels, rest = columns/from.Width, columns%from.Width
+		}
+
+		var indent string
+		if to.Tabs {
+			indent = strings.Repeat("\t", levels)
+		} else {
+			indent = strings.Repeat(" ", levels*to.Width)
+		}
+		indent += strings.Repeat(" ", rest)
+		lines[i] = append([]byte(indent), line[lead:]...)
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,65 @@
+package combiner
+
+import "testing"
+
+func TestDetectIndent(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    IndentStyle
+		ok      bool
+	}{
+		{"tabs", "func f() {\n\tif x {\n\t\ty()\n\t}\n}\n", IndentStyle{Tabs: true}, true},
+		{"two spaces", "a:\n  b:\n    c: 1\n  d: 2\n", IndentStyle{Width: 2}, true},
+		{"four spaces", "def f():\n    if x:\n        y()\n    return\n", IndentStyle{Width: 4}, true},
+		{"flat", "a\nb\nc\n", IndentStyle{}, false},
+		{"empty", "", IndentStyle{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := detectIndent([]byte(tt.content))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: detectIndent = %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReindent(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		from, to IndentStyle
+		want     string
+	}{
+		{"tabs to spaces", "a\n\tb\n\t\tc\n", IndentStyle{Tabs: true}, IndentStyle{Width: 4}, "a\n    b\n        c\n"},
+		{"spaces to tabs", "a\n  b\n    c\n", IndentStyle{Width: 2}, IndentStyle{Tabs: true}, "a\n\tb\n\t\tc\n"},
+		{"alignment kept", "a\n     b\n", IndentStyle{Width: 4}, IndentStyle{Width: 2}, "a\n   b\n"},
+		{"blank lines untouched", "a\n    \n    b\r\n", IndentStyle{Width: 4}, IndentStyle{Tabs: true}, "a\n    \n\tb\r\n"},
+		{"same style", "\tx\n", IndentStyle{Tabs: true}, IndentStyle{Tabs: true}, "\tx\n"},
+	}
+	for _, tt := range tests {
+		if got := string(reindent([]byte(tt.content), tt.from, tt.to)); got != tt.want {
+			t.Errorf("%s: reindent = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseIndentStyle(t *testing.T) {
+	tests := []struct {
+		value string
+		want  IndentStyle
+		err   bool
+	}{
+		{"tab", IndentStyle{Tabs: true}, false},
+		{"TABS", IndentStyle{Tabs: true}, false},
+		{"2", IndentStyle{Width: 2}, false},
+		{"0", IndentStyle{}, true},
+		{"17", IndentStyle{}, true},
+		{"wide", IndentStyle{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseIndentStyle(tt.value)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseIndentStyle(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package combiner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// jsonTool identifies the program that wrote a -format json document
+type jsonTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// jsonDocument is the metadata written ahead of the files in -format json
+type jsonDocument struct {
+	Tool      jsonTool `json:"tool"`
+	Generated string   `json:"generated,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Root      string   `json:"root"`
+}
+
+// jsonFile is one file in -format json output. Encoding is "base64" for
+// binary files and content that is not valid UTF-8.
+type jsonFile struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size"`
+	ModTime  string `json:"mtime,omitempty"`
+	Language string `json:"language,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Content  string `json:"content"`
+}
+
+// writeJSONDocument writes the files as a single JSON document: the tool
+// metadata, then a "files" array with one record per file. Records are
+// encoded one at a time, so the whole corpus is never held in memory.
+func writeJSONDocument(w io.Writer, config *Options, files []string) (int, int) {
+	document := jsonDocument{
+		Tool:  jsonTool{Name: "combine", Version: config.Version},
+		Title: config.Title,
+		Root:  filepath.ToSlash(config.Root),
+	}
+	if !config.NoTimestamp {
+		document.Generated = config.combineTime().Format(time.RFC3339)
+	}
+	head, _ := json.MarshalIndent(document, "", "  ")
+	io.WriteString(w, strings.TrimSuffix(string(head), "\n}")+",\n  \"files\": [")
+
+	successCount := 0
+	errorCount := 0
+	for idx, filePath := range files {
+		if config.canceled() {
+			break
+		}
+		if config.Verbose {
+			fmt.Printf("Processing [%d/%d]: %s\n", idx+1, len(files), filepath.Base(filePath))
+		}
+		record, err := jsonRecord(config, filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
+			errorCount++
+			continue
+		}
+		// Code is full of < > &; keep them readable instead of \u003c
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("    ", "  ")
+		encoder.Encode(record)
+		if successCount > 0 {
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "\n    ")
+		w.Write(bytes.TrimSuffix(data.Bytes(), []byte("\n")))
+		successCount++
+	}
+
+	if successCount > 0 {
+		io.WriteString(w, "\n  ")
+	}
+	io.WriteString(w, "]\n}\n")
+	return successCount, errorCount
+}
+
+// jsonRecord reads a file, applying the content transformations, into its
+// -format json record
+func jsonRecord(config *Options, filePath string) (jsonFile, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return jsonFile{}, err
+	}
+	relPath, _ := filepath.Rel(config.Root, filePath)
+	record := jsonFile{
+		Path:     collapsePath(filepath.ToSlash(relPath), config.CollapseDepth),
+		Size:     info.Size(),
+		Language: detectLanguage(filePath),
+	}
+	if mtime, ok := fileModTime(config, info); ok {
+		record.ModTime = mtime.UTC().Format(time.RFC3339)
+	}
+
+	var content []byte
+	if config.isBinary(filePath) {
+		content, err = os.ReadFile(filePath)
+	} else {
+		content, err = loadContent(config, filePath)
+	}
+	if err != nil {
+		return jsonFile{}, err
+	}
+	if config.isBinary(filePath) || !utf8.Valid(content) {
+		record.Encoding = "base64"
+		record.Content = base64.StdEncoding.EncodeToString(content)
+	} else {
+		record.Content = string(content)
+	}
+	return record, nil
+}
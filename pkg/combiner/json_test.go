@@ -0,0 +1,126 @@
+package combiner
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jsonOutput is a -format json document as a reader decodes it
+type jsonOutput struct {
+	jsonDocument
+	Files []jsonFile `json:"files"`
+}
+
+func TestJSONOutput(t *testing.T) {
+	files := map[string]string{
+		"a.go":      "package a\n\nfunc A() { if x < y && z > 0 {} }\n",
+		"docs/b.md": "# b\n",
+		"c.txt":     "no newline",
+		"d.dat":     "\xff\xfe\x00\x01",
+	}
+	mtime := time.Date(2020, 5, 17, 8, 30, 0, 0, time.UTC)
+	root := writeTree(t, files)
+	for name := range files {
+		if err := os.Chtimes(filepath.Join(root, filepath.FromSlash(name)), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	tests := []struct {
+		name        string
+		patterns    []string
+		noTimestamp bool
+		binary      bool
+		wantPaths   []string
+	}{
+		{"text files", []string{"*.go", "**/*.md", "*.txt"}, false, false, []string{"a.go", "c.txt", "docs/b.md"}},
+		{"no timestamps", []string{"*.go"}, true, false, []string{"a.go"}},
+		{"binary as base64", []string{"*.dat"}, false, true, []string{"d.dat"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, tt.patterns...)
+			config.Format = FORMAT_JSON
+			config.Title = "Corpus"
+			config.Version = "1.2.3"
+			config.NoTimestamp = tt.noTimestamp
+			config.IncludeBinary = tt.binary
+			out := combine(t, config)
+			if strings.Contains(out, `\u003c`) {
+				t.Errorf("HTML-escaped content in %s", out)
+			}
+
+			var document jsonOutput
+			if err := json.Unmarshal([]byte(out), &document); err != nil {
+				t.Fatalf("invalid JSON: %v\n%s", err, out)
+			}
+			if document.Tool != (jsonTool{Name: "combine", Version: "1.2.3"}) || document.Title != "Corpus" {
+				t.Errorf("metadata = %+v", document.jsonDocument)
+			}
+			wantGenerated := "2023-11-14T22:13:20Z"
+			if tt.noTimestamp {
+				wantGenerated = ""
+			}
+			if document.Generated != wantGenerated {
+				t.Errorf("generated = %q, want %q", document.Generated, wantGenerated)
+			}
+
+			var paths []string
+			for _, record := range document.Files {
+				paths = append(paths, record.Path)
+				content := files[record.Path]
+				if record.Size != int64(len(content)) {
+					t.Errorf("%s: size %d, want %d", record.Path, record.Size, len(content))
+				}
+				if tt.noTimestamp != (record.ModTime == "") || !tt.noTimestamp && record.ModTime != "2020-05-17T08:30:00Z" {
+					t.Errorf("%s: mtime %q", record.Path, record.ModTime)
+				}
+				if record.Language != detectLanguage(record.Path) {
+					t.Errorf("%s: language %q", record.Path, record.Language)
+				}
+				got := record.Content
+				if record.Encoding == "base64" {
+					data, err := base64.StdEncoding.DecodeString(got)
+					if err != nil {
+						t.Fatalf("%s: %v", record.Path, err)
+					}
+					got = string(data)
+				} else if record.Encoding != "" {
+					t.Errorf("%s: encoding %q", record.Path, record.Encoding)
+				}
+				if tt.binary != (record.Encoding == "base64") {
+					t.Errorf("%s: encoding %q", record.Path, record.Encoding)
+				}
+				if got != content {
+					t.Errorf("%s: content %q, want %q", record.Path, got, content)
+				}
+			}
+			if !sameStrings(paths, tt.wantPaths) {
+				t.Errorf("paths = %q, want %q", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestJSONDocumentWithoutFiles(t *testing.T) {
+	config := testOptions(t, t.TempDir())
+	config.Title = "Empty"
+	var out bytes.Buffer
+	if success, errors := writeJSONDocument(&out, config, nil); success != 0 || errors != 0 {
+		t.Errorf("wrote %d files with %d errors", success, errors)
+	}
+	var document jsonOutput
+	if err := json.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	if document.Files == nil || len(document.Files) != 0 || document.Title != "Empty" {
+		t.Errorf("document = %s", out.String())
+	}
+}
@@ -0,0 +1,90 @@
+package combiner
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Languages by file extension
+var languageByExt = map[string]string{
+	".go": "go", ".py": "python", ".rb": "ruby", ".js": "javascript", ".jsx": "javascript",
+	".mjs": "javascript", ".cjs": "javascript", ".ts": "typescript", ".tsx": "typescript",
+	".java": "java", ".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".hpp": "cpp",
+	".cs": "csharp", ".swift": "swift", ".kt": "kotlin", ".scala": "scala", ".rs": "rust",
+	".dart": "dart", ".php": "php", ".lua": "lua", ".pl": "perl", ".pm": "perl", ".r": "r",
+	".sh": "shell", ".bash": "shell", ".zsh": "shell", ".ps1": "powershell",
+	".bat": "batch", ".cmd": "batch", ".vb": "vb", ".fs": "fsharp", ".m": "matlab",
+	".lisp": "lisp", ".clj": "clojure", ".scm": "scheme", ".erl": "erlang",
+	".ex": "elixir", ".exs": "elixir", ".sql": "sql",
+	".html": "html", ".htm": "html", ".xml": "xml", ".svg": "xml", ".css": "css",
+	".scss": "scss", ".sass": "sass", ".less": "less", ".vue": "vue", ".svelte": "svelte",
+	".astro": "astro", ".json": "json", ".yaml": "yaml", ".yml": "yaml", ".toml": "toml",
+	".ini": "ini", ".cfg": "ini", ".conf": "ini", ".csv": "csv", ".env": "dotenv",
+	".md": "markdown", ".rst": "rst", ".adoc": "asciidoc", ".textile": "textile",
+	".org": "org", ".tex": "latex", ".txt": "text", ".dockerfile": "dockerfile",
+}
+
+// Languages by exact file name, for files without a meaningful extension
+var languageByName = map[string]string{
+	"Dockerfile": "dockerfile", "Makefile": "makefile", "GNUmakefile": "makefile",
+	"CMakeLists.txt": "cmake", "Gemfile": "ruby", "Rakefile": "ruby", "Jenkinsfile": "groovy",
+	"go.mod": "gomod", "go.sum": "gosum",
+}
+
+// Languages by shebang interpreter
+var languageByInterpreter = map[string]string{
+	"sh": "shell", "bash": "shell", "zsh": "shell", "python": "python", "python3": "python",
+	"node": "javascript", "ruby": "ruby", "perl": "perl", "php": "php", "lua": "lua",
+}
+
+// Short names accepted by -language / -exclude-language
+var languageAliases = map[string]string{
+	"js": "javascript", "ts": "typescript", "py": "python", "rb": "ruby", "rs": "rust",
+	"sh": "shell", "bash": "shell", "yml": "yaml", "md": "markdown", "c++": "cpp",
+	"cs": "csharp", "kt": "kotlin", "txt": "text",
+}
+
+// NormalizeLanguage maps a user supplied language name to its canonical form
+func NormalizeLanguage(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := languageAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// detectLanguage classifies a file by name, extension and finally by its
+// shebang line. It returns "" when the language is unknown.
+func detectLanguage(path string) string {
+	base := filepath.Base(path)
+	if lang, ok := languageByName[base]; ok {
+		return lang
+	}
+	if lang, ok := languageByExt[strings.ToLower(filepath.Ext(base))]; ok {
+		return lang
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	line, _ := reader.ReadString('\n')
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	return languageByInterpreter[interpreter]
+}
@@ -0,0 +1,124 @@
+package combiner
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	files := map[string]string{
+		"main.go":        "package main\n",
+		"App.TSX":        "export {}\n",
+		"Dockerfile":     "FROM scratch\n",
+		"CMakeLists.txt": "project(x)\n",
+		"deploy":         "#!/usr/bin/env bash\necho\n",
+		"tool":           "#!/usr/bin/python3\nprint()\n",
+		"notes":          "just text\n",
+	}
+	root := writeTree(t, files)
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"main.go", "go"},
+		{"App.TSX", "typescript"},
+		{"Dockerfile", "dockerfile"},
+		{"CMakeLists.txt", "cmake"},
+		{"deploy", "shell"},
+		{"tool", "python"},
+		{"notes", ""},
+	}
+	for _, tt := range tests {
+		if got := detectLanguage(filepath.Join(root, tt.name)); got != tt.want {
+			t.Errorf("detectLanguage(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := map[string]string{"JS": "javascript", " py ": "python", "c++": "cpp", "go": "go", "Rust": "rust"}
+	for name, want := range tests {
+		if got := NormalizeLanguage(name); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestLanguageFilters(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":    "package a\n",
+		"b.py":    "print()\n",
+		"c.md":    "# c\n",
+		"run":     "#!/bin/sh\necho\n",
+		"data.js": "export {}\n",
+	})
+	tests := []struct {
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{nil, []string{"markdown"}, []string{"a.go", "b.py", "data.js", "run"}},
+		{nil, []string{"shell", "javascript"}, []string{"a.go", "b.py", "c.md"}},
+		{[]string{"go", "python"}, nil, []string{"a.go", "b.py"}},
+		{[]string{"go", "python"}, []string{"python"}, []string{"a.go"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*")
+		config.Languages = tt.include
+		config.ExcludeLanguages = tt.exclude
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("-language %q -exclude-language %q: got %q, want %q", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestLanguageSkipReasons(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "package a\n",
+		"b.py": "print()\n",
+		"c.md": "# c\n",
+	})
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    map[string]string
+	}{
+		{"excluded", nil, []string{"python", "markdown"}, map[string]string{
+			"b.py": "Excluded language (python)",
+			"c.md": "Excluded language (markdown)",
+		}},
+		{"not selected", []string{"go"}, nil, map[string]string{
+			"b.py": "Language not selected",
+			"c.md": "Language not selected",
+		}},
+		{"selected, then excluded", []string{"go", "python"}, []string{"python"}, map[string]string{
+			"b.py": "Excluded language (python)",
+			"c.md": "Language not selected",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*")
+			config.Languages = tt.include
+			config.ExcludeLanguages = tt.exclude
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			skipped := make(map[string]string)
+			for _, file := range report.Skipped {
+				skipped[relFiles(t, root, []string{file.Path})[0]] = file.Reason
+			}
+			if len(skipped) != len(tt.want) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.want)
+			}
+			for name, reason := range tt.want {
+				if skipped[name] != reason {
+					t.Errorf("%s skipped with %q, want %q", name, skipped[name], reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// numberLines prefixes every line of content with its line number, starting
+// at first, padded to the width of the last line number. Line endings (LF,
+// CRLF or a missing final newline) are kept as they are.
+func numberLines(content []byte, first int) []byte {
+	if len(content) == 0 {
+		return content
+	}
+	total := bytes.Count(content, []byte("\n"))
+	if !bytes.HasSuffix(content, []byte("\n")) {
+		total++
+	}
+	width := len(strconv.Itoa(first + total - 1))
+
+	var out bytes.Buffer
+	out.Grow(len(content) + total*(width+3))
+	for n := first; len(content) > 0; n++ {
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 {
+			end = len(content)
+		}
+		fmt.Fprintf(&out, "%*d | ", width, n)
+		out.Write(content[:end])
+		content = content[end:]
+	}
+	return out.Bytes()
+}
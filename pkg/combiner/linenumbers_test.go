@@ -0,0 +1,64 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNumberLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		first   int
+		want    string
+	}{
+		{"empty", "", 1, ""},
+		{"one line", "a\n", 1, "1 | a\n"},
+		{"no final newline", "a\nb", 1, "1 | a\n2 | b"},
+		{"crlf", "a\r\nb\r\n", 1, "1 | a\r\n2 | b\r\n"},
+		{"blank lines", "\n\n", 1, "1 | \n2 | \n"},
+		{"width grows", strings.Repeat("x\n", 10), 1, " 1 | x\n 2 | x\n 3 | x\n 4 | x\n 5 | x\n 6 | x\n 7 | x\n 8 | x\n 9 | x\n10 | x\n"},
+		{"range start", "a\nb\n", 99, " 99 | a\n100 | b\n"},
+	}
+	for _, tt := range tests {
+		if got := string(numberLines([]byte(tt.content), tt.first)); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLineNumbersOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt": "one\ntwo\n",
+		"b.go":  strings.Repeat("x\n", 12),
+		"c.go":  "package c\n\nfunc C() {}\n\nfunc D() {}\n",
+	})
+	config := testOptions(t, root, "*")
+	config.LineNumbers = true
+	out := combine(t, config)
+
+	for _, want := range []string{
+		"\n1 | one\n2 | two\n",     // numbering restarts at every file
+		"\n 1 | x\n", "\n12 | x\n", // padded to the file's own width
+		"FILE 1: a.txt\n", // separators are not numbered
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.Contains(line, "FILE ") && strings.Contains(line, " | ") {
+			t.Errorf("separator line numbered: %q", line)
+		}
+	}
+
+	// A line range keeps the file's own numbers
+	config = testOptions(t, root, "c.go:3-5")
+	config.LineNumbers = true
+	if err := ExtractLineRanges(config); err != nil {
+		t.Fatal(err)
+	}
+	if out := combine(t, config); !strings.Contains(out, "3 | func C() {}\n4 | \n5 | func D() {}\n") {
+		t.Errorf("line range not numbered from 3:\n%s", out)
+	}
+}
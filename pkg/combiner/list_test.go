@@ -0,0 +1,56 @@
+package combiner
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrintFileList(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":          "package a\n",
+		"sub/b.go":      "package sub\n",
+		"sub/c.txt":     "text\n",
+		"blob.go.dat":   "\x00\x01binary",
+		"vendor/v.go":   "package v\n",
+		"with space.go": "package space\n",
+	})
+	tests := []struct {
+		name      string
+		pathStyle string
+		null      bool
+		want      string
+	}{
+		{"relative", "relative", false, "a.go\n" + filepath.Join("sub", "b.go") + "\nwith space.go\n"},
+		{"null", "relative", true, "a.go\x00" + filepath.Join("sub", "b.go") + "\x00with space.go\x00"},
+		{"absolute", "absolute", false, filepath.Join(root, "a.go") + "\n" + filepath.Join(root, "sub", "b.go") + "\n" + filepath.Join(root, "with space.go") + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.go", "**/*.dat")
+			config.Excludes = []string{"vendor"}
+			config.List = true
+			config.PathStyle = tt.pathStyle
+			config.NullSeparated = tt.null
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			config.Stdout = &out
+			decoration := captureStdout(t, func() {
+				report, err := New(config).Select(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				PrintFileList(config, report.Files)
+			})
+			if decoration != "" {
+				t.Errorf("printed more than the paths:\n%s", decoration)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
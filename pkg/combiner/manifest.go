@@ -0,0 +1,187 @@
+package combiner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// contentRegion locates a file's content inside the combined output
+type contentRegion struct {
+	Offset int64
+	Length int64
+}
+
+// regionLog collects the content regions written by writeSerial and
+// writeSharded. Offsets are recorded relative to the start of the sections
+// and shifted by base, the bytes written before them (BOM and header).
+type regionLog struct {
+	mu      sync.Mutex
+	base    int64
+	regions map[string]contentRegion
+}
+
+func (l *regionLog) reset(base int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.base = base
+	l.regions = make(map[string]contentRegion)
+}
+
+func (l *regionLog) addBase(n int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.base += n
+}
+
+func (l *regionLog) record(file string, offset, length int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.regions != nil {
+		l.regions[file] = contentRegion{l.base + offset, length}
+	}
+}
+
+// manifestFile describes one combined file in the -manifest JSON
+type manifestFile struct {
+	Path         string          `json:"path"`
+	AbsPath      string          `json:"abs_path"`
+	Size         int64           `json:"size"`
+	SHA256       string          `json:"sha256"`
+	Lines        int             `json:"lines"`
+	ModTime      string          `json:"mod_time,omitempty"`
+	CommentStyle manifestComment `json:"comment_style"`
+	Offset       int64           `json:"offset"`
+	Length       int64           `json:"length"`
+}
+
+type manifestComment struct {
+	SingleLine string `json:"single_line,omitempty"`
+	BlockStart string `json:"block_start,omitempty"`
+	BlockEnd   string `json:"block_end,omitempty"`
+}
+
+type manifestSkipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// manifestConfig records the options that shape the output
+type manifestConfig struct {
+	Root            string   `json:"root"`
+	Patterns        []string `json:"patterns"`
+	Excludes        []string `json:"excludes,omitempty"`
+	Recursive       bool     `json:"recursive"`
+	Format          string   `json:"format"`
+	Encoding        string   `json:"encoding"`
+	Newline         string   `json:"newline"`
+	Sort            string   `json:"sort"`
+	Reverse         bool     `json:"reverse"`
+	MaxSize         int64    `json:"max_size"`
+	MinSize         int64    `json:"min_size,omitempty"`
+	SkipEmpty       bool     `json:"skip_empty,omitempty"`
+	MaxFiles        int      `json:"max_files,omitempty"`
+	SeparatorFormat string   `json:"separator_format,omitempty"`
+	NoSeparator     bool     `json:"no_separator,omitempty"`
+	LineNumbers     bool     `json:"line_numbers,omitempty"`
+	ImportsOnly     bool     `json:"imports_only,omitempty"`
+	Gzip            bool     `json:"gzip,omitempty"`
+}
+
+// jsonManifest is the document written by -manifest
+type jsonManifest struct {
+	Version      string            `json:"version"`
+	Generated    string            `json:"generated,omitempty"`
+	Config       manifestConfig    `json:"config"`
+	Output       string            `json:"output"`
+	OutputSize   int64             `json:"output_size,omitempty"`
+	OutputSHA256 string            `json:"output_sha256,omitempty"`
+	TotalFiles   int               `json:"total_files"`
+	Files        []manifestFile    `json:"files"`
+	Skipped      []manifestSkipped `json:"skipped"`
+}
+
+// writeJSONManifest writes the -manifest document for the files that made it
+// into the output. Offset and length are byte positions in the output file,
+// so a single file's content can be read back without parsing separators;
+// the source's sha256 and the output's let CI verify the artifact.
+func writeJSONManifest(config *Options, files []string, skipped []FileInfo) error {
+	manifest := jsonManifest{
+		Version: config.Version,
+		Config: manifestConfig{
+			Root:            filepath.ToSlash(config.Root),
+			Patterns:        config.Patterns,
+			Excludes:        config.Excludes,
+			Recursive:       config.Recursive,
+			Format:          config.Format,
+			Encoding:        config.Encoding,
+			Newline:         config.newlineType(),
+			Sort:            config.Sort,
+			Reverse:         config.Reverse,
+			MaxSize:         config.MaxSize,
+			MinSize:         config.MinSize,
+			SkipEmpty:       config.SkipEmpty,
+			MaxFiles:        config.MaxFiles,
+			SeparatorFormat: config.SeparatorFormat,
+			NoSeparator:     config.NoSeparator,
+			LineNumbers:     config.LineNumbers,
+			ImportsOnly:     config.ImportsOnly,
+			Gzip:            config.Gzip,
+		},
+		Output:  config.Output,
+		Files:   []manifestFile{},
+		Skipped: []manifestSkipped{},
+	}
+	if !config.NoTimestamp {
+		manifest.Generated = config.combineTime().Format(time.RFC3339)
+	}
+	if info, err := os.Stat(config.Output); err == nil && info.Mode().IsRegular() {
+		manifest.OutputSize = info.Size()
+		manifest.OutputSHA256, _ = hashFile(config.Output)
+	}
+
+	for _, file := range files {
+		region, ok := config.run.regions.regions[file]
+		if !ok {
+			continue
+		}
+		entry := manifestFile{Offset: region.Offset, Length: region.Length}
+		entry.Path, _ = filepath.Rel(config.Root, file)
+		entry.Path = filepath.ToSlash(entry.Path)
+		entry.AbsPath, _ = filepath.Abs(file)
+		if info, err := os.Stat(file); err == nil {
+			entry.Size = info.Size()
+			if mtime, ok := fileModTime(config, info); ok {
+				entry.ModTime = mtime.UTC().Format(time.RFC3339)
+			}
+		}
+		if data, err := os.ReadFile(file); err == nil {
+			sum := sha256.Sum256(data)
+			entry.SHA256 = hex.EncodeToString(sum[:])
+			entry.Lines = contentLineCount(data)
+		}
+		style := getCommentStyle(file)
+		entry.CommentStyle = manifestComment{style.SingleLine, style.BlockStart, style.BlockEnd}
+		manifest.Files = append(manifest.Files, entry)
+	}
+	manifest.TotalFiles = len(manifest.Files)
+
+	for _, s := range skipped {
+		relPath, _ := filepath.Rel(config.Root, s.Path)
+		manifest.Skipped = append(manifest.Skipped, manifestSkipped{filepath.ToSlash(relPath), s.Reason})
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(config.Manifest, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write manifest: %v", err)
+	}
+	return nil
+}
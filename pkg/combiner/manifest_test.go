@@ -0,0 +1,132 @@
+package combiner
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestManifestOffsets(t *testing.T) {
+	files := map[string]string{
+		"a.go":        "package a\n",
+		"b.py":        "print('b')\n",
+		"docs/c.md":   "# c\n\ntext\n",
+		"docs/d.txt":  "no newline",
+		"src/e/f.css": "p {}\n",
+	}
+	tests := []struct {
+		name  string
+		setup func(*Options)
+	}{
+		{"plain", nil},
+		{"bom", func(config *Options) { config.BOM = "always" }},
+		{"title and toc", func(config *Options) { config.Title = "T"; config.TOC = true }},
+		{"tree", func(config *Options) { config.Tree = true }},
+		{"content prefix", func(config *Options) { config.ContentPrefix = "<<{path}>>" }},
+		{"no leading separator", func(config *Options) { config.NoLeadingSeparator = true }},
+		{"markdown", func(config *Options) { config.Format = FORMAT_MARKDOWN }},
+		{"jobs", func(config *Options) { config.Jobs = 3 }},
+		{"jobs with header", func(config *Options) { config.Jobs = 3; config.Header = "HEADER" }},
+		{"header and footer", func(config *Options) { config.Header = "top\r\nlines"; config.Footer = "bottom" }},
+	}
+	root := writeTree(t, files)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+
+			data, err := os.ReadFile(config.Manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var manifest jsonManifest
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				t.Fatal(err)
+			}
+			if manifest.TotalFiles != len(files) || len(manifest.Files) != len(files) {
+				t.Fatalf("manifest lists %d files, want %d", len(manifest.Files), len(files))
+			}
+			for _, file := range manifest.Files {
+				end := file.Offset + file.Length
+				if file.Offset < 0 || end > int64(len(out)) {
+					t.Errorf("%s: region %d+%d outside the %d byte output", file.Path, file.Offset, file.Length, len(out))
+					continue
+				}
+				if got := out[file.Offset:end]; got != files[file.Path] {
+					t.Errorf("%s: region holds %q, want %q", file.Path, got, files[file.Path])
+				}
+			}
+		})
+	}
+}
+
+func TestManifestDocument(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":   "package main\n\nfunc main() {}\n",
+		"style.css": "p {}",
+		"skip.log":  "log\n",
+		"blob.dat":  "\x00\x01",
+	})
+	mtime := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC) // before SOURCE_DATE_EPOCH, so not clamped
+	if err := os.Chtimes(filepath.Join(root, "main.go"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+
+	config := testOptions(t, root, "*")
+	config.Excludes = []string{"*.log"}
+	config.NoTimestamp = false
+	config.Version = "v9.9.9"
+	config.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+	combine(t, config)
+
+	data, err := os.ReadFile(config.Manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest jsonManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	outputHash, _ := hashFile(config.Output)
+	info, _ := os.Stat(config.Output)
+	if manifest.Version != "v9.9.9" || manifest.Generated != "2023-11-14T22:13:20Z" ||
+		manifest.Output != config.Output || manifest.OutputSize != info.Size() || manifest.OutputSHA256 != outputHash {
+		t.Errorf("top-level fields: %+v", manifest)
+	}
+	if manifest.Config.Root != filepath.ToSlash(root) || strings.Join(manifest.Config.Excludes, ",") != "*.log" {
+		t.Errorf("config: %+v", manifest.Config)
+	}
+
+	if manifest.TotalFiles != 2 || len(manifest.Files) != 2 {
+		t.Fatalf("files: %+v", manifest.Files)
+	}
+	goFile, cssFile := manifest.Files[0], manifest.Files[1]
+	sum, _ := hashFile(filepath.Join(root, "main.go"))
+	if goFile.Path != "main.go" || goFile.AbsPath != filepath.Join(root, "main.go") || goFile.Size != 29 ||
+		goFile.Lines != 3 || goFile.SHA256 != sum || goFile.ModTime != "2020-03-01T12:00:00Z" {
+		t.Errorf("main.go entry: %+v", goFile)
+	}
+	if goFile.CommentStyle != (manifestComment{"//", "/*", "*/"}) {
+		t.Errorf("main.go comment style: %+v", goFile.CommentStyle)
+	}
+	if cssFile.Path != "style.css" || cssFile.Lines != 1 || cssFile.CommentStyle != (manifestComment{BlockStart: "/*", BlockEnd: "*/"}) {
+		t.Errorf("style.css entry: %+v", cssFile)
+	}
+
+	reasons := make(map[string]string)
+	for _, s := range manifest.Skipped {
+		reasons[s.Path] = s.Reason
+	}
+	if reasons["skip.log"] != "Excluded" || reasons["blob.dat"] != "Binary file" || len(reasons) != 2 {
+		t.Errorf("skipped: %+v", manifest.Skipped)
+	}
+}
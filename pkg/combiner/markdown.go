@@ -0,0 +1,40 @@
+package combiner
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// markdownHeading is the "### path" line introducing a file in -format
+// markdown output
+func markdownHeading(config *Options, path string) string {
+	relPath, _ := filepath.Rel(config.Root, path)
+	relPath = collapsePath(filepath.ToSlash(relPath), config.CollapseDepth)
+	return "\n### " + relPath + "\n\n"
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in
+// content, so the content cannot close its own code block
+func markdownFence(content []byte) string {
+	longest, run := 0, 0
+	for _, b := range content {
+		if b == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		longest = 2
+	}
+	return strings.Repeat("`", longest+1)
+}
+
+// markdownTitle renders the -title, or the root directory's name, as a
+// top-level heading
+func markdownTitle(config *Options) string {
+	return "# " + documentTitle(config) + "\n"
+}
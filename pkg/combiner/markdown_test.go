@@ -0,0 +1,68 @@
+package combiner
+
+import "testing"
+
+func TestMarkdownFence(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"", "```"},
+		{"no backticks", "```"},
+		{"`inline` and ``double``", "```"},
+		{"```go\nfenced\n```", "````"},
+		{"a ```` b ``", "`````"},
+		{"```", "````"},
+	}
+	for _, tt := range tests {
+		if got := markdownFence([]byte(tt.content)); got != tt.want {
+			t.Errorf("markdownFence(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":          "package a\n\nfunc A() {}\n",
+		"c.txt":         "no newline",
+		"docs/guide.md": "x := \"```\"\n",
+		"lib/deep/x.py": "print(1)\n",
+	})
+	tests := []struct {
+		name     string
+		title    string
+		collapse int
+		want     string
+	}{
+		{
+			name:  "fences and languages",
+			title: "Project",
+			want: "# Project\n" +
+				"\n### a.go\n\n```go\npackage a\n\nfunc A() {}\n```\n" +
+				"\n### c.txt\n\n```text\nno newline\n```\n" +
+				"\n### docs/guide.md\n\n````markdown\nx := \"```\"\n````\n" +
+				"\n### lib/deep/x.py\n\n```python\nprint(1)\n```\n",
+		},
+		{
+			name:     "collapsed headings",
+			title:    "Collapsed",
+			collapse: 1,
+			want: "# Collapsed\n" +
+				"\n### a.go\n\n```go\npackage a\n\nfunc A() {}\n```\n" +
+				"\n### c.txt\n\n```text\nno newline\n```\n" +
+				"\n### docs/guide.md\n\n````markdown\nx := \"```\"\n````\n" +
+				"\n### lib/…/x.py\n\n```python\nprint(1)\n```\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.Format = FORMAT_MARKDOWN
+			config.Title = tt.title
+			config.CollapseDepth = tt.collapse
+			if got := combine(t, config); got != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
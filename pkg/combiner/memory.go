@@ -0,0 +1,96 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// memoryFeature is an option that holds something for every file in memory,
+// or renders every section ahead of the output, and that -max-memory
+// switches off near its limit
+type memoryFeature struct {
+	flag string
+	on   func(*Options) bool
+	off  func(*Options)
+}
+
+// selectionMemoryFeatures are switched off by Select, before its passes
+// over the candidates run
+var selectionMemoryFeatures = []memoryFeature{
+	{"--dedup", func(c *Options) bool { return c.Dedup }, func(c *Options) { c.Dedup = false }},
+	{"--max-total-size", func(c *Options) bool { return c.MaxTotalSize > 0 }, func(c *Options) { c.MaxTotalSize = 0 }},
+	{"--max-tokens", func(c *Options) bool { return c.MaxTokens > 0 }, func(c *Options) { c.MaxTokens = 0 }},
+}
+
+// outputMemoryFeatures are switched off by Combine, before it writes
+var outputMemoryFeatures = []memoryFeature{
+	{"--toc", func(c *Options) bool { return c.TOC }, func(c *Options) { c.TOC = false }},
+	{"--embed-manifest", func(c *Options) bool { return c.EmbedManifest }, func(c *Options) { c.EmbedManifest = false }},
+	{"--manifest", func(c *Options) bool { return c.Manifest != "" }, func(c *Options) { c.Manifest = "" }},
+	{"--self-check", func(c *Options) bool { return c.SelfCheck }, func(c *Options) { c.SelfCheck = false }},
+	{"--resume", func(c *Options) bool { return c.Resume }, func(c *Options) { c.Resume = false }},
+	{"clipboard output", func(c *Options) bool { return c.Output == "c" }, func(c *Options) { c.Output = clipboardFallback(c.Format) }},
+}
+
+// limitMemory switches off the features that need full buffering, with a
+// warning, once the estimated output for files reaches 90% of -max-memory.
+// It works on the run's copy of the options; the output itself is always
+// streamed.
+func limitMemory(config *Options, files []string, features []memoryFeature) {
+	if config.MaxMemory <= 0 {
+		return
+	}
+	estimate := estimateOutputSize(config, files)
+	if estimate < config.MaxMemory*9/10 {
+		return
+	}
+	clipboard := config.Output == "c"
+	var off []string
+	for _, feature := range features {
+		if feature.on(config) {
+			feature.off(config)
+			off = append(off, feature.flag)
+		}
+	}
+	if len(off) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: Estimated output (%s) approaches --max-memory (%s); switching off %s\n",
+		formatSize(estimate), formatSize(config.MaxMemory), strings.Join(off, ", "))
+	if clipboard && config.Output != "c" {
+		fmt.Fprintf(os.Stderr, "Warning: Writing to %s instead of the clipboard\n", config.Output)
+	}
+}
+
+// clipboardFallback is the file clipboard output goes to when it would not
+// fit in -max-memory
+func clipboardFallback(format string) string {
+	switch format {
+	case FORMAT_CSV:
+		return "combined.csv"
+	case FORMAT_MARKDOWN:
+		return "combined.md"
+	case FORMAT_JSON:
+		return "combined.json"
+	case FORMAT_XML:
+		return "combined.xml"
+	}
+	return "combined.txt"
+}
+
+// estimateOutputSize approximates the combined size: file contents, base64
+// encoded for -include-binary files, plus separator overhead
+func estimateOutputSize(config *Options, files []string) int64 {
+	var total int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size := info.Size()
+			if config.isBinary(file) {
+				size = size * 4 / 3
+			}
+			total += size + 256
+		}
+	}
+	return total
+}
@@ -0,0 +1,180 @@
+package combiner
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxMemory(t *testing.T) {
+	files := map[string]string{
+		"a.txt":      strings.Repeat("a", 2000) + "\n",
+		"b.txt":      strings.Repeat("b", 2000) + "\n",
+		"copy/a.txt": strings.Repeat("a", 2000) + "\n",
+	}
+	root := writeTree(t, files)
+	estimate := estimateOutputSize(DefaultOptions(), treeFiles(root, files))
+	options := func(t *testing.T) *Options {
+		config := testOptions(t, root, "**/*.txt")
+		config.Dedup = true
+		config.MaxTotalSize = 1 << 20
+		config.TOC = true
+		config.Manifest = filepath.Join(t.TempDir(), "manifest.json")
+		return config
+	}
+	plain := options(t)
+	plain.Dedup, plain.MaxTotalSize, plain.TOC, plain.Manifest = false, 0, false, ""
+	unbuffered := combine(t, plain)
+
+	tests := []struct {
+		name  string
+		limit int64
+		off   bool
+	}{
+		{"unlimited", 0, false},
+		{"far above", 1 << 20, false},
+		{"just above the 90% mark", estimate*10/9 + 16, false},
+		{"at the 90% mark", estimate * 10 / 9, true},
+		{"below", 1024, true},
+		{"tiny", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := options(t)
+			config.MaxMemory = tt.limit
+			var got string
+			stderr := captureStderr(t, func() { got = combine(t, config) })
+			for _, flag := range []string{"--dedup", "--max-total-size", "--toc", "--manifest"} {
+				if strings.Contains(stderr, flag) != tt.off {
+					t.Errorf("%s switched off = %v, want %v; stderr:\n%s", flag, !tt.off, tt.off, stderr)
+				}
+			}
+			_, err := os.Stat(config.Manifest)
+			if tt.off {
+				if got != unbuffered {
+					t.Errorf("output:\n%s\nwant it as without the buffering features:\n%s", got, unbuffered)
+				}
+				if err == nil {
+					t.Errorf("manifest written although switched off")
+				}
+			} else if !strings.Contains(got, "TABLE OF CONTENTS") || strings.Count(got, strings.Repeat("a", 2000)) != 1 || err != nil {
+				t.Errorf("buffering features missing (manifest: %v):\n%s", err, got)
+			}
+			if !config.Dedup || !config.TOC || config.MaxTotalSize == 0 || config.Manifest == "" {
+				t.Errorf("the run changed the options: %+v", config)
+			}
+		})
+	}
+}
+
+func TestMaxMemoryOutputs(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": strings.Repeat("a", 2000) + "\n"})
+	tests := []struct {
+		name  string
+		setup func(*Options, *bytes.Buffer)
+	}{
+		{"file", func(*Options, *bytes.Buffer) {}},
+		{"stdout", func(config *Options, stdout *bytes.Buffer) { config.Output, config.Stdout = "-", stdout }},
+		{"pipe", func(config *Options, stdout *bytes.Buffer) { config.PipeTo, config.Stdout = "cat", stdout }},
+		{"append", func(config *Options, _ *bytes.Buffer) { config.Append = true }},
+		{"parts", func(config *Options, _ *bytes.Buffer) { config.SplitLines = 1000 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			config := testOptions(t, root, "*.txt")
+			config.TOC = true
+			config.MaxMemory = 1024
+			tt.setup(config, &stdout)
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			stderr := captureStderr(t, func() {
+				captureStdout(t, func() { _, err = New(config).Run(context.Background()) })
+			})
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if !strings.Contains(stderr, "switching off --toc") {
+				t.Errorf("stderr:\n%s", stderr)
+			}
+			out := stdout.String()
+			if config.Output != "-" && config.PipeTo == "" {
+				data, _ := os.ReadFile(config.Output)
+				if config.SplitLines > 0 {
+					data, _ = os.ReadFile(partName(config, 1, 1))
+				}
+				out = string(data)
+			}
+			if !strings.Contains(out, strings.Repeat("a", 2000)) || strings.Contains(out, "TABLE OF CONTENTS") {
+				t.Errorf("output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestMaxMemoryClipboard(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": strings.Repeat("a", 2000) + "\n"})
+	tests := []struct {
+		format string
+		file   string
+	}{
+		{FORMAT_TEXT, "combined.txt"},
+		{FORMAT_MARKDOWN, "combined.md"},
+		{FORMAT_JSON, "combined.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			config := testOptions(t, root, "*.txt")
+			config.Output = "c"
+			config.Format = tt.format
+			config.MaxMemory = 1024
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			stderr := captureStderr(t, func() { captureStdout(t, func() { _, err = New(config).Run(context.Background()) }) })
+			if err != nil {
+				t.Fatalf("clipboard above --max-memory: %v", err)
+			}
+			if !strings.Contains(stderr, "Writing to "+tt.file+" instead of the clipboard") {
+				t.Errorf("stderr:\n%s", stderr)
+			}
+			if data, err := os.ReadFile(tt.file); err != nil || !strings.Contains(string(data), strings.Repeat("a", 2000)) {
+				t.Errorf("%s: %v\n%s", tt.file, err, data)
+			}
+			if config.Output != "c" {
+				t.Errorf("Output changed to %q", config.Output)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"1048576", 1048576, true},
+		{"512KB", 512 << 10, true},
+		{"64mb", 64 << 20, true},
+		{" 2G ", 2 << 30, true},
+		{"10B", 10, true},
+		{"1.5M", 0, false},
+		{"MB", 0, false},
+		{"-1K", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
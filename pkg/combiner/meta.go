@@ -0,0 +1,130 @@
+package combiner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Fields -separator-meta can add to the default separators
+const (
+	META_SIZE   = "size"
+	META_LINES  = "lines"
+	META_SHA256 = "sha256"
+	META_MTIME  = "mtime"
+	META_GIT    = "git"
+)
+
+// separatorMetaLabels are the labels of the metadata lines, in the order
+// they are written after the FILE line; -unpack skips lines with these
+// labels (and "Combined at") when it reads separators back
+var separatorMetaLabels = []struct{ field, label string }{
+	{META_SIZE, "Size"},
+	{META_LINES, "Lines"},
+	{META_SHA256, "SHA-256"},
+	{META_MTIME, "Modified"},
+	{META_GIT, "Last commit"},
+}
+
+// NO_FINAL_NEWLINE is the separator line marking a file whose content does
+// not end with a newline, so -unpack drops the one added after it
+const NO_FINAL_NEWLINE = "No newline at end of file"
+
+// missingFinalNewline reports whether content does not end with a newline
+// of any kind, so one is written after it
+func missingFinalNewline(content []byte) bool {
+	return len(content) > 0 && content[len(content)-1] != '\n' && content[len(content)-1] != '\r'
+}
+
+// marksFinalNewline reports whether the separators carry NO_FINAL_NEWLINE,
+// which only output meant to be split back needs
+func marksFinalNewline(config *Options) bool {
+	return config.ExactNewlines || config.SelfCheck
+}
+
+// wantsSeparatorMeta reports whether -separator-meta asks for the field
+func wantsSeparatorMeta(config *Options, field string) bool {
+	for _, f := range config.SeparatorMeta {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidSeparatorMeta reports whether name is a -separator-meta field
+func ValidSeparatorMeta(name string) bool {
+	for _, meta := range separatorMetaLabels {
+		if meta.field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// separatorMetaLines renders the -separator-meta fields of a file, in the
+// fixed order of separatorMetaLabels
+func separatorMetaLines(config *Options, path string) []string {
+	if len(config.SeparatorMeta) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(config.SeparatorMeta))
+	for _, field := range config.SeparatorMeta {
+		wanted[field] = true
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	var data []byte
+	if wanted[META_LINES] || wanted[META_SHA256] {
+		if data, err = os.ReadFile(path); err != nil {
+			return nil
+		}
+	}
+
+	var lines []string
+	for _, meta := range separatorMetaLabels {
+		if !wanted[meta.field] {
+			continue
+		}
+		value := ""
+		switch meta.field {
+		case META_SIZE:
+			value = fmt.Sprintf("%s (%d bytes)", formatSize(info.Size()), info.Size())
+		case META_LINES:
+			value = fmt.Sprint(contentLineCount(data))
+		case META_SHA256:
+			sum := sha256.Sum256(data)
+			value = hex.EncodeToString(sum[:])
+		case META_MTIME:
+			mtime, ok := fileModTime(config, info)
+			if !ok {
+				continue
+			}
+			value = mtime.Format("2006-01-02 15:04:05")
+		case META_GIT:
+			if value = lastCommitLine(config, path); value == "" {
+				continue
+			}
+		}
+		lines = append(lines, meta.label+": "+value)
+	}
+	return lines
+}
+
+// isSeparatorMetaLine reports whether a separator line, without its comment
+// prefix, is one of the metadata lines
+func isSeparatorMetaLine(line string) bool {
+	if strings.HasPrefix(line, " Combined at: ") || line == " "+NO_FINAL_NEWLINE {
+		return true
+	}
+	for _, meta := range separatorMetaLabels {
+		if strings.HasPrefix(line, " "+meta.label+": ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeparatorMetaLines(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "one\ntwo\nthree"})
+	path := filepath.Join(root, "a.txt")
+	mtime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	const hash = "058053d87c818d699cde0f00d670bca0e1c6ad857caa9758ea6a556d7c64fcee"
+	tests := []struct {
+		name        string
+		fields      []string
+		noTimestamp bool
+		want        []string
+	}{
+		{"none", nil, false, nil},
+		{"size", []string{META_SIZE}, false, []string{"Size: 13 B (13 bytes)"}},
+		{"lines without a final newline", []string{META_LINES}, false, []string{"Lines: 3"}},
+		{"sha256", []string{META_SHA256}, false, []string{"SHA-256: " + hash}},
+		{"mtime", []string{META_MTIME}, false, []string{"Modified: 2021-03-04 05:06:07"}},
+		{"mtime without timestamps", []string{META_MTIME}, true, nil},
+		{
+			name:   "fixed order",
+			fields: []string{META_MTIME, META_SHA256, META_LINES, META_SIZE},
+			want:   []string{"Size: 13 B (13 bytes)", "Lines: 3", "SHA-256: " + hash, "Modified: 2021-03-04 05:06:07"},
+		},
+		{"git outside a repository", []string{META_GIT, META_LINES}, false, []string{"Lines: 3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.SeparatorMeta = tt.fields
+			config.NoTimestamp = tt.noTimestamp
+			if got := separatorMetaLines(config, path); !sameStrings(got, tt.want) {
+				t.Errorf("separatorMetaLines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidSeparatorMeta(t *testing.T) {
+	for _, name := range []string{META_SIZE, META_LINES, META_SHA256, META_MTIME, META_GIT} {
+		if !ValidSeparatorMeta(name) {
+			t.Errorf("ValidSeparatorMeta(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"", "hash", "Size", "modified"} {
+		if ValidSeparatorMeta(name) {
+			t.Errorf("ValidSeparatorMeta(%q) = true", name)
+		}
+	}
+}
+
+func TestIsSeparatorMetaLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{" Combined at: 2024-01-01 00:00:00", true},
+		{" Size: 1 B (1 bytes)", true},
+		{" Lines: 3", true},
+		{" SHA-256: abc", true},
+		{" Modified: 2024-01-01 00:00:00", true},
+		{" Last commit: abc1234 Ann 2024-01-01", true},
+		{" " + NO_FINAL_NEWLINE, true},
+		{" FILE 1: a.txt", false},
+		{"Size: 1 B", false},
+		{" Sizes: 1", false},
+	}
+	for _, tt := range tests {
+		if got := isSeparatorMetaLine(tt.line); got != tt.want {
+			t.Errorf("isSeparatorMetaLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSeparatorMetaOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.py": "print(1)\n", "b.txt": "x"})
+	config := testOptions(t, root, "*")
+	config.SeparatorMeta = []string{META_SIZE, META_LINES}
+	out := combine(t, config)
+	for _, want := range []string{
+		"# FILE 1: a.py\n# Size: 9 B (9 bytes)\n# Lines: 1\n",
+		"# FILE 2: b.txt\n# Size: 1 B (1 bytes)\n# Lines: 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
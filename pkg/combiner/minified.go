@@ -0,0 +1,47 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MINIFIED_LINE_LENGTH = 300  // average characters per line
+	MINIFIED_MIN_SIZE    = 1024 // smaller files are never considered minified
+)
+
+// detectMinified reports whether a file looks minified: either its name
+// contains ".min." or, for files of at least minSize bytes, its average line
+// length exceeds avgLineLength. The string describes why.
+func detectMinified(path string, avgLineLength int, minSize int64) (bool, string) {
+	if strings.Contains(strings.ToLower(filepath.Base(path)), ".min.") {
+		return true, ".min. in name"
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < minSize {
+		return false, ""
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, ""
+	}
+
+	lines := bytes.Count(content, []byte{'\n'})
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	if lines == 0 {
+		return false, ""
+	}
+
+	avg := len(content) / lines
+	if avg > avgLineLength {
+		return true, fmt.Sprintf("average line length %d", avg)
+	}
+	return false, ""
+}
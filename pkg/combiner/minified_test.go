@@ -0,0 +1,51 @@
+package combiner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectMinified(t *testing.T) {
+	long := strings.Repeat("x", 2000) + "\n"
+	code := strings.Repeat("var x = 1;\n", 200)
+	files := map[string]string{
+		"app.min.js":   "a\n",
+		"APP.MIN.CSS":  "a\n",
+		"bundle.js":    long,
+		"source.js":    code,
+		"tiny.js":      strings.Repeat("x", 500),
+		"one-line.css": strings.Repeat("p{}", 400),
+	}
+	root := writeTree(t, files)
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app.min.js", true},
+		{"APP.MIN.CSS", true},
+		{"bundle.js", true},
+		{"source.js", false},
+		{"tiny.js", false}, // below the minimum size
+		{"one-line.css", true},
+	}
+	for _, tt := range tests {
+		got, reason := detectMinified(filepath.Join(root, tt.name), MINIFIED_LINE_LENGTH, MINIFIED_MIN_SIZE)
+		if got != tt.want {
+			t.Errorf("detectMinified(%s) = %v (%s), want %v", tt.name, got, reason, tt.want)
+		}
+	}
+}
+
+func TestSkipMinified(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"app.js":     "console.log(1)\n",
+		"app.min.js": "console.log(1)\n",
+		"vendor.js":  strings.Repeat("x", 4000),
+	})
+	config := testOptions(t, root, "*.js")
+	config.SkipMinified = true
+	if got := selectRel(t, config); !sameStrings(got, []string{"app.js"}) {
+		t.Errorf("got %q, want only app.js", got)
+	}
+}
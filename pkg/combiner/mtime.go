@@ -0,0 +1,56 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the timestamps accepted by -newer-than and -older-than,
+// read in the local time zone unless they carry their own
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeBound parses a -newer-than/-older-than value: a duration back
+// from now such as 48h, 90m, 14d or 2w, or a timestamp such as 2024-01-01
+// or 2024-01-01 09:30
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	// Days and weeks, which time.ParseDuration doesn't know
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64); err == nil && strings.HasSuffix(s, suffix) && n >= 0 {
+			return now.Add(-time.Duration(n * float64(unit))), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid duration or timestamp: %q", s)
+}
+
+// modTimeSkipReason says why the -newer-than/-older-than window leaves out
+// a file, or returns "" when its modification time is inside it
+func modTimeSkipReason(config *Options, info os.FileInfo) string {
+	mtime := info.ModTime()
+	switch {
+	case !config.NewerThan.IsZero() && !mtime.After(config.NewerThan):
+		return fmt.Sprintf("Modified %s, before --newer-than", mtime.Format("2006-01-02 15:04"))
+	case !config.OlderThan.IsZero() && !mtime.Before(config.OlderThan):
+		return fmt.Sprintf("Modified %s, after --older-than", mtime.Format("2006-01-02 15:04"))
+	}
+	return ""
+}
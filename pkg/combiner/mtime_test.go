@@ -0,0 +1,128 @@
+package combiner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "48h", want: now.Add(-48 * time.Hour)},
+		{in: "90m", want: now.Add(-90 * time.Minute)},
+		{in: "1h30m", want: now.Add(-90 * time.Minute)},
+		{in: "14d", want: now.AddDate(0, 0, -14)},
+		{in: "1.5d", want: now.Add(-36 * time.Hour)},
+		{in: "2w", want: now.AddDate(0, 0, -14)},
+		{in: "0d", want: now},
+		{in: " 2d ", want: now.AddDate(0, 0, -2)},
+		{in: "2024-01-01", want: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)},
+		{in: "2024-01-01 09:30", want: time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local)},
+		{in: "2024-01-01T09:30:15", want: time.Date(2024, 1, 1, 9, 30, 15, 0, time.Local)},
+		{in: "2024-01-01T09:30:00Z", want: time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)},
+		{in: "2024-01-01T09:30:00+02:00", want: time.Date(2024, 1, 1, 7, 30, 0, 0, time.UTC)},
+		{in: "", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "-2h", wantErr: true},
+		{in: "-3d", wantErr: true},
+		{in: "3x", wantErr: true},
+		{in: "2024-13-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTimeBound(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestModTimeWindow(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"old.txt":    "old\n",
+		"middle.txt": "middle\n",
+		"new.txt":    "new\n",
+	})
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.Local) }
+	for name, mtime := range map[string]time.Time{"old.txt": day(1), "middle.txt": day(10), "new.txt": day(20)} {
+		if err := os.Chtimes(filepath.Join(root, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name        string
+		newer       time.Time
+		older       time.Time
+		want        []string
+		wantSkipped map[string]string
+	}{
+		{name: "no window", want: []string{"middle.txt", "new.txt", "old.txt"}},
+		{
+			name:        "newer than",
+			newer:       day(5),
+			want:        []string{"middle.txt", "new.txt"},
+			wantSkipped: map[string]string{"old.txt": "Modified 2024-01-01 12:00, before --newer-than"},
+		},
+		{
+			name:        "older than",
+			older:       day(15),
+			want:        []string{"middle.txt", "old.txt"},
+			wantSkipped: map[string]string{"new.txt": "Modified 2024-01-20 12:00, after --older-than"},
+		},
+		{
+			name:  "window",
+			newer: day(5),
+			older: day(15),
+			want:  []string{"middle.txt"},
+			wantSkipped: map[string]string{
+				"old.txt": "Modified 2024-01-01 12:00, before --newer-than",
+				"new.txt": "Modified 2024-01-20 12:00, after --older-than",
+			},
+		},
+		{
+			name:        "bounds are exclusive",
+			newer:       day(10),
+			older:       day(20),
+			want:        nil,
+			wantSkipped: map[string]string{"old.txt": "", "middle.txt": "", "new.txt": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.NewerThan = tt.newer
+			config.OlderThan = tt.older
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			if got := relFiles(t, root, report.Files); !sameStrings(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+			skipped := make(map[string]string)
+			for _, file := range report.Skipped {
+				skipped[relFiles(t, root, []string{file.Path})[0]] = file.Reason
+			}
+			if len(skipped) != len(tt.wantSkipped) {
+				t.Errorf("skipped = %q, want %q", skipped, tt.wantSkipped)
+			}
+			for name, reason := range tt.wantSkipped {
+				if got, ok := skipped[name]; !ok || reason != "" && got != reason {
+					t.Errorf("%s skipped with %q, want %q", name, got, reason)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// lineEndings tallies the line terminators found in some content
+type lineEndings struct {
+	LF   int
+	CRLF int
+	CR   int
+}
+
+// countLineEndings classifies every line terminator in data
+func countLineEndings(data []byte) lineEndings {
+	var counts lineEndings
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				counts.CRLF++
+				i++
+			} else {
+				counts.CR++
+			}
+		case '\n':
+			counts.LF++
+		}
+	}
+	return counts
+}
+
+// detectNewline returns the dominant line ending ("lf", "crlf" or "cr")
+// across files. Ties and files without any line breaks fall back to LF.
+func detectNewline(files []string, verbose bool) string {
+	var total lineEndings
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		counts := countLineEndings(content)
+		total.LF += counts.LF
+		total.CRLF += counts.CRLF
+		total.CR += counts.CR
+	}
+
+	result := "lf"
+	if total.CRLF > total.LF && total.CRLF > total.CR {
+		result = "crlf"
+	} else if total.CR > total.LF && total.CR > total.CRLF {
+		result = "cr"
+	}
+
+	if verbose {
+		fmt.Printf("Detected line endings: LF=%d CRLF=%d CR=%d -> %s\n", total.LF, total.CRLF, total.CR, result)
+	}
+	return result
+}
+
+// mixed reports whether more than one kind of line ending was seen
+func (c lineEndings) mixed() bool {
+	kinds := 0
+	for _, n := range []int{c.LF, c.CRLF, c.CR} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	return kinds > 1
+}
+
+// mixedNewlineFile is a file that uses more than one kind of line ending
+type mixedNewlineFile struct {
+	Path   string
+	Counts lineEndings
+}
+
+// newlineLog collects the line endings of the files a -warn-mixed-newlines
+// run reads, classified while their content is in memory; workers record
+// concurrently
+type newlineLog struct {
+	mu     sync.Mutex
+	counts map[string]lineEndings
+}
+
+// recordNewlines classifies the line endings of a file's content under
+// -warn-mixed-newlines
+func (config *Options) recordNewlines(path string, content []byte) {
+	if !config.WarnMixedNewlines || config.run == nil {
+		return
+	}
+	counts := countLineEndings(content)
+	log := &config.run.newlines
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	if log.counts == nil {
+		log.counts = make(map[string]lineEndings)
+	}
+	log.counts[path] = counts
+}
+
+// mixed returns the files, in the order given, that mix LF, CRLF and/or CR
+// line endings
+func (l *newlineLog) mixed(files []string) []mixedNewlineFile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var mixed []mixedNewlineFile
+	for _, file := range files {
+		if counts, ok := l.counts[file]; ok && counts.mixed() {
+			mixed = append(mixed, mixedNewlineFile{Path: file, Counts: counts})
+		}
+	}
+	return mixed
+}
+
+// printMixedNewlines warns about the files that mix line endings
+func printMixedNewlines(root string, mixed []mixedNewlineFile) {
+	if len(mixed) == 0 {
+		return
+	}
+	fmt.Println("\nWARNING: MIXED LINE ENDINGS (showing first 15):")
+	limit := len(mixed)
+	if limit > 15 {
+		limit = 15
+	}
+	for i := 0; i < limit; i++ {
+		relPath, _ := filepath.Rel(root, mixed[i].Path)
+		c := mixed[i].Counts
+		fmt.Printf("  ! %s (LF=%d CRLF=%d CR=%d)\n", relPath, c.LF, c.CRLF, c.CR)
+	}
+	if len(mixed) > 15 {
+		fmt.Printf("  ... and %d more files\n", len(mixed)-15)
+	}
+}
+
+// normalizeNewlines rewrites every line ending in data (CRLF, lone CR or LF)
+// to newline
+func normalizeNewlines(data []byte, newline string) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\r':
+			if i+1 < len(data) && data[i+1] == '\n' {
+				i++
+			}
+			out = append(out, newline...)
+		case '\n':
+			out = append(out, newline...)
+		default:
+			out = append(out, data[i])
+		}
+	}
+	return out
+}
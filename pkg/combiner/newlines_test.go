@@ -0,0 +1,177 @@
+package combiner
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// treeFiles returns the paths of the files of a tree written by writeTree
+func treeFiles(root string, files map[string]string) []string {
+	var paths []string
+	for name := range files {
+		paths = append(paths, filepath.Join(root, filepath.FromSlash(name)))
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestDetectNewline(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"lf", map[string]string{"a": "1\n2\n"}, "lf"},
+		{"mostly crlf", map[string]string{"a": "1\r\n2\r\n3\r\n", "b": "1\n"}, "crlf"},
+		{"mostly lf", map[string]string{"a": "1\r\n", "b": "1\n2\n"}, "lf"},
+		{"cr", map[string]string{"a": "1\r2\r"}, "cr"},
+		{"tie", map[string]string{"a": "1\r\n", "b": "1\n"}, "lf"},
+		{"no line breaks", map[string]string{"a": "x"}, "lf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			if got := detectNewline(treeFiles(root, tt.files), false); got != tt.want {
+				t.Errorf("detectNewline = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewlineAutoOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "1\r\n2\r\n", "b.txt": "3\r\n"})
+	config := testOptions(t, root, "*.txt")
+	config.NewlineType = "auto"
+	out := combine(t, config)
+	if config.NewlineType != "auto" {
+		t.Errorf("NewlineType = %q, want it left at auto", config.NewlineType)
+	}
+	counts := countLineEndings([]byte(out))
+	if counts.CRLF == 0 {
+		t.Errorf("output has no CRLF line endings: %+v", counts)
+	}
+}
+
+func TestNewlineLog(t *testing.T) {
+	files := map[string]string{
+		"clean.txt":  "1\n2\n",
+		"crlf.txt":   "1\r\n2\r\n",
+		"mixed.txt":  "1\r\n2\n3\n",
+		"lone-cr.go": "1\n2\r3\n",
+	}
+	root := writeTree(t, files)
+	paths := treeFiles(root, files)
+	config := testOptions(t, root, "*")
+	config.WarnMixedNewlines = true
+	config.run = &runState{}
+	for _, path := range paths {
+		if _, err := loadContent(config, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mixed := config.run.newlines.mixed(paths)
+	if len(mixed) != 2 {
+		t.Fatalf("found %d mixed files, want 2: %+v", len(mixed), mixed)
+	}
+	if got := filepath.Base(mixed[0].Path); got != "lone-cr.go" || mixed[0].Counts != (lineEndings{LF: 2, CR: 1}) {
+		t.Errorf("first = %s %+v", got, mixed[0].Counts)
+	}
+	if got := filepath.Base(mixed[1].Path); got != "mixed.txt" || mixed[1].Counts != (lineEndings{LF: 2, CRLF: 1}) {
+		t.Errorf("second = %s %+v", got, mixed[1].Counts)
+	}
+}
+
+func TestNormalizeNewlines(t *testing.T) {
+	tests := []struct {
+		data    string
+		newline string
+		want    string
+	}{
+		{"a\r\nb\nc\rd", "\n", "a\nb\nc\nd"},
+		{"a\r\nb\nc\rd\n", "\r\n", "a\r\nb\r\nc\r\nd\r\n"},
+		{"a\r\r\nb", "\n", "a\n\nb"},
+		{"a\nb\n", "\r", "a\rb\r"},
+		{"", "\r\n", ""},
+	}
+	for _, tt := range tests {
+		if got := string(normalizeNewlines([]byte(tt.data), tt.newline)); got != tt.want {
+			t.Errorf("normalizeNewlines(%q, %q) = %q, want %q", tt.data, tt.newline, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNewlinesOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "1\n2\r3\r\n", "b.txt": "4\n5"})
+	config := testOptions(t, root, "*.txt")
+	config.NewlineType = "crlf"
+	config.NormalizeNewlines = true
+	out := combine(t, config)
+	for _, want := range []string{"\n1\r\n2\r\n3\r\n\n", "\n4\r\n5\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%q", want, out)
+		}
+	}
+	if strings.Contains(out, "\r\n\r\n") {
+		t.Errorf("line ending doubled at a file boundary:\n%q", out)
+	}
+}
+
+func TestMixedNewlinesWarning(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"clean.txt": "1\n2\n",
+		"mixed.txt": "1\r\n2\n3\r",
+		"range.txt": "1\n2\n3\r\n",
+	})
+	tests := []struct {
+		name  string
+		setup func(*Options)
+		want  []string
+		not   []string
+	}{
+		{"off", func(config *Options) { config.WarnMixedNewlines = false }, nil, []string{"Mixed newlines", "MIXED LINE ENDINGS"}},
+		{"on", nil, []string{
+			"Mixed newlines: 2 files",
+			"WARNING: MIXED LINE ENDINGS",
+			"  ! mixed.txt (LF=1 CRLF=1 CR=1)",
+			"  ! range.txt (LF=2 CRLF=1 CR=0)",
+		}, []string{"clean.txt ("}},
+		{"jobs", func(config *Options) { config.Jobs = 3 }, []string{
+			"Mixed newlines: 2 files",
+			"  ! mixed.txt (LF=1 CRLF=1 CR=1)",
+		}, nil},
+		{"normalized output", func(config *Options) { config.NormalizeNewlines = true }, []string{
+			"Mixed newlines: 2 files",
+		}, nil},
+		{"only what is combined", func(config *Options) {
+			config.LineRanges = map[string]lineRange{filepath.Join(root, "range.txt"): {Start: 1, End: 2}}
+		}, []string{"Mixed newlines: 1 files", "  ! mixed.txt"}, []string{"range.txt ("}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.WarnMixedNewlines = true
+			config.Rebuilding = false
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := captureStdout(t, func() {
+				if _, err := New(config).Run(context.Background()); err != nil {
+					t.Errorf("Run: %v", err)
+				}
+			})
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("report lacks %q:\n%s", want, out)
+				}
+			}
+			for _, not := range tt.not {
+				if strings.Contains(out, not) {
+					t.Errorf("report has %q:\n%s", not, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Orderings selectable with -sort
+const (
+	SORT_PATH    = "path"
+	SORT_SIZE    = "size"
+	SORT_MTIME   = "mtime"
+	SORT_PATTERN = "pattern"
+)
+
+func ValidSort(mode string) bool {
+	return mode == SORT_PATH || mode == SORT_SIZE || mode == SORT_MTIME || mode == SORT_PATTERN
+}
+
+// orderFiles applies the requested ordering to the discovered files.
+// findFiles already returns paths sorted alphabetically, and every sort is
+// stable, so ties keep that order (also with -reverse). patternOrder holds
+// the index of the first pattern that matched each file.
+func orderFiles(config *Options, files []string, patternOrder map[string]int) []string {
+	keys := make(map[string]int64, len(files))
+	switch config.Sort {
+	case SORT_SIZE, SORT_MTIME:
+		for _, f := range files {
+			if info, err := os.Stat(f); err == nil {
+				if config.Sort == SORT_SIZE {
+					keys[f] = info.Size()
+				} else {
+					keys[f] = info.ModTime().UnixNano()
+				}
+			}
+		}
+	case SORT_PATTERN:
+		for _, f := range files {
+			keys[f] = int64(patternOrder[f])
+		}
+	default:
+		if config.Reverse {
+			for i, j := 0, len(files)-1; i < j; i, j = i+1, j-1 {
+				files[i], files[j] = files[j], files[i]
+			}
+		}
+		return files
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if config.Reverse {
+			return keys[files[i]] > keys[files[j]]
+		}
+		return keys[files[i]] < keys[files[j]]
+	})
+	return files
+}
+
+// orderDescription describes the ordering orderFiles and limitFiles applied,
+// for the -order-note header line
+func orderDescription(config *Options) string {
+	desc := config.Sort + " ascending"
+	if config.Reverse {
+		desc = config.Sort + " descending"
+	}
+	if config.FilesFrom != "" && config.Sort == SORT_PATH {
+		desc = "listed order (--files-from)"
+		if config.Reverse {
+			desc = "reversed listed order (--files-from)"
+		}
+	}
+	if config.LargestFirst && config.Sort == SORT_SIZE {
+		desc += " (--largest-first)"
+	}
+	if config.MaxFiles > 0 {
+		desc += fmt.Sprintf(", first %d files (--max-files)", config.MaxFiles)
+	}
+	return desc
+}
+
+// limitFiles keeps the first config.MaxFiles entries and reports the rest as skipped
+func limitFiles(config *Options, files []string) ([]string, []FileInfo) {
+	if config.MaxFiles <= 0 || len(files) <= config.MaxFiles {
+		return files, nil
+	}
+
+	var skipped []FileInfo
+	for _, f := range files[config.MaxFiles:] {
+		skipped = append(skipped, FileInfo{f, fmt.Sprintf("Beyond --max-files limit (%d)", config.MaxFiles)})
+	}
+	return files[:config.MaxFiles], skipped
+}
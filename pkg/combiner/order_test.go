@@ -0,0 +1,123 @@
+package combiner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortModes(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.md":  "aaaa\n",
+		"b.go":  "b\n",
+		"c.txt": "cccccccc\n",
+	})
+	now := time.Now()
+	for i, name := range []string{"c.txt", "a.md", "b.go"} { // oldest first
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(filepath.Join(root, name), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		sort    string
+		reverse bool
+		want    []string
+	}{
+		{SORT_PATH, false, []string{"a.md", "b.go", "c.txt"}},
+		{SORT_PATH, true, []string{"c.txt", "b.go", "a.md"}},
+		{SORT_SIZE, false, []string{"b.go", "a.md", "c.txt"}},
+		{SORT_SIZE, true, []string{"c.txt", "a.md", "b.go"}},
+		{SORT_MTIME, false, []string{"c.txt", "a.md", "b.go"}},
+		{SORT_PATTERN, false, []string{"c.txt", "b.go", "a.md"}},
+		{SORT_PATTERN, true, []string{"a.md", "b.go", "c.txt"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*.txt", "*.go", "*.md")
+		config.Sort = tt.sort
+		config.Reverse = tt.reverse
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("-sort %s reverse=%v: got %q, want %q", tt.sort, tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestValidSort(t *testing.T) {
+	for _, mode := range []string{SORT_PATH, SORT_SIZE, SORT_MTIME, SORT_PATTERN} {
+		if !ValidSort(mode) {
+			t.Errorf("ValidSort(%q) = false", mode)
+		}
+	}
+	if ValidSort("name") {
+		t.Error(`ValidSort("name") = true`)
+	}
+}
+
+func TestLargestFirst(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"small.txt":  "s\n",
+		"big.txt":    "bbbbbbbbbbbbbbbb\n",
+		"medium.txt": "mmmmmm\n",
+		"huge.txt":   "hhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhhh\n",
+	})
+	config := testOptions(t, root, "*.txt")
+	config.LargestFirst = true
+	config.Sort = SORT_SIZE
+	config.Reverse = true
+	config.MaxFiles = 2
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := New(config).Select(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := relFiles(t, root, report.Files); !sameStrings(got, []string{"huge.txt", "big.txt"}) {
+		t.Errorf("got %q, want the two largest files", got)
+	}
+	limited := 0
+	for _, skip := range report.Skipped {
+		if skip.Reason == "Beyond --max-files limit (2)" {
+			limited++
+		}
+	}
+	if limited != 2 {
+		t.Errorf("%d files reported beyond --max-files, want 2: %+v", limited, report.Skipped)
+	}
+}
+
+func TestOrderDescription(t *testing.T) {
+	tests := []struct {
+		setup func(*Options)
+		want  string
+	}{
+		{func(*Options) {}, "path ascending"},
+		{func(config *Options) { config.Reverse = true }, "path descending"},
+		{func(config *Options) { config.Sort = SORT_MTIME }, "mtime ascending"},
+		{func(config *Options) {
+			config.Sort, config.Reverse, config.LargestFirst, config.MaxFiles = SORT_SIZE, true, true, 5
+		}, "size descending (--largest-first), first 5 files (--max-files)"},
+		{func(config *Options) { config.FilesFrom = "list.txt" }, "listed order (--files-from)"},
+		{func(config *Options) { config.FilesFrom, config.Reverse = "-", true }, "reversed listed order (--files-from)"},
+	}
+	for _, tt := range tests {
+		config := DefaultOptions()
+		tt.setup(config)
+		if got := orderDescription(config); got != tt.want {
+			t.Errorf("orderDescription = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestOrderNote(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n"})
+	config := testOptions(t, root, "*.txt")
+	config.OrderNote = true
+	config.Sort = SORT_SIZE
+	if out := combine(t, config); !strings.Contains(out, "# Order: size ascending\n") {
+		t.Errorf("no order note in:\n%s", out)
+	}
+}
@@ -0,0 +1,335 @@
+package combiner
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// partItem is an entry of a part: a whole file or, with lines set, a range
+// of a file too big to fit in a part of its own
+type partItem struct {
+	path  string
+	lines *lineRange
+}
+
+// splitsOutput reports whether the output is written in numbered parts
+// (-chunk-tokens, -split-size, -split-lines)
+func (config *Options) splitsOutput() bool {
+	return config.ChunkTokens > 0 || config.SplitSize > 0 || config.SplitLines > 0
+}
+
+// partFits reports whether output of these totals stays within every
+// limit set for a part
+func partFits(config *Options, t outputTotals) bool {
+	return (config.ChunkTokens <= 0 || t.tokens(config) <= config.ChunkTokens) &&
+		(config.SplitSize <= 0 || t.Bytes <= config.SplitSize) &&
+		(config.SplitLines <= 0 || t.Lines <= config.SplitLines)
+}
+
+// partLimits describes the limits of a part for warnings
+func partLimits(config *Options) string {
+	var limits []string
+	if config.ChunkTokens > 0 {
+		limits = append(limits, fmt.Sprintf("--chunk-tokens %d", config.ChunkTokens))
+	}
+	if config.SplitSize > 0 {
+		limits = append(limits, "--split-size "+formatSize(config.SplitSize))
+	}
+	if config.SplitLines > 0 {
+		limits = append(limits, fmt.Sprintf("--split-lines %d", config.SplitLines))
+	}
+	return strings.Join(limits, ", ")
+}
+
+// partFields returns the placeholder values of -part-name for the nth of
+// count parts: the output path without its extension, the extension (with
+// .gz) and the numbers
+func partFields(output string, n, count string) map[string]string {
+	gz := ""
+	if strings.HasSuffix(strings.ToLower(output), ".gz") {
+		gz = output[len(output)-3:]
+		output = output[:len(output)-3]
+	}
+	ext := filepath.Ext(output)
+	return map[string]string{
+		"stem":  strings.TrimSuffix(output, ext),
+		"ext":   strings.TrimPrefix(ext+gz, "."),
+		"n":     n,
+		"parts": count,
+	}
+}
+
+// partTemplate is the -part-name template, by default {stem}.part{n}.{ext}
+// (out.txt is numbered out.part1.txt, out.txt.gz out.part1.txt.gz, an
+// output without extension out.part1)
+func partTemplate(config *Options) string {
+	if config.PartName != "" {
+		return config.PartName
+	}
+	if partFields(config.Output, "", "")["ext"] == "" {
+		return "{stem}.part{n}"
+	}
+	return "{stem}.part{n}.{ext}"
+}
+
+// partName is the path of the nth of count parts of the output
+func partName(config *Options, n, count int) string {
+	// The template was checked by Validate
+	name, _ := expandPlaceholders(partTemplate(config), partFields(config.Output, strconv.Itoa(n), strconv.Itoa(count)))
+	return name
+}
+
+// isPartFile reports whether path is a part of the output, left by an
+// earlier run, so the new parts don't take it for an input
+func isPartFile(config *Options, path string) bool {
+	const number = "\x00"
+	name, err := expandPlaceholders(partTemplate(config), partFields(config.Output, number, number))
+	if err != nil {
+		return false
+	}
+	absName, _ := filepath.Abs(name)
+	absPath, _ := filepath.Abs(path)
+	pattern := "^" + strings.ReplaceAll(regexp.QuoteMeta(absName), number, `\d+`) + "$"
+	matched, _ := regexp.MatchString(pattern, absPath)
+	return matched
+}
+
+// validatePartName checks a -part-name template: known placeholders, and a
+// {n} so the parts get different names
+func validatePartName(tmpl string) error {
+	if _, err := expandPlaceholders(tmpl, partFields("out.txt", "1", "1")); err != nil {
+		return fmt.Errorf("invalid --part-name: %v", err)
+	}
+	if !strings.Contains(strings.ReplaceAll(tmpl, "{{", ""), "{n}") {
+		return errors.New("--part-name needs the part number {n}")
+	}
+	return nil
+}
+
+// withLineRange returns a copy of config that limits file to the range
+func withLineRange(config *Options, file string, r lineRange) *Options {
+	ranged := *config
+	ranged.LineRanges = make(map[string]lineRange, len(config.LineRanges)+1)
+	for path, existing := range config.LineRanges {
+		ranged.LineRanges[path] = existing
+	}
+	abs, _ := filepath.Abs(file)
+	ranged.LineRanges[abs] = r
+	return &ranged
+}
+
+// partOverhead returns a function measuring the header and footer of a part
+// holding items. They only depend on the items when the header lists the
+// files or a -header/-footer template may; otherwise they are measured once.
+func partOverhead(config *Options) func(items []partItem) outputTotals {
+	labeled := *config
+	labeled.part = "9999 of 9999" // the widest label the parts will get
+	measureItems := func(items []partItem) outputTotals {
+		paths := make([]string, len(items))
+		for i, item := range items {
+			paths[i] = item.path
+		}
+		return measure(config, []byte(createDocumentHeader(&labeled, paths)+createDocumentFooter(&labeled, paths)))
+	}
+	templated := strings.Contains(config.Header+config.Footer, "{{")
+	if config.TOC || config.EmbedManifest || config.Tree || templated {
+		return measureItems
+	}
+	fixed := measureItems(nil)
+	return func([]partItem) outputTotals { return fixed }
+}
+
+// planParts groups the files, in order, into parts that stay within the
+// limits, each a complete output with its own header and footer.
+// Files are never split across parts, unless one does not fit in a part of
+// its own; that file is cut into line ranges that each fill a part.
+func planParts(config *Options, files []string) [][]partItem {
+	overhead := partOverhead(config)
+	sections := sectionTotals(config, files)
+
+	var parts [][]partItem
+	var current []partItem
+	content := newTotals(config)
+	for idx, file := range files {
+		candidate := append(current[:len(current):len(current)], partItem{path: file})
+		next := content
+		next.add(sections[idx])
+		total := overhead(candidate)
+		total.add(next)
+		if partFits(config, total) {
+			current, content = candidate, next
+			continue
+		}
+		if len(current) > 0 {
+			parts = append(parts, current)
+			current, content = nil, newTotals(config)
+		}
+		if fitsAlone(config, overhead, file, sections[idx]) {
+			current, content = []partItem{{path: file}}, sections[idx]
+			continue
+		}
+
+		ranges, last := splitRanges(config, overhead, file)
+		if len(ranges) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %s does not fit in a part (%s) and cannot be split; it gets a part of its own\n", file, partLimits(config))
+			parts = append(parts, []partItem{{path: file}})
+			continue
+		}
+		for i := range ranges {
+			item := partItem{path: file, lines: &ranges[i]}
+			if i < len(ranges)-1 {
+				parts = append(parts, []partItem{item})
+			} else {
+				current, content = []partItem{item}, last
+			}
+		}
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// fitsAlone reports whether a file, with the given section totals, fits in
+// a part by itself
+func fitsAlone(config *Options, overhead func([]partItem) outputTotals, file string, section outputTotals) bool {
+	total := overhead([]partItem{{path: file}})
+	total.add(section)
+	return partFits(config, total)
+}
+
+// splitRanges cuts a file too big for any part into line ranges that each
+// fill a part of their own, as far as its lines allow. It also returns the
+// section totals of the last range, which later files may join. Binary
+// files are not split and give no ranges.
+func splitRanges(config *Options, overhead func([]partItem) outputTotals, file string) ([]lineRange, outputTotals) {
+	if config.isBinary(file) {
+		return nil, outputTotals{}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, outputTotals{}
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	// A path:start-end file is split within its range
+	first, end := 1, len(lines)
+	if abs, _ := filepath.Abs(file); config.LineRanges != nil {
+		if r, ok := config.LineRanges[abs]; ok {
+			first = r.Start
+			if r.End != 0 && r.End < end {
+				end = r.End
+			}
+		}
+	}
+	if first > end {
+		return nil, outputTotals{}
+	}
+
+	var ranges []lineRange
+	var section outputTotals
+	for start := first; start <= end; {
+		// The first line comes with the separator, the range marker and
+		// the part's header and footer; the rest are added while they fit
+		r := lineRange{Start: start, End: start}
+		section = sectionTotals(withLineRange(config, file, r), []string{file})[0]
+		total := overhead([]partItem{{path: file, lines: &r}})
+		total.add(section)
+		if !partFits(config, total) {
+			fmt.Fprintf(os.Stderr, "Warning: line %d of %s alone exceeds the part limit (%s)\n", start, file, partLimits(config))
+		}
+		for r.End < end {
+			next := total
+			next.add(measure(config, lines[r.End]))
+			if !partFits(config, next) {
+				break
+			}
+			total = next
+			section.add(measure(config, lines[r.End]))
+			r.End++
+		}
+		ranges = append(ranges, r)
+		start = r.End + 1
+	}
+	return ranges, section
+}
+
+// writeParts writes the files into numbered parts that each stay within the
+// limits, and returns the files written, those skipped due to errors
+// and the names of the parts
+func writeParts(config *Options, files []string) (int, int, []string, error) {
+	parts := planParts(config, files)
+	var names []string
+	successCount := 0
+	errorCount := 0
+	index := config.IndexOffset
+	var compressed int64
+	for n, items := range parts {
+		part := *config
+		part.Output = partName(config, n+1, len(parts))
+		part.part = fmt.Sprintf("%d of %d", n+1, len(parts))
+		part.IndexOffset = index
+		paths := make([]string, len(items))
+		for i, item := range items {
+			paths[i] = item.path
+			if item.lines != nil {
+				part.LineRanges = withLineRange(&part, item.path, *item.lines).LineRanges
+			}
+		}
+		if config.Verbose {
+			fmt.Printf("Writing part %d of %d: %s (%d files)\n", n+1, len(parts), part.Output, len(items))
+		}
+
+		written, failed, err := writePart(&part, paths)
+		if err != nil {
+			return successCount, errorCount, names, err
+		}
+		successCount += written
+		errorCount += failed
+		compressed += config.run.compressed
+		names = append(names, part.Output)
+		index += len(items)
+	}
+	config.run.compressed = compressed
+
+	// A file cut into ranges counts once
+	items := 0
+	for _, part := range parts {
+		items += len(part)
+	}
+	successCount -= items - len(files)
+	return successCount, errorCount, names, nil
+}
+
+// writePart writes one part to its file
+func writePart(config *Options, files []string) (int, int, error) {
+	outFile, err := createOutputFile(config.Output)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer outFile.Close()
+
+	writer := bufio.NewWriter(outFile)
+	signed, sign := signOutput(config, writer)
+	out, closeOut := compressOutput(config, signed)
+	successCount, errorCount := writeOutput(out, config, files)
+	if err := closeOut(); err != nil {
+		return successCount, errorCount, fmt.Errorf("Failed to compress %s: %v", config.Output, err)
+	}
+	if err := sign(); err != nil {
+		return successCount, errorCount, fmt.Errorf("Failed to sign %s: %v", config.Output, err)
+	}
+	if err := writer.Flush(); err != nil {
+		return successCount, errorCount, fmt.Errorf("Failed to write %s: %v", config.Output, err)
+	}
+	return successCount, errorCount, nil
+}
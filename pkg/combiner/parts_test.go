@@ -0,0 +1,217 @@
+package combiner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// runParts writes the output in parts and returns their contents in order
+func runParts(t *testing.T, config *Options) []string {
+	t.Helper()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	var err error
+	captureStderr(t, func() { _, err = New(config).Run(context.Background()) })
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	var parts []string
+	for n := 1; ; n++ {
+		data, err := os.ReadFile(partName(config, n, 0))
+		if err != nil {
+			break
+		}
+		parts = append(parts, string(data))
+	}
+	if len(parts) == 0 {
+		t.Fatal("no parts written")
+	}
+	return parts
+}
+
+// rangeMarker is the comment opening a section cut to a line range
+var rangeMarker = regexp.MustCompile(`^# lines \d+-\d+ of \d+\n`)
+
+// joinParts splits every part and joins the sections of each file, which
+// may be spread over several parts in line ranges
+func joinParts(parts []string) map[string]string {
+	files := make(map[string]string)
+	for _, part := range parts {
+		for _, section := range splitCombined([]byte(part)) {
+			files[section.Path] += rangeMarker.ReplaceAllString(string(section.Content), "")
+		}
+	}
+	return files
+}
+
+// numberedLines returns n lines starting with prefix
+func numberedLines(prefix string, n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%s line %d\n", prefix, i)
+	}
+	return b.String()
+}
+
+func TestChunkTokens(t *testing.T) {
+	files := map[string]string{
+		"a.txt":   numberedLines("a", 30),
+		"b.txt":   numberedLines("b", 30),
+		"c.txt":   numberedLines("c", 30),
+		"big.txt": numberedLines("big", 400),
+	}
+	root := writeTree(t, files)
+	tests := []struct {
+		name      string
+		estimator string
+		chunk     int64
+		setup     func(*Options)
+		parts     int
+		split     bool // whether big.txt is cut into line ranges
+	}{
+		{"one part", TOKENS_BYTES, 1 << 20, nil, 1, false},
+		{"whole files", TOKENS_BYTES, 1500, nil, 2, false},
+		{"big file in ranges", TOKENS_BYTES, 400, nil, 6, true},
+		{"with title and toc", TOKENS_BYTES, 400, func(config *Options) {
+			config.Title = "Project"
+			config.TOC = true
+		}, 6, true},
+		{"words", TOKENS_WORDS, 600, nil, 5, true},
+		{"cl100k", TOKENS_CL100K, 600, nil, 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.TokenEstimator = tt.estimator
+			config.ChunkTokens = tt.chunk
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			parts := runParts(t, config)
+			if len(parts) != tt.parts {
+				t.Errorf("%d parts, want %d", len(parts), tt.parts)
+			}
+			ranged := false
+			for n, part := range parts {
+				if tokens := measure(config, []byte(part)).tokens(config); tokens > tt.chunk {
+					t.Errorf("part %d is ~%d tokens, over --chunk-tokens %d", n+1, tokens, tt.chunk)
+				}
+				if len(parts) > 1 && !strings.Contains(part, fmt.Sprintf("Part %d of %d", n+1, len(parts))) {
+					t.Errorf("part %d is not labeled", n+1)
+				}
+				ranged = ranged || strings.Contains(part, " of 400\n")
+				for _, small := range []string{"a.txt", "b.txt", "c.txt"} {
+					if strings.Contains(part, ": "+small+"\n") && !strings.Contains(part, files[small]) {
+						t.Errorf("%s was split across parts", small)
+					}
+				}
+			}
+			if ranged != tt.split {
+				t.Errorf("big.txt cut into ranges = %v, want %v", ranged, tt.split)
+			}
+			for name, content := range joinParts(parts) {
+				if content != files[name] {
+					t.Errorf("%s does not join back from the parts", name)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitSizeAndLines(t *testing.T) {
+	files := map[string]string{
+		"a.txt":   numberedLines("a", 20),
+		"b.txt":   numberedLines("b", 20),
+		"c.txt":   numberedLines("c", 20),
+		"big.txt": numberedLines("big", 200),
+	}
+	root := writeTree(t, files)
+	tests := []struct {
+		name  string
+		size  int64
+		lines int64
+	}{
+		{"bytes", 1024, 0},
+		{"lines", 0, 60},
+		{"both", 2048, 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*.txt")
+			config.SplitSize = tt.size
+			config.SplitLines = tt.lines
+			parts := runParts(t, config)
+			if len(parts) < 2 {
+				t.Fatalf("got %d parts, want several", len(parts))
+			}
+			for n, part := range parts {
+				totals := measure(config, []byte(part))
+				if tt.size > 0 && totals.Bytes > tt.size {
+					t.Errorf("part %d is %d bytes, over --split-size %d", n+1, totals.Bytes, tt.size)
+				}
+				if tt.lines > 0 && totals.Lines > tt.lines {
+					t.Errorf("part %d has %d lines, over --split-lines %d", n+1, totals.Lines, tt.lines)
+				}
+			}
+			joined := joinParts(parts)
+			for name, content := range files {
+				if joined[name] != content {
+					t.Errorf("%s does not join back from the parts", name)
+				}
+			}
+		})
+	}
+}
+
+func TestPartName(t *testing.T) {
+	tests := []struct {
+		output   string
+		template string
+		want     string
+	}{
+		{"out.txt", "", "out.part3.txt"},
+		{"dir/out.md", "", "dir/out.part3.md"},
+		{"out.txt.gz", "", "out.part3.txt.gz"},
+		{"bundle", "", "bundle.part3"},
+		{"out.txt", "{stem}-{n}-of-{parts}.{ext}", "out-3-of-7.txt"},
+		{"out.txt", "chunks/{n}.{ext}", "chunks/3.txt"},
+	}
+	for _, tt := range tests {
+		config := DefaultOptions()
+		config.Output = tt.output
+		config.PartName = tt.template
+		got := partName(config, 3, 7)
+		if got != tt.want {
+			t.Errorf("partName(%q, %q) = %q, want %q", tt.output, tt.template, got, tt.want)
+		}
+		if !isPartFile(config, got) {
+			t.Errorf("isPartFile(%q) = false for its own part", got)
+		}
+		if isPartFile(config, tt.output) {
+			t.Errorf("isPartFile(%q) = true for the output itself", tt.output)
+		}
+	}
+}
+
+func TestValidatePartName(t *testing.T) {
+	tests := []struct {
+		template string
+		ok       bool
+	}{
+		{"{stem}.part{n}.{ext}", true},
+		{"{n}", true},
+		{"{stem}.{ext}", false},
+		{"{{n}}.txt", false},
+		{"{stem}.{bogus}.{n}", false},
+	}
+	for _, tt := range tests {
+		if err := validatePartName(tt.template); (err == nil) != tt.ok {
+			t.Errorf("validatePartName(%q) = %v, want ok=%v", tt.template, err, tt.ok)
+		}
+	}
+}
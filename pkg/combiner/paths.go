@@ -0,0 +1,18 @@
+package combiner
+
+import "strings"
+
+// collapsePath shortens a slash-separated display path by keeping its first
+// depth directories and the base name, replacing the directories in between
+// with a single "…". A depth of 0 leaves the path untouched.
+func collapsePath(relPath string, depth int) string {
+	if depth <= 0 {
+		return relPath
+	}
+	parts := strings.Split(relPath, "/")
+	if len(parts)-1 <= depth {
+		return relPath
+	}
+	kept := append(parts[:depth:depth], "…", parts[len(parts)-1])
+	return strings.Join(kept, "/")
+}
@@ -0,0 +1,35 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollapsePath(t *testing.T) {
+	tests := []struct {
+		path  string
+		depth int
+		want  string
+	}{
+		{"a/b/c/d/e.go", 0, "a/b/c/d/e.go"},
+		{"a/b/c/d/e.go", 1, "a/…/e.go"},
+		{"a/b/c/d/e.go", 2, "a/b/…/e.go"},
+		{"a/b/c/d/e.go", 4, "a/b/c/d/e.go"},
+		{"a/b.go", 1, "a/b.go"},
+		{"b.go", 1, "b.go"},
+	}
+	for _, tt := range tests {
+		if got := collapsePath(tt.path, tt.depth); got != tt.want {
+			t.Errorf("collapsePath(%q, %d) = %q, want %q", tt.path, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestCollapsedSeparators(t *testing.T) {
+	root := writeTree(t, map[string]string{"src/internal/deep/pkg/a.go": "package pkg\n"})
+	config := testOptions(t, root, "**/*.go")
+	config.CollapseDepth = 1
+	if out := combine(t, config); !strings.Contains(out, "FILE 1: src/…/a.go\n") {
+		t.Errorf("separator path not collapsed:\n%s", out)
+	}
+}
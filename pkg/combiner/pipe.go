@@ -0,0 +1,47 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// pipeToCommand starts command through the system shell and streams the
+// combined output into its stdin. It returns the written and failed file
+// counts, plus an error when the command could not run or exited non-zero.
+func pipeToCommand(config *Options, files []string, command string) (int, int, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stdout = config.stdout()
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, 0, fmt.Errorf("cannot start %q: %v", command, err)
+	}
+
+	writer := bufio.NewWriter(stdin)
+	successCount, errorCount := writeOutput(writer, config, files)
+	writer.Flush()
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return successCount, errorCount, fmt.Errorf("command %q exited with status %d", command, exitErr.ExitCode())
+		}
+		return successCount, errorCount, fmt.Errorf("command %q failed: %v", command, err)
+	}
+	if config.Verbose {
+		fmt.Printf("Command %q exited with status 0\n", command)
+	}
+	return successCount, errorCount, nil
+}
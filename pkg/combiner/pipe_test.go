@@ -0,0 +1,68 @@
+package combiner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPipeTo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands below need a POSIX shell")
+	}
+	if _, err := exec.LookPath("wc"); err != nil {
+		t.Skip("wc is not available")
+	}
+	root := writeTree(t, map[string]string{
+		"a.txt":     "alpha\n",
+		"sub/b.txt": strings.Repeat("beta\n", 1000),
+	})
+	want := combine(t, testOptions(t, root, "**/*.txt"))
+
+	tests := []struct {
+		name    string
+		command string
+		stdout  string
+		status  int // 0 when the command succeeds
+	}{
+		{"byte count", "wc -c", strconv.Itoa(len(want)), 0},
+		{"content", "cat", want, 0},
+		{"exit status", "cat >/dev/null; exit 3", "", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.txt")
+			config.Output = ""
+			config.PipeTo = tt.command
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			config.Stdout = &out
+			var err error
+			stderr := captureStderr(t, func() { _, err = New(config).Run(context.Background()) })
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatalf("Run: %v\n%s", err, stderr)
+				}
+				if got := strings.TrimSpace(out.String()); got != strings.TrimSpace(tt.stdout) {
+					t.Errorf("command printed %q, want %q", got, tt.stdout)
+				}
+				return
+			}
+			var exit *ExitError
+			if !errors.As(err, &exit) || exit.Code != 2 {
+				t.Errorf("err = %v, want exit code 2", err)
+			}
+			if want := "exited with status " + strconv.Itoa(tt.status); !strings.Contains(stderr, want) {
+				t.Errorf("stderr missing %q:\n%s", want, stderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// expandPlaceholders replaces {name} placeholders in tmpl with values from
+// fields. An unknown placeholder is reported as an error so typos don't end
+// up verbatim in the output. Literal braces can be written as {{ and }}.
+func expandPlaceholders(tmpl string, fields map[string]string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		if c == '{' && i+1 < len(tmpl) && tmpl[i+1] == '{' {
+			sb.WriteByte('{')
+			i++
+			continue
+		}
+		if c == '}' && i+1 < len(tmpl) && tmpl[i+1] == '}' {
+			sb.WriteByte('}')
+			i++
+			continue
+		}
+		if c != '{' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		end := strings.IndexByte(tmpl[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated placeholder in %q", tmpl)
+		}
+		name := tmpl[i+1 : i+end]
+		val, ok := fields[name]
+		if !ok {
+			return "", fmt.Errorf("unknown placeholder {%s}", name)
+		}
+		sb.WriteString(val)
+		i += end
+	}
+	return sb.String(), nil
+}
+
+// fileFields returns the placeholder values available for a single file
+func fileFields(path, root string, index int) map[string]string {
+	relPath, _ := filepath.Rel(root, path)
+	absPath, _ := filepath.Abs(path)
+	return map[string]string{
+		"index":   strconv.Itoa(index),
+		"path":    filepath.ToSlash(relPath),
+		"abspath": absPath,
+		"name":    filepath.Base(path),
+		"ext":     strings.TrimPrefix(filepath.Ext(path), "."),
+	}
+}
+
+// UnescapeTemplate turns the \n and \t escapes typed on the command line into real characters
+func UnescapeTemplate(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(s)
+}
+
+// separatorFields extends fileFields with the values only -separator-format
+// offers: size in bytes, modification time and a short content hash
+func separatorFields(path, root string, index int) map[string]string {
+	fields := fileFields(path, root, index)
+	fields["size"], fields["mtime"], fields["hash"] = "", "", ""
+	if info, err := os.Stat(path); err == nil {
+		fields["size"] = strconv.FormatInt(info.Size(), 10)
+		fields["mtime"] = info.ModTime().Format("2006-01-02 15:04:05")
+	}
+	if hash, err := hashFile(path); err == nil {
+		fields["hash"] = hash[:12]
+	}
+	return fields
+}
+
+// renderSeparatorFormat expands a -separator-format template and frames
+// its lines in the comment style of the file it introduces
+func renderSeparatorFormat(tmpl string, fields map[string]string, style CommentStyle) (string, error) {
+	text, err := expandPlaceholders(tmpl, fields)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(text, "\n")
+
+	var sb strings.Builder
+	sb.WriteString("\n")
+	switch {
+	case style.BlockStart != "" && style.BlockEnd != "":
+		sb.WriteString(style.BlockStart + "\n")
+		for _, line := range lines {
+			sb.WriteString(" " + line + "\n")
+		}
+		sb.WriteString(style.BlockEnd + "\n")
+	case style.SingleLine != "":
+		for _, line := range lines {
+			sb.WriteString(style.SingleLine + " " + line + "\n")
+		}
+	default:
+		sb.WriteString(text + "\n")
+	}
+	sb.WriteString("\n")
+	return sb.String(), nil
+}
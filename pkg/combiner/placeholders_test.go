@@ -0,0 +1,74 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandPlaceholders(t *testing.T) {
+	fields := map[string]string{"path": "src/a.go", "index": "3", "ext": "go"}
+	tests := []struct {
+		tmpl string
+		want string
+		err  bool
+	}{
+		{"// begin {path}", "// begin src/a.go", false},
+		{"[{index}] {path} ({ext})", "[3] src/a.go (go)", false},
+		{"{{literal}} {path}", "{literal} src/a.go", false},
+		{"no placeholders", "no placeholders", false},
+		{"{unknown}", "", true},
+		{"{path", "", true},
+	}
+	for _, tt := range tests {
+		got, err := expandPlaceholders(tt.tmpl, fields)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("expandPlaceholders(%q) = %q, %v; want %q, error %v", tt.tmpl, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestContentPrefixSuffix(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n", "sub/b.py": "print()"})
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{FORMAT_TEXT, []string{
+			"\n\n<<< 1 a.go\npackage a\n>>> a.go\n",
+			"\n\n<<< 2 sub/b.py\nprint()\n>>> b.py\n",
+		}},
+		{FORMAT_MARKDOWN, []string{
+			"```go\n<<< 1 a.go\npackage a\n>>> a.go\n```\n",
+			"```python\n<<< 2 sub/b.py\nprint()\n>>> b.py\n```\n",
+		}},
+		{FORMAT_XML, []string{
+			"<file path=\"a.go\">\n<<< 1 a.go\npackage a\n>>> a.go\n</file>\n",
+			"<file path=\"sub/b.py\">\n<<< 2 sub/b.py\nprint()\n>>> b.py\n</file>\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.Format = tt.format
+			config.ContentPrefix = "<<< {index} {path}"
+			config.ContentSuffix = ">>> {name}"
+			out := combine(t, config)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output lacks %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestContentSuffixFence(t *testing.T) {
+	// A suffix with backticks gets a longer fence, as content would
+	root := writeTree(t, map[string]string{"a.go": "package a\n"})
+	config := testOptions(t, root, "*.go")
+	config.Format = FORMAT_MARKDOWN
+	config.ContentSuffix = "```end```"
+	if out := combine(t, config); !strings.Contains(out, "````go\npackage a\n```end```\n````\n") {
+		t.Errorf("output:\n%s", out)
+	}
+}
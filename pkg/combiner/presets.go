@@ -0,0 +1,51 @@
+package combiner
+
+import (
+	"path"
+	"strings"
+)
+
+// Selection presets for -code-only and -docs-only
+const (
+	PRESET_CODE = "code"
+	PRESET_DOCS = "docs"
+)
+
+var (
+	// docFilePatterns are matched case-insensitively against base names
+	docFilePatterns = []string{"*.md", "*.markdown", "*.rst", "*.txt", "*.adoc", "license*", "licence*", "copying*"}
+	// docDirs mark every file below them as documentation
+	docDirs = []string{"docs", "doc"}
+)
+
+// isDocFile reports whether a slash-separated relative path is documentation
+func isDocFile(relPath string) bool {
+	parts := strings.Split(strings.ToLower(relPath), "/")
+	for _, dir := range parts[:len(parts)-1] {
+		if containsString(docDirs, dir) {
+			return true
+		}
+	}
+	base := parts[len(parts)-1]
+	for _, pattern := range docFilePatterns {
+		if matched, _ := path.Match(pattern, base); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// presetSkipReason returns why the preset drops relPath, or "" to keep it
+func presetSkipReason(preset, relPath string) string {
+	switch preset {
+	case PRESET_CODE:
+		if isDocFile(relPath) {
+			return "Documentation (--code-only)"
+		}
+	case PRESET_DOCS:
+		if !isDocFile(relPath) {
+			return "Not documentation (--docs-only)"
+		}
+	}
+	return ""
+}
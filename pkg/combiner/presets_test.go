@@ -0,0 +1,74 @@
+package combiner
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPresetSkipReason(t *testing.T) {
+	tests := []struct {
+		path string
+		code bool // kept by -code-only
+	}{
+		{"main.go", true},
+		{"README.md", false},
+		{"CHANGES.rst", false},
+		{"LICENSE", false},
+		{"notes.txt", false},
+		{"docs/api.go", false},
+		{"Doc/setup.py", false},
+		{"src/doctor.go", true},
+		{"web/index.html", true},
+	}
+	for _, tt := range tests {
+		if kept := presetSkipReason(PRESET_CODE, tt.path) == ""; kept != tt.code {
+			t.Errorf("-code-only keeps %s = %v, want %v", tt.path, kept, tt.code)
+		}
+		if kept := presetSkipReason(PRESET_DOCS, tt.path) == ""; kept == tt.code {
+			t.Errorf("-docs-only keeps %s = %v, want %v", tt.path, kept, !tt.code)
+		}
+	}
+	if reason := presetSkipReason("", "README.md"); reason != "" {
+		t.Errorf("no preset skipped README.md: %s", reason)
+	}
+}
+
+func TestPresetSelection(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":        "package main\n",
+		"README.md":      "# r\n",
+		"LICENSE":        "MIT\n",
+		"docs/guide.go":  "package docs\n",
+		"src/doctor.go":  "package src\n",
+		"src/notes.txt":  "notes\n",
+		"web/index.html": "<p>hi</p>\n",
+	})
+	tests := []struct {
+		preset string
+		want   []string
+		reason string
+	}{
+		{"", []string{"LICENSE", "README.md", "docs/guide.go", "main.go", "src/doctor.go", "src/notes.txt", "web/index.html"}, ""},
+		{PRESET_CODE, []string{"main.go", "src/doctor.go", "web/index.html"}, "Documentation (--code-only)"},
+		{PRESET_DOCS, []string{"LICENSE", "README.md", "docs/guide.go", "src/notes.txt"}, "Not documentation (--docs-only)"},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "**/*")
+		config.Preset = tt.preset
+		report, err := New(config).Select(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := relFiles(t, root, report.Files); !sameStrings(got, tt.want) {
+			t.Errorf("preset %q: got %q, want %q", tt.preset, got, tt.want)
+		}
+		if len(report.Files)+len(report.Skipped) != 7 {
+			t.Errorf("preset %q: %d files and %d skipped, want 7 in all", tt.preset, len(report.Files), len(report.Skipped))
+		}
+		for _, file := range report.Skipped {
+			if file.Reason != tt.reason {
+				t.Errorf("preset %q: %s skipped with %q, want %q", tt.preset, file.Path, file.Reason, tt.reason)
+			}
+		}
+	}
+}
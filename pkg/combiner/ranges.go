@@ -0,0 +1,92 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// lineRange limits a file to lines Start..End (1-based, inclusive); End 0 means to the end
+type lineRange struct {
+	Start int
+	End   int
+}
+
+func (r lineRange) String() string {
+	if r.End == 0 {
+		return fmt.Sprintf("%d-", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+var lineRangeSuffix = regexp.MustCompile(`^(.+):(\d+)-(\d*)$`)
+
+// splitLineRange separates a "path:start-end" pattern into the path and its range
+func splitLineRange(pattern string) (string, lineRange, bool, error) {
+	m := lineRangeSuffix.FindStringSubmatch(pattern)
+	if m == nil {
+		return pattern, lineRange{}, false, nil
+	}
+	r := lineRange{}
+	r.Start, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		r.End, _ = strconv.Atoi(m[3])
+	}
+	if r.Start < 1 || (r.End != 0 && r.End < r.Start) {
+		return "", r, false, fmt.Errorf("invalid line range in %s", pattern)
+	}
+	return m[1], r, true, nil
+}
+
+// ExtractLineRanges strips ":start-end" suffixes from the patterns and records
+// each range under the absolute paths the pattern may resolve to (relative to
+// the working directory or to the root)
+func ExtractLineRanges(config *Options) error {
+	for i, pattern := range config.Patterns {
+		path, r, ok, err := splitLineRange(pattern)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if config.LineRanges == nil {
+			config.LineRanges = make(map[string]lineRange)
+		}
+		config.Patterns[i] = path
+		for _, candidate := range []string{path, filepath.Join(config.Root, path)} {
+			if abs, err := filepath.Abs(candidate); err == nil {
+				config.LineRanges[abs] = r
+			}
+		}
+	}
+	return nil
+}
+
+// applyLineRange cuts content down to the range. It also returns a comment
+// line, in the file's comment style, noting which lines were kept.
+func applyLineRange(filePath string, content []byte, r lineRange) ([]byte, []byte, error) {
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if r.Start > len(lines) {
+		return nil, nil, fmt.Errorf("line range %s starts after the end of the file (%d lines)", r, len(lines))
+	}
+	end := r.End
+	if end == 0 || end > len(lines) {
+		end = len(lines)
+	}
+
+	marker := fmt.Sprintf("lines %d-%d of %d", r.Start, end, len(lines))
+	style := getCommentStyle(filePath)
+	if style.SingleLine != "" {
+		marker = style.SingleLine + " " + marker
+	} else {
+		marker = style.BlockStart + " " + marker + " " + style.BlockEnd
+	}
+
+	return []byte(marker + "\n"), bytes.Join(lines[r.Start-1:end], nil), nil
+}
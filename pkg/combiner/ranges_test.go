@@ -0,0 +1,64 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitLineRange(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		r       lineRange
+		ok      bool
+		err     bool
+	}{
+		{"main.go:10-40", "main.go", lineRange{10, 40}, true, false},
+		{"main.go:10-", "main.go", lineRange{10, 0}, true, false},
+		{"src/a.go:1-1", "src/a.go", lineRange{1, 1}, true, false},
+		{"main.go", "main.go", lineRange{}, false, false},
+		{"C:/x.go", "C:/x.go", lineRange{}, false, false},
+		{"main.go:0-3", "", lineRange{0, 3}, false, true},
+		{"main.go:5-3", "", lineRange{5, 3}, false, true},
+	}
+	for _, tt := range tests {
+		path, r, ok, err := splitLineRange(tt.pattern)
+		if path != tt.path || r != tt.r || ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("splitLineRange(%q) = %q, %v, %v, %v", tt.pattern, path, r, ok, err)
+		}
+	}
+}
+
+func TestApplyLineRange(t *testing.T) {
+	content := []byte("1\n2\n3\n4\n5\n")
+	tests := []struct {
+		path   string
+		r      lineRange
+		want   string
+		marker string
+		err    bool
+	}{
+		{"a.go", lineRange{2, 3}, "2\n3\n", "// lines 2-3 of 5\n", false},
+		{"a.py", lineRange{4, 0}, "4\n5\n", "# lines 4-5 of 5\n", false},
+		{"a.css", lineRange{1, 99}, "1\n2\n3\n4\n5\n", "/* lines 1-5 of 5 */\n", false},
+		{"a.go", lineRange{6, 0}, "", "", true},
+	}
+	for _, tt := range tests {
+		marker, got, err := applyLineRange(tt.path, content, tt.r)
+		if string(got) != tt.want || string(marker) != tt.marker || (err != nil) != tt.err {
+			t.Errorf("applyLineRange(%s, %v) = %q, %q, %v", tt.path, tt.r, marker, got, err)
+		}
+	}
+}
+
+func TestLineRangeOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n\nfunc A() {}\n\nfunc B() {}\n"})
+	config := testOptions(t, root, "a.go:3-3")
+	if err := ExtractLineRanges(config); err != nil {
+		t.Fatal(err)
+	}
+	out := combine(t, config)
+	if !strings.Contains(out, "// lines 3-3 of 5\nfunc A() {}\n") || strings.Contains(out, "func B") {
+		t.Errorf("output does not hold just line 3:\n%s", out)
+	}
+}
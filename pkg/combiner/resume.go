@@ -0,0 +1,128 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	manifestStartLine = regexp.MustCompile(`Manifest: (\d+) files$`)
+	manifestEntryLine = regexp.MustCompile(`^\S*\s+#(\d+) (.+)$`)
+)
+
+// manifestLines lists the selected files for -embed-manifest, so that -resume
+// can later tell whether a partial output belongs to the same selection
+func manifestLines(config *Options, files []string) []string {
+	lines := []string{fmt.Sprintf("Manifest: %d files", len(files))}
+	for idx, file := range files {
+		lines = append(lines, fmt.Sprintf("  #%d %s", idx+1, manifestPath(config, file)))
+	}
+	return lines
+}
+
+func manifestPath(config *Options, file string) string {
+	relPath, _ := filepath.Rel(config.Root, file)
+	return filepath.ToSlash(relPath)
+}
+
+// readManifest returns the file paths recorded by -embed-manifest in data,
+// or nil if it has none
+func readManifest(data []byte) []string {
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		m := manifestStartLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if m == nil {
+			continue
+		}
+		count, _ := strconv.Atoi(m[1])
+		paths := make([]string, 0, count)
+		for _, entry := range lines[i+1:] {
+			e := manifestEntryLine.FindStringSubmatch(strings.TrimRight(entry, "\r"))
+			if e == nil || len(paths) == count {
+				break
+			}
+			paths = append(paths, e[2])
+		}
+		if len(paths) == count {
+			return paths
+		}
+	}
+	return nil
+}
+
+// resumePoint works out how much of an interrupted output can be kept. It
+// returns the number of files that are already complete and the byte offset
+// at which writing continues. The last section found may have been cut off,
+// so it is always written again.
+func resumePoint(config *Options, files []string, data []byte) (int, int64, error) {
+	manifest := readManifest(data)
+	if manifest == nil {
+		return 0, 0, fmt.Errorf("%s has no embedded manifest to resume from", config.Output)
+	}
+	if len(manifest) != len(files) {
+		return 0, 0, fmt.Errorf("file selection changed since %s was started (%d files, now %d)", config.Output, len(manifest), len(files))
+	}
+	for idx, file := range files {
+		if manifest[idx] != manifestPath(config, file) {
+			return 0, 0, fmt.Errorf("file selection changed since %s was started (#%d was %s, now %s)",
+				config.Output, idx+1, manifest[idx], manifestPath(config, file))
+		}
+	}
+
+	// Without a complete section there is nothing worth keeping
+	sections := splitCombined(data)
+	if len(sections) == 0 || sections[len(sections)-1].Index <= 1 {
+		return 0, 0, nil
+	}
+	last := sections[len(sections)-1]
+	if last.Index > len(files) {
+		return 0, 0, fmt.Errorf("unexpected FILE %d in %s", last.Index, config.Output)
+	}
+	return last.Index - 1, int64(last.Start), nil
+}
+
+// resumeOutput continues writing an output interrupted during an earlier
+// -resume or -embed-manifest run. A missing output is simply written from scratch.
+func resumeOutput(config *Options, files []string) (int, int, error) {
+	data, err := os.ReadFile(config.Output)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, 0, fmt.Errorf("Cannot read %s: %v", config.Output, err)
+	}
+
+	done, offset := 0, int64(0)
+	if len(data) > 0 {
+		if done, offset, err = resumePoint(config, files, data); err != nil {
+			return 0, 0, err
+		}
+	}
+	if done > 0 {
+		fmt.Printf("Resuming after %d of %d files\n", done, len(files))
+	}
+
+	outFile, err := os.OpenFile(config.Output, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Cannot open output file: %v", err)
+	}
+	defer outFile.Close()
+	if err := outFile.Truncate(offset); err != nil {
+		return 0, 0, fmt.Errorf("Cannot truncate output file: %v", err)
+	}
+	if _, err := outFile.Seek(offset, 0); err != nil {
+		return 0, 0, fmt.Errorf("Cannot seek output file: %v", err)
+	}
+
+	writer := bufio.NewWriter(outFile)
+	resumed := *config
+	resumed.IndexOffset = done
+	resumed.Continuing = offset > 0
+	successCount, errorCount := writeOutput(writer, &resumed, files[done:])
+	if err := writer.Flush(); err != nil {
+		return successCount, errorCount, fmt.Errorf("Failed to write combined content to file: %v", err)
+	}
+	return done + successCount, errorCount, nil
+}
@@ -0,0 +1,62 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResume(t *testing.T) {
+	files := map[string]string{
+		"a.txt": strings.Repeat("a", 100) + "\n",
+		"b.go":  "package b\n",
+		"c.py":  strings.Repeat("print()\n", 20),
+		"d.md":  "# d\n",
+	}
+	root := writeTree(t, files)
+	full := testOptions(t, root, "*")
+	full.EmbedManifest = true
+	want := combine(t, full)
+
+	tests := []struct {
+		name string
+		cut  func(string) string
+	}{
+		{"cut inside the third file", func(s string) string { return s[:strings.Index(s, "print()")+30] }},
+		{"cut inside a separator", func(s string) string { return s[:strings.Index(s, "FILE 4:")] }},
+		{"complete", func(s string) string { return s }},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*")
+			config.Resume = true
+			config.EmbedManifest = true // as -resume sets it
+			if tt.cut != nil {
+				if err := os.WriteFile(config.Output, []byte(tt.cut(want)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := combine(t, config); got != want {
+				t.Errorf("resumed output differs:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestResumeSelectionChanged(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"})
+	config := testOptions(t, root, "*.txt")
+	config.EmbedManifest = true
+	data := []byte(combine(t, config))
+
+	changed := testOptions(t, root, "a.txt", "c.txt")
+	files := []string{filepath.Join(root, "a.txt"), filepath.Join(root, "c.txt")}
+	if _, _, err := resumePoint(changed, files, data); err == nil {
+		t.Error("resumed although the selection changed")
+	}
+	if _, _, err := resumePoint(changed, files, data[:20]); err == nil {
+		t.Error("resumed an output whose manifest was cut off")
+	}
+}
@@ -0,0 +1,124 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ResolveRoots makes the common parent directory of several -root
+// directories the Root, so that every path in the output starts with the
+// root it came from (backend/..., shared/proto/...) and files from
+// different roots can't collide. The roots are kept relative to the working
+// directory when their parent is inside it.
+func ResolveRoots(config *Options) error {
+	if len(config.Roots) < 2 {
+		return nil
+	}
+	absRoots := make([]string, len(config.Roots))
+	for i, root := range config.Roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return fmt.Errorf("invalid --root %s: %v", root, err)
+		}
+		absRoots[i] = abs
+	}
+
+	parent := absRoots[0]
+	for _, root := range absRoots[1:] {
+		for !withinDir(parent, root) {
+			up := filepath.Dir(parent)
+			if up == parent {
+				return fmt.Errorf("the roots %s have no common parent directory", strings.Join(config.Roots, ", "))
+			}
+			parent = up
+		}
+	}
+
+	// Relative paths read better in the output, when they don't climb out
+	// of the working directory
+	if wd, err := os.Getwd(); err == nil && withinDir(wd, parent) {
+		parent, _ = filepath.Rel(wd, parent)
+		for i, root := range absRoots {
+			absRoots[i], _ = filepath.Rel(wd, root)
+		}
+	}
+	config.Root = parent
+	config.Roots = absRoots
+	return nil
+}
+
+// beyondMaxDepth reports whether a slash-separated path relative to the
+// root lies deeper than -max-depth allows. A file directly in the root is at
+// depth 1; a directory, given with a trailing slash, is too deep when
+// nothing inside it is allowed.
+func beyondMaxDepth(config *Options, relPath string) bool {
+	if config.MaxDepth <= 0 || strings.HasPrefix(relPath, "../") {
+		return false
+	}
+	return strings.Count(relPath, "/")+1 > config.MaxDepth
+}
+
+// isHidden reports whether a file or directory name is a dotfile
+func isHidden(name string) bool {
+	return len(name) > 1 && name[0] == '.' && name != ".."
+}
+
+// hiddenPath reports whether a slash-separated path relative to the root is
+// a dotfile or lies in a dot-directory
+func hiddenPath(relPath string) bool {
+	for _, segment := range strings.Split(relPath, "/") {
+		if isHidden(segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// withinDir reports whether path is dir or below it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// searchRoots are the directories files are taken from: the -root
+// directories of a multi-root run, or the root
+func searchRoots(config *Options) []string {
+	if len(config.Roots) > 1 {
+		return config.Roots
+	}
+	return []string{config.Root}
+}
+
+// outsideRoots reports whether a directory below the common parent of
+// several -root directories can hold none of their files: it is neither one
+// of them, below one, nor on the way down to one. The walks from the common
+// parent skip such directories.
+func outsideRoots(config *Options, dir string) bool {
+	if len(config.Roots) < 2 {
+		return false
+	}
+	abs, _ := filepath.Abs(dir)
+	for _, root := range config.Roots {
+		absRoot, _ := filepath.Abs(root)
+		if withinDir(absRoot, abs) || withinDir(abs, absRoot) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRoots reports whether a file lies below one of the -root directories
+func inRoots(config *Options, path string) bool {
+	if len(config.Roots) < 2 {
+		return true
+	}
+	abs, _ := filepath.Abs(path)
+	for _, root := range config.Roots {
+		if absRoot, _ := filepath.Abs(root); withinDir(absRoot, abs) {
+			return true
+		}
+	}
+	return false
+}
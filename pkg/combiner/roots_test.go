@@ -0,0 +1,215 @@
+package combiner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveRoots(t *testing.T) {
+	parent := t.TempDir()
+	config := DefaultOptions()
+	config.Roots = []string{filepath.Join(parent, "backend"), filepath.Join(parent, "shared", "proto")}
+	if err := ResolveRoots(config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Root != parent {
+		t.Errorf("Root = %q, want the common parent %q", config.Root, parent)
+	}
+
+	single := DefaultOptions()
+	single.Root = "src"
+	single.Roots = []string{"src"}
+	if err := ResolveRoots(single); err != nil || single.Root != "src" {
+		t.Errorf("single root: Root = %q, %v", single.Root, err)
+	}
+}
+
+func TestMultiRootSelection(t *testing.T) {
+	parent := writeTree(t, map[string]string{
+		".gitignore":               "*.log\n",
+		"backend/main.go":          "package main\n",
+		"backend/debug.log":        "log\n",
+		"backend/internal/db.go":   "package internal\n",
+		"shared/proto/api.go":      "package proto\n",
+		"shared/proto/gen/api.go":  "package gen\n",
+		"shared/other/skip.go":     "package other\n",
+		"frontend/app.go":          "package frontend\n",
+		"backend/vendor/x/x.go":    "package x\n",
+		"shared/proto/deep/a/b.go": "package b\n",
+	})
+	tests := []struct {
+		name     string
+		patterns []string
+		setup    func(*Options)
+		want     []string
+	}{
+		{"only the roots", []string{"**/*.go"}, nil, []string{
+			"backend/internal/db.go", "backend/main.go", "backend/vendor/x/x.go",
+			"shared/proto/api.go", "shared/proto/deep/a/b.go", "shared/proto/gen/api.go",
+		}},
+		{"patterns relative to the parent", []string{"shared/proto/*.go", "backend/*.go"}, nil, []string{
+			"backend/main.go", "shared/proto/api.go",
+		}},
+		{"excludes relative to the parent", []string{"**/*.go"}, func(config *Options) {
+			config.Excludes = []string{"shared/proto/gen", "vendor", "deep"}
+		}, []string{"backend/internal/db.go", "backend/main.go", "shared/proto/api.go"}},
+		{"depth counted from the parent", []string{"**/*.go"}, func(config *Options) {
+			config.MaxDepth = 3
+		}, []string{"backend/internal/db.go", "backend/main.go", "shared/proto/api.go"}},
+		{"the parent's .gitignore applies", []string{"**/*"}, func(config *Options) {
+			config.Excludes = []string{"*.go"}
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, parent, tt.patterns...)
+			config.Roots = []string{filepath.Join(parent, "backend"), filepath.Join(parent, "shared", "proto")}
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			if err := ResolveRoots(config); err != nil {
+				t.Fatal(err)
+			}
+			if got := selectRel(t, config); !sameStrings(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMultiRootSeparators(t *testing.T) {
+	parent := writeTree(t, map[string]string{
+		"backend/main.go":      "package main\n",
+		"tools/main.go":        "package main // tools\n",
+		"shared/proto/api.txt": "api\n",
+	})
+	tests := []struct {
+		name   string
+		roots  []string
+		format string
+		want   []string
+	}{
+		{"text", []string{"backend", "tools"}, FORMAT_TEXT, []string{" FILE 1: backend/main.go\n", " FILE 2: tools/main.go\n"}},
+		{"nested root", []string{"backend", "shared/proto"}, FORMAT_TEXT, []string{" FILE 1: backend/main.go\n", "# FILE 2: shared/proto/api.txt\n"}},
+		{"markdown", []string{"backend", "tools"}, FORMAT_MARKDOWN, []string{"### backend/main.go\n", "### tools/main.go\n"}},
+		{"xml", []string{"backend", "tools"}, FORMAT_XML, []string{`<file path="backend/main.go">`, `<file path="tools/main.go">`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, parent, "**/*")
+			config.Format = tt.format
+			for _, root := range tt.roots {
+				config.Roots = append(config.Roots, filepath.Join(parent, filepath.FromSlash(root)))
+			}
+			if err := ResolveRoots(config); err != nil {
+				t.Fatal(err)
+			}
+			out := combine(t, config)
+			for _, want := range tt.want {
+				if strings.Count(out, want) != 1 {
+					t.Errorf("output has %q %d times:\n%s", want, strings.Count(out, want), out)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":         "package a\n",
+		"b/b.go":       "package b\n",
+		"b/c/c.go":     "package c\n",
+		"b/c/d/d.go":   "package d\n",
+		"b/c/d/e/e.go": "package e\n",
+	})
+	tests := []struct {
+		depth     int
+		patterns  []string
+		recursive bool
+		want      []string
+	}{
+		{0, []string{"**/*.go"}, false, []string{"a.go", "b/b.go", "b/c/c.go", "b/c/d/d.go", "b/c/d/e/e.go"}},
+		{1, []string{"**/*.go"}, false, []string{"a.go"}},
+		{2, []string{"**/*.go"}, false, []string{"a.go", "b/b.go"}},
+		{3, []string{"*.go"}, true, []string{"a.go", "b/b.go", "b/c/c.go"}},
+		{2, []string{"b/c/*.go"}, false, nil},
+		{3, []string{"b/**/*.go"}, false, []string{"b/b.go", "b/c/c.go"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, tt.patterns...)
+		config.MaxDepth = tt.depth
+		config.Recursive = tt.recursive
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("-max-depth %d %q: got %q, want %q", tt.depth, tt.patterns, got, tt.want)
+		}
+	}
+}
+
+func TestBeyondMaxDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		path  string
+		want  bool
+	}{
+		{0, "a/b/c/d.go", false},
+		{1, "a.go", false},
+		{1, "a/b.go", true},
+		{1, "a/", true},
+		{2, "a/", false},
+		{2, "a/b/", true},
+		{1, "../outside/a.go", false},
+	}
+	for _, tt := range tests {
+		config := DefaultOptions()
+		config.MaxDepth = tt.depth
+		if got := beyondMaxDepth(config, tt.path); got != tt.want {
+			t.Errorf("beyondMaxDepth(%d, %q) = %v, want %v", tt.depth, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHiddenFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":                    "a\n",
+		".env":                     "SECRET=1\n",
+		".github/workflows/ci.yml": "on: push\n",
+		"src/.eslintrc":            "{}\n",
+		"src/b.txt":                "b\n",
+		".git/config":              "[core]\n",
+	})
+	tests := []struct {
+		skipHidden bool
+		want       []string
+	}{
+		{false, []string{".env", ".github/workflows/ci.yml", "a.txt", "src/.eslintrc", "src/b.txt"}},
+		{true, []string{"a.txt", "src/b.txt"}},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "**/*")
+		config.SkipHidden = tt.skipHidden
+		if got := selectRel(t, config); !sameStrings(got, tt.want) {
+			t.Errorf("SkipHidden=%v: got %q, want %q", tt.skipHidden, got, tt.want)
+		}
+	}
+}
+
+func TestHiddenPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"a.txt", false},
+		{".env", true},
+		{".github/ci.yml", true},
+		{"src/.eslintrc", true},
+		{"src/a.b.txt", false},
+		{"../a.txt", false},
+		{".", false},
+	}
+	for _, tt := range tests {
+		if got := hiddenPath(tt.path); got != tt.want {
+			t.Errorf("hiddenPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
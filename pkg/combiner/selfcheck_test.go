@@ -0,0 +1,110 @@
+package combiner
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelfCheck(t *testing.T) {
+	files := map[string]string{
+		"main.go":     "package main\n\nfunc main() {}\n",
+		"crlf.txt":    "one\r\ntwo\r\n",
+		"bare.txt":    "no final newline",
+		"empty.txt":   "",
+		"sub/page.md": "# Page\n\n======\n",
+	}
+	tests := []struct {
+		name  string
+		setup func(*Options)
+	}{
+		{"defaults", nil},
+		{"title and toc", func(config *Options) {
+			config.Title = "Project"
+			config.TOC = true
+		}},
+		{"tree preamble", func(config *Options) { config.Tree = true }},
+		{"utf-16", func(config *Options) { config.Encoding = "utf-16le" }},
+		{"bom", func(config *Options) { config.BOM = "always" }},
+		{"signed", func(config *Options) { config.SignKey = "secret" }},
+		{"jobs", func(config *Options) { config.Jobs = 3 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, files)
+			config := testOptions(t, root, "**/*")
+			config.SelfCheck = true
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := captureStdout(t, func() { combine(t, config) })
+			if !strings.Contains(out, "SELF-CHECK PASSED: 5 files round-trip exactly") {
+				t.Errorf("self-check did not pass:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestSelfCheckMismatch(t *testing.T) {
+	files := map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"}
+	tests := []struct {
+		name     string
+		damage   func(root, output string) error
+		selected []string
+		want     string
+	}{
+		{"original changed", func(root, output string) error {
+			return os.WriteFile(filepath.Join(root, "a.txt"), []byte("changed\n"), 0o644)
+		}, []string{"a.txt", "b.txt", "c.txt"}, "a.txt: content differs (8 bytes -> 2 bytes)"},
+		{"section missing", func(root, output string) error {
+			data, err := os.ReadFile(output)
+			if err != nil {
+				return err
+			}
+			cut := strings.Index(string(data), "FILE 3:")
+			cut = strings.LastIndex(string(data[:cut]), "\n\n")
+			return os.WriteFile(output, data[:cut+1], 0o644)
+		}, []string{"a.txt", "b.txt", "c.txt"}, "c.txt: missing from split output"},
+		{"unexpected section", func(root, output string) error {
+			return os.Remove(filepath.Join(root, "b.txt"))
+		}, []string{"a.txt", "c.txt"}, "b.txt: unexpected section in output"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, files)
+			config := testOptions(t, root, "*.txt")
+			combine(t, config)
+			if err := tt.damage(root, config.Output); err != nil {
+				t.Fatal(err)
+			}
+			var selected []string
+			for _, name := range tt.selected {
+				selected = append(selected, filepath.Join(root, name))
+			}
+			var problems int
+			out := captureStdout(t, func() { problems = selfCheck(config, selected) })
+			if problems != 1 || !strings.Contains(out, tt.want) {
+				t.Errorf("got %d problems, want 1 with %q:\n%s", problems, tt.want, out)
+			}
+		})
+	}
+}
+
+func TestSelfCheckExitCode(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n"})
+	config := testOptions(t, root, "*.txt")
+	config.SelfCheck = true
+	config.ContentPrefix = "> " // changes every line, so nothing round-trips
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	captureStdout(t, func() { _, err = New(config).Run(context.Background()) })
+	var exit *ExitError
+	if !errors.As(err, &exit) || exit.Code != 3 {
+		t.Errorf("err = %v, want exit code 3", err)
+	}
+}
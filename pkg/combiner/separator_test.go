@@ -0,0 +1,123 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNoLeadingSeparator(t *testing.T) {
+	files := map[string]string{"a.txt": "a\n", "b.txt": "b\n", "c.txt": "c\n"}
+	tests := []struct {
+		name   string
+		setup  func(*Options)
+		prefix string // what the output starts with
+	}{
+		{"plain", nil, "a\n\n# ===="},
+		{"jobs", func(config *Options) { config.Jobs = 2 }, "a\n\n# ===="},
+		{"title and toc", func(config *Options) {
+			config.Title = "T"
+			config.TOC = true
+		}, "# ====="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, writeTree(t, files), "*.txt")
+			config.NoLeadingSeparator = true
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+			if !strings.HasPrefix(out, tt.prefix) {
+				t.Errorf("output starts %q, want %q", out[:min(len(out), 40)], tt.prefix)
+			}
+			if strings.Contains(out, "FILE 1:") {
+				t.Errorf("first file has a separator:\n%s", out)
+			}
+			for _, want := range []string{"FILE 2: b.txt\n", "FILE 3: c.txt\n"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("missing %q:\n%s", want, out)
+				}
+			}
+			if sections := splitCombined([]byte(out)); len(sections) != 2 {
+				t.Errorf("split found %d sections, want 2 (the first file has no separator)", len(sections))
+			}
+		})
+	}
+}
+
+func TestNoLeadingSeparatorAppend(t *testing.T) {
+	first := testOptions(t, writeTree(t, map[string]string{"a.txt": "a\n"}), "*.txt")
+	combine(t, first)
+
+	second := testOptions(t, writeTree(t, map[string]string{"b.txt": "b\n"}), "*.txt")
+	second.Output = first.Output
+	second.Append = true
+	second.NoLeadingSeparator = true
+	out := combine(t, second)
+	if !strings.Contains(out, "FILE 1: a.txt\n") || !strings.Contains(out, "FILE 2: b.txt\n") {
+		t.Errorf("appended files must keep their separators:\n%s", out)
+	}
+}
+
+func TestSeparatorFormat(t *testing.T) {
+	files := map[string]string{
+		"main.go":    "package main\n",
+		"style.css":  "p {}\n",
+		"notes.txt":  "notes\n",
+		"data.plain": "plain\n",
+	}
+	root := writeTree(t, files)
+	hash, err := hashFile(filepath.Join(root, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(root, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		format string
+		want   []string
+	}{
+		{"minimal", "----- {path} -----", []string{
+			"\n/*\n ----- main.go -----\n*/\n\npackage main\n",
+			"\n/*\n ----- style.css -----\n*/\n\np {}\n",
+			"\n# ----- notes.txt -----\n\nnotes\n",
+		}},
+		{"size and hash", "{index}. {name} {size} bytes {hash}", []string{
+			"/*\n 2. main.go 13 bytes " + hash[:12] + "\n*/\n",
+		}},
+		{"several lines", "{path}\nmodified {mtime}", []string{
+			"/*\n main.go\n modified " + info.ModTime().Format("2006-01-02 15:04:05") + "\n*/\n",
+		}},
+		{"absolute path", "{abspath}", []string{
+			"# " + filepath.Join(root, "notes.txt") + "\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*")
+			config.SeparatorFormat = tt.format
+			out := combine(t, config)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output missing %q:\n%s", want, out)
+				}
+			}
+			if strings.Contains(out, "FILE 1:") {
+				t.Errorf("default separator still written:\n%s", out)
+			}
+		})
+	}
+
+	for _, format := range []string{"{path} {bogus}", "{path"} {
+		config := testOptions(t, root, "*")
+		config.SeparatorFormat = format
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "invalid --separator-format") {
+			t.Errorf("-separator-format %q: err = %v, want invalid --separator-format", format, err)
+		}
+	}
+}
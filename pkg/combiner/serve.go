@@ -0,0 +1,63 @@
+package combiner
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ServeHTTP combines the files again for every GET request and sends the
+// output as the response body, so the serve command always reflects the
+// current tree. Requests are answered one at a time.
+func (c *Combiner) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stamp := time.Now().Format("15:04:05")
+	body := &countingWriter{w: w}
+	options := *c.Options
+	options.Output = "-"
+	options.Stdout = body
+	options.Rebuilding = true
+	run := New(&options)
+
+	report, err := run.Select(r.Context())
+	if err != nil {
+		fmt.Printf("[%s] %s %s: %v\n", stamp, r.Method, r.URL.Path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType(&options))
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if err := run.Combine(r.Context(), report); err != nil {
+		fmt.Printf("[%s] %s %s: %v\n", stamp, r.Method, r.URL.Path, err)
+		if body.n == 0 {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	fmt.Printf("[%s] %s %s: %d files (%s)\n", stamp, r.Method, r.URL.Path, len(report.Files), run.last.totals.describe(&options))
+}
+
+// contentType is the media type of the output a run writes
+func contentType(config *Options) string {
+	switch {
+	case config.Gzip:
+		return "application/gzip"
+	case config.Format == FORMAT_CSV:
+		return "text/csv; charset=utf-8"
+	}
+	if enc, _ := lookupEncoding(config.Encoding); !enc.isUTF8() {
+		return "text/plain; charset=" + config.Encoding
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,107 @@
+package combiner
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n", "b.txt": "b\n"})
+	want := combine(t, testOptions(t, root, "*"))
+
+	tests := []struct {
+		name        string
+		method      string
+		setup       func(*Options)
+		status      int
+		contentType string
+		body        string
+	}{
+		{"get", http.MethodGet, nil, http.StatusOK, "text/plain; charset=utf-8", want},
+		{"head", http.MethodHead, nil, http.StatusOK, "text/plain; charset=utf-8", ""},
+		{"post", http.MethodPost, nil, http.StatusMethodNotAllowed, "text/plain; charset=utf-8", "method not allowed\n"},
+		{"csv", http.MethodGet, func(config *Options) { config.Format = FORMAT_CSV }, http.StatusOK, "text/csv; charset=utf-8", ""},
+		{"encoding", http.MethodGet, func(config *Options) { config.Encoding = "latin1" }, http.StatusOK, "text/plain; charset=latin1", want},
+		{"missing root", http.MethodGet, func(config *Options) { config.Root = filepath.Join(root, "missing") }, http.StatusInternalServerError, "text/plain; charset=utf-8", "Root directory does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "*")
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			recorder := httptest.NewRecorder()
+			captureStdout(t, func() {
+				New(config).ServeHTTP(recorder, httptest.NewRequest(tt.method, "/", nil))
+			})
+			response := recorder.Result()
+			if response.StatusCode != tt.status {
+				t.Errorf("status %d, want %d", response.StatusCode, tt.status)
+			}
+			if got := response.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			if tt.method == http.MethodPost && response.Header.Get("Allow") != "GET, HEAD" {
+				t.Errorf("Allow = %q", response.Header.Get("Allow"))
+			}
+			body := recorder.Body.String()
+			switch {
+			case tt.status == http.StatusOK && tt.body == "":
+				if tt.method == http.MethodHead && body != "" {
+					t.Errorf("HEAD body %q", body)
+				}
+			case tt.status == http.StatusOK:
+				if body != tt.body {
+					t.Errorf("body:\n%s\nwant:\n%s", body, tt.body)
+				}
+			case !strings.Contains(body, tt.body):
+				t.Errorf("body %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestServeHTTPFreshOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "first\n"})
+	config := testOptions(t, root, "*.txt")
+	config.Gzip = true
+	handler := New(config)
+	get := func() string {
+		t.Helper()
+		recorder := httptest.NewRecorder()
+		captureStdout(t, func() { handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil)) })
+		if got := recorder.Header().Get("Content-Type"); got != "application/gzip" {
+			t.Errorf("Content-Type %q", got)
+		}
+		gz, err := gzip.NewReader(bytes.NewReader(recorder.Body.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if out := get(); !strings.Contains(out, "first") {
+		t.Errorf("first response:\n%s", out)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.txt"), []byte("second\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := get(); !strings.Contains(out, "first") || !strings.Contains(out, "second") {
+		t.Errorf("response after adding b.txt:\n%s", out)
+	}
+	// The handler leaves the options it was made with alone
+	if config.Output == "-" || config.Stdout != nil {
+		t.Errorf("options changed: output %q", config.Output)
+	}
+}
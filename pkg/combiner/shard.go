@@ -0,0 +1,144 @@
+package combiner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// shardSection locates one rendered file inside a worker's shard
+type shardSection struct {
+	shard     int
+	sepStart  int64 // separator offset
+	bodyStart int64 // body offset (after the separator)
+	end       int64
+	ok        bool
+
+	contentStart  int64 // offset of the file content within the body
+	contentLength int64
+}
+
+// countingWriter tracks how many bytes have been written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeSharded renders file sections concurrently, each worker appending to
+// its own temporary shard file, then concatenates the sections back in file
+// order. The result is byte-for-byte identical to the serial path.
+func writeSharded(w io.Writer, config *Options, files []string) (int, int) {
+	newline := getNewline(config.newlineType())
+	jobs := config.Jobs
+	if jobs > len(files) {
+		jobs = len(files)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "combine-shards-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: Cannot create shard directory (%v); combining serially\n", err)
+		return writeSerial(w, config, files)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sections := make([]shardSection, len(files))
+	shards := make([]*os.File, jobs)
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < jobs; worker++ {
+		shard, err := os.Create(filepath.Join(tmpDir, fmt.Sprintf("shard-%d", worker)))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Cannot create shard: %v\n", err)
+			return 0, len(files)
+		}
+		shards[worker] = shard
+
+		wg.Add(1)
+		go func(worker int, shard *os.File) {
+			defer wg.Done()
+			buffered := bufio.NewWriter(shard)
+			out := &countingWriter{w: buffered}
+
+			for idx := range indexes {
+				filePath := files[idx]
+				if config.Verbose {
+					fmt.Printf("Processing [%d/%d]: %s\n", idx+1, len(files), filepath.Base(filePath))
+				}
+
+				readStart := time.Now()
+				content, err := loadContent(config, filePath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: Skipped %s: %v\n", filePath, err)
+					continue
+				}
+				readTime := time.Since(readStart)
+				writeStart := time.Now()
+
+				index := config.IndexOffset + idx + 1
+				section := shardSection{shard: worker, sepStart: out.n}
+				io.WriteString(out, sectionSeparator(config, filePath, index, content))
+				section.bodyStart = out.n
+				section.contentStart = section.bodyStart + writeSectionBody(out, config, filePath, index, content, newline)
+				section.contentLength = int64(len(content))
+				section.end = out.n
+				section.ok = true
+				if config.Debug {
+					config.run.timings.record(filePath, readTime, time.Since(writeStart))
+				}
+				sections[idx] = section
+			}
+			buffered.Flush()
+		}(worker, shard)
+	}
+
+	for idx := range files {
+		if config.canceled() {
+			break
+		}
+		indexes <- idx
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Concatenate the sections in their original order
+	successCount := 0
+	errorCount := 0
+	previous := ""
+	out := &countingWriter{w: w}
+	for idx, section := range sections {
+		if !section.ok {
+			errorCount++
+			continue
+		}
+		io.WriteString(out, directoryBanner(config, previous, files[idx]))
+		previous = files[idx]
+		start := section.sepStart
+		if config.NoLeadingSeparator && successCount == 0 && !config.Continuing {
+			start = section.bodyStart
+		}
+		config.run.regions.record(files[idx], out.n+section.contentStart-start, section.contentLength)
+		reader := io.NewSectionReader(shards[section.shard], start, section.end-start)
+		if _, err := io.Copy(out, reader); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Cannot copy shard: %v\n", err)
+			errorCount++
+			continue
+		}
+		successCount++
+	}
+
+	for _, shard := range shards {
+		shard.Close()
+	}
+	return successCount, errorCount
+}
@@ -0,0 +1,65 @@
+package combiner
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestShardedMatchesSerial(t *testing.T) {
+	tree := make(map[string]string)
+	for i := 0; i < 40; i++ {
+		ext := []string{".go", ".py", ".html", ".txt"}[i%4]
+		tree[fmt.Sprintf("d%d/f%02d%s", i%5, i, ext)] = strings.Repeat(fmt.Sprintf("line %d\n", i), i+1)
+	}
+	tree["d0/empty.txt"] = ""
+	tree["d1/no-newline.txt"] = "last"
+	root := writeTree(t, tree)
+
+	tests := []struct {
+		name  string
+		setup func(*Options)
+	}{
+		{"default", nil},
+		{"no leading separator", func(config *Options) { config.NoLeadingSeparator = true }},
+		{"line numbers", func(config *Options) { config.LineNumbers = true }},
+		{"markdown", func(config *Options) { config.Format = FORMAT_MARKDOWN }},
+		{"xml", func(config *Options) { config.Format = FORMAT_XML }},
+		{"separator meta", func(config *Options) { config.SeparatorMeta = []string{META_SIZE, META_SHA256} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outputs []string
+			for _, jobs := range []int{1, 2, 7, 64} {
+				config := testOptions(t, root, "**/*")
+				config.Jobs = jobs
+				if tt.setup != nil {
+					tt.setup(config)
+				}
+				outputs = append(outputs, combine(t, config))
+			}
+			for i, out := range outputs[1:] {
+				if out != outputs[0] {
+					t.Errorf("output %d differs from the serial output", i+1)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCombineJobs(b *testing.B) {
+	tree := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		tree[fmt.Sprintf("d%d/f%03d.go", i%10, i)] = strings.Repeat("// some source line\n", 500)
+	}
+	root := writeTree(b, tree)
+	for _, jobs := range []int{1, 4} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				config := testOptions(b, root, "**/*.go")
+				config.Jobs = jobs
+				combine(b, config)
+			}
+		})
+	}
+}
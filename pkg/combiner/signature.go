@@ -0,0 +1,75 @@
+package combiner
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// signaturePrefix starts the trailer line written by -sign-key
+const signaturePrefix = "# hmac-sha256: "
+
+// signOutput tees everything written through the returned writer into an
+// HMAC-SHA256 keyed with -sign-key. The returned function appends the
+// signature trailer to w once the output is complete.
+func signOutput(config *Options, w io.Writer) (io.Writer, func() error) {
+	if config.SignKey == "" {
+		return w, func() error { return nil }
+	}
+	mac := hmac.New(sha256.New, []byte(config.SignKey))
+	return io.MultiWriter(w, mac), func() error {
+		_, err := fmt.Fprintf(w, "\n%s%x\n", signaturePrefix, mac.Sum(nil))
+		return err
+	}
+}
+
+// splitSignature separates a signed output into the signed bytes and the
+// signature of its trailer; ok is false when there is no trailer
+func splitSignature(data []byte) (signed, signature []byte, ok bool) {
+	idx := bytes.LastIndex(data, []byte("\n"+signaturePrefix))
+	if idx < 0 {
+		return data, nil, false
+	}
+	trailer := strings.TrimRight(string(data[idx+1+len(signaturePrefix):]), "\r\n")
+	signature, err := hex.DecodeString(trailer)
+	if err != nil || len(signature) != sha256.Size {
+		return data, nil, false
+	}
+	return data[:idx], signature, true
+}
+
+// stripSignature drops a -sign-key trailer so the sections can be parsed
+func stripSignature(data []byte) []byte {
+	signed, _, _ := splitSignature(data)
+	return signed
+}
+
+// VerifySignature checks the trailer of the file named by -verify-signature
+// against -sign-key and returns the exit code
+func VerifySignature(config *Options) int {
+	data, err := os.ReadFile(config.VerifySignature)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot read %s: %v\n", config.VerifySignature, err)
+		return 1
+	}
+
+	signed, signature, ok := splitSignature(data)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: No signature trailer found in %s\n", config.VerifySignature)
+		return 5
+	}
+	mac := hmac.New(sha256.New, []byte(config.SignKey))
+	mac.Write(signed)
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		fmt.Fprintf(os.Stderr, "SIGNATURE INVALID: %s was altered or signed with another key\n", config.VerifySignature)
+		return 5
+	}
+
+	fmt.Printf("SIGNATURE OK: %s (%s signed)\n", config.VerifySignature, formatSize(int64(len(signed))))
+	return 0
+}
@@ -0,0 +1,73 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifySignature(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "alpha\n", "b.txt": "beta\n"})
+	config := testOptions(t, root, "*.txt")
+	config.SignKey = "secret"
+	signed := combine(t, config)
+	if !strings.Contains(signed, "\n"+signaturePrefix) {
+		t.Fatalf("no signature trailer in:\n%s", signed)
+	}
+
+	tests := []struct {
+		name   string
+		key    string
+		modify func(string) string
+		want   int
+	}{
+		{"untouched", "secret", nil, 0},
+		{"wrong key", "other", nil, 5},
+		{"content changed", "secret", func(s string) string { return strings.Replace(s, "alpha", "alphA", 1) }, 5},
+		{"byte appended before the trailer", "secret", func(s string) string {
+			i := strings.LastIndex(s, "\n"+signaturePrefix)
+			return s[:i] + " " + s[i:]
+		}, 5},
+		{"trailer removed", "secret", func(s string) string { return s[:strings.LastIndex(s, "\n"+signaturePrefix)] }, 5},
+		{"trailer garbled", "secret", func(s string) string { return strings.TrimSuffix(s, "\n") + "zz\n" }, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := signed
+			if tt.modify != nil {
+				data = tt.modify(data)
+			}
+			path := filepath.Join(t.TempDir(), "signed.txt")
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			verify := DefaultOptions()
+			verify.SignKey = tt.key
+			verify.VerifySignature = path
+			if got := VerifySignature(verify); got != tt.want {
+				t.Errorf("VerifySignature = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSignature(t *testing.T) {
+	sig := strings.Repeat("ab", 32)
+	tests := []struct {
+		data   string
+		signed string
+		ok     bool
+	}{
+		{"body\n\n" + signaturePrefix + sig + "\n", "body\n", true},
+		{"body\r\n\n" + signaturePrefix + sig + "\r\n", "body\r\n", true},
+		{"body\n", "body\n", false},
+		{"body\n\n" + signaturePrefix + "abcd\n", "body\n\n" + signaturePrefix + "abcd\n", false},
+	}
+	for _, tt := range tests {
+		signed, _, ok := splitSignature([]byte(tt.data))
+		if ok != tt.ok || string(signed) != tt.signed {
+			t.Errorf("splitSignature(%q) = %q, %v; want %q, %v", tt.data, signed, ok, tt.signed, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// splitSection is one file recovered from a combined output
+type splitSection struct {
+	Index   int
+	Path    string
+	Content []byte
+	Start   int // offset of the section's separator in the combined data
+
+	noFinalNewline bool // the combiner added the newline ending Content
+}
+
+var (
+	bareFileLine  = regexp.MustCompile(`^ FILE (\d+): (.+)$`)
+	separatorRule = strings.Repeat("=", 70)
+)
+
+// blockEnds maps each block comment opener used by createSeparator to its closer
+func blockEnds() map[string]string {
+	ends := make(map[string]string)
+	for _, style := range commentStyles {
+		if style.BlockStart != "" && style.BlockEnd != "" {
+			ends[style.BlockStart] = style.BlockEnd
+		}
+	}
+	return ends
+}
+
+// splitCombined parses the separators written by createSeparator (block
+// comment, single-line comment and the bare === fallback) and returns the
+// content found between them. Anything before the first separator, such as a
+// title banner, is ignored, and so is a -sign-key trailer.
+func splitCombined(data []byte) []splitSection {
+	data = stripSignature(data)
+	var lines []string
+	var starts []int
+	for offset := 0; offset <= len(data); {
+		end := bytes.IndexByte(data[offset:], '\n')
+		starts = append(starts, offset)
+		if end < 0 {
+			lines = append(lines, string(data[offset:]))
+			break
+		}
+		lines = append(lines, string(data[offset:offset+end]))
+		offset += end + 1
+	}
+
+	line := func(i int) string {
+		if i < len(lines) {
+			return lines[i]
+		}
+		return "\x00"
+	}
+
+	ends := blockEnds()
+	var sections []splitSection
+	var contentStarts, headerStarts []int
+
+	for i := 1; i+3 < len(lines); i++ {
+		if lines[i-1] != "" {
+			continue
+		}
+		open := lines[i]
+		var prefix, closing string
+
+		switch {
+		case open == separatorRule:
+			closing = separatorRule
+		case ends[open] != "":
+			closing = ends[open]
+		case strings.HasSuffix(open, " "+separatorRule) && !strings.Contains(strings.TrimSuffix(open, " "+separatorRule), " "):
+			prefix = strings.TrimSuffix(open, " "+separatorRule)
+			closing = open
+		default:
+			continue
+		}
+
+		m := bareFileLine.FindStringSubmatch(strings.TrimPrefix(line(i+1), prefix))
+		if m == nil {
+			continue
+		}
+		j := i + 2
+		noFinalNewline := false
+		for isSeparatorMetaLine(strings.TrimPrefix(line(j), prefix)) {
+			noFinalNewline = noFinalNewline || strings.TrimPrefix(line(j), prefix) == " "+NO_FINAL_NEWLINE
+			j++
+		}
+		if line(j) != closing || line(j+1) != "" || j+2 >= len(lines) {
+			continue
+		}
+
+		index, _ := strconv.Atoi(m[1])
+		sections = append(sections, splitSection{Index: index, Path: m[2], Start: starts[i] - 1, noFinalNewline: noFinalNewline})
+		headerStarts = append(headerStarts, starts[i]-1)
+		contentStarts = append(contentStarts, starts[j+2])
+		i = j + 1
+	}
+
+	for k := range sections {
+		end := len(data)
+		if k+1 < len(sections) {
+			end = headerStarts[k+1]
+		}
+		sections[k].Content = data[contentStarts[k]:end]
+		if k+1 < len(sections) {
+			sections[k].Content = trimDirectoryBanner(sections[k].Content, sections[k+1].Path)
+		}
+		if sections[k].noFinalNewline {
+			sections[k].Content = trimFinalNewline(sections[k].Content)
+		}
+		if data, ok := decodeBinary(sections[k].Content); ok {
+			sections[k].Content = data
+		}
+	}
+	return sections
+}
+
+// trimFinalNewline removes the newline the combiner wrote after content
+// that did not end with one
+func trimFinalNewline(content []byte) []byte {
+	if bytes.HasSuffix(content, []byte("\r\n")) {
+		return content[:len(content)-2]
+	}
+	if bytes.HasSuffix(content, []byte("\n")) {
+		return content[:len(content)-1]
+	}
+	return bytes.TrimSuffix(content, []byte("\r"))
+}
+
+// safeJoin resolves a relative path recovered from a separator under target,
+// refusing absolute paths and anything that would escape it
+func safeJoin(target, relPath string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(relPath))
+	if filepath.IsAbs(clean) || filepath.VolumeName(clean) != "" ||
+		clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to write outside target directory: %s", relPath)
+	}
+	return filepath.Join(target, clean), nil
+}
+
+// writeSections recreates every section as a file under target
+func writeSections(sections []splitSection, target string) error {
+	for _, section := range sections {
+		dest, err := safeJoin(target, section.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("cannot create directory for %s: %v", section.Path, err)
+		}
+		if err := os.WriteFile(dest, section.Content, 0644); err != nil {
+			return fmt.Errorf("cannot write %s: %v", section.Path, err)
+		}
+	}
+	return nil
+}
+
+// selfCheck splits the freshly written output into a temporary directory and
+// compares every reconstructed file with its original. It returns the number
+// of problems found.
+func selfCheck(config *Options, files []string) int {
+	if config.Output == "c" {
+		fmt.Fprintln(os.Stderr, "Error: --self-check needs a file output, not the clipboard")
+		return 1
+	}
+
+	data, err := os.ReadFile(config.Output)
+	if err == nil {
+		enc, _ := lookupEncoding(config.Encoding)
+		data, err = enc.decode(data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot read output for self-check: %v\n", err)
+		return 1
+	}
+
+	tmpDir, err := os.MkdirTemp("", "combine-selfcheck-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot create temp directory: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sections := splitCombined(data)
+	if err := writeSections(sections, tmpDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Self-check split failed: %v\n", err)
+		return 1
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	absOutput, _ := filepath.Abs(config.Output)
+	for _, file := range files {
+		if absFile, _ := filepath.Abs(file); absFile == absOutput {
+			continue
+		}
+		relPath, _ := filepath.Rel(config.Root, file)
+		relPath = filepath.ToSlash(relPath)
+		seen[relPath] = true
+
+		original, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		rebuilt, err := os.ReadFile(filepath.Join(tmpDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: missing from split output", relPath))
+			continue
+		}
+		if !bytes.Equal(original, rebuilt) {
+			problems = append(problems, fmt.Sprintf("%s: content differs (%d bytes -> %d bytes)", relPath, len(original), len(rebuilt)))
+		}
+	}
+	for _, section := range sections {
+		if !seen[section.Path] {
+			problems = append(problems, fmt.Sprintf("%s: unexpected section in output", section.Path))
+		}
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	if len(problems) == 0 {
+		fmt.Printf("SELF-CHECK PASSED: %d files round-trip exactly\n", len(sections))
+	} else {
+		fmt.Printf("SELF-CHECK FAILED: %d problem(s)\n", len(problems))
+		for _, p := range problems {
+			fmt.Printf("  × %s\n", p)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 70))
+
+	return len(problems)
+}
+
+// readCombined reads a combined output (- for stdin), undoing -gzip and
+// the output encoding
+func readCombined(config *Options, path string) ([]byte, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err == nil {
+		data, err = decompressInput(data)
+	}
+	if err == nil {
+		enc, _ := lookupEncoding(config.Encoding)
+		data, err = enc.decode(data)
+	}
+	return data, err
+}
+
+// UnpackCombined recreates the files contained in the combined file named by
+// -unpack under config.Root. Every path is checked before anything is written,
+// so a single traversal attempt aborts the whole unpack.
+func UnpackCombined(config *Options) int {
+	data, err := readCombined(config, config.Unpack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot read %s: %v\n", config.Unpack, err)
+		return 1
+	}
+
+	sections := splitCombined(data)
+	if len(sections) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: No FILE separators found in %s\n", config.Unpack)
+		return 1
+	}
+	for _, section := range sections {
+		if _, err := safeJoin(config.Root, section.Path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, section := range sections {
+		if config.Verbose || config.DryRun {
+			fmt.Printf("  ✓ %s (%d bytes)\n", section.Path, len(section.Content))
+		}
+	}
+	if config.DryRun {
+		fmt.Printf("\nDry-run mode: %d files would be unpacked into %s\n", len(sections), config.Root)
+		return 0
+	}
+
+	if err := writeSections(sections, config.Root); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Unpack failed: %v\n", err)
+		return 2
+	}
+
+	fmt.Println("\n" + strings.Repeat("=", 70))
+	fmt.Printf("SUCCESS: Unpacked %d files into %s\n", len(sections), config.Root)
+	fmt.Println(strings.Repeat("=", 70))
+	return 0
+}
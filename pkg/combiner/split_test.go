@@ -0,0 +1,178 @@
+package combiner
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		setup func(*Options)
+	}{
+		{"comment styles", map[string]string{
+			"main.go":         "package main\n\nfunc main() {}\n",
+			"tool.py":         "print('hi')\n",
+			"page.html":       "<p>hi</p>\n",
+			"style.css":       "p { color: red; }\n",
+			"notes.txt":       "plain text\n",
+			"sub/dir/deep.sh": "echo deep\n",
+		}, nil},
+		{"no trailing newline", map[string]string{
+			"a.txt": "no newline",
+			"b.txt": "two\nlines",
+		}, func(config *Options) { config.ExactNewlines = true }},
+		{"crlf output", map[string]string{
+			"a.txt": "lf\n",
+			"b.txt": "crlf\r\n",
+			"c.txt": "none",
+		}, func(config *Options) {
+			config.NewlineType = "crlf"
+			config.ExactNewlines = true
+		}},
+		{"cr output", map[string]string{
+			"a.txt": "lf\n",
+			"b.txt": "none",
+		}, func(config *Options) {
+			config.NewlineType = "cr"
+			config.ExactNewlines = true
+		}},
+		{"content that looks like a separator", map[string]string{
+			"fake.txt": "\n======================================================================\n not a FILE line\n",
+			"z.txt":    "after\n",
+		}, nil},
+		{"title and toc", map[string]string{
+			"a.md": "# a\n",
+			"b.md": "# b\n",
+		}, func(config *Options) {
+			config.Title = "Project"
+			config.TOC = true
+		}},
+		{"tree preamble", map[string]string{
+			"x/a.txt": "a\n",
+			"y/b.txt": "b\n",
+		}, func(config *Options) { config.Tree = true }},
+		{"separator metadata", map[string]string{
+			"a.txt":  "a\n",
+			"b.py":   "no newline",
+			"c/d.md": "# d\n",
+		}, func(config *Options) {
+			config.NoTimestamp = false
+			config.ExactNewlines = true
+			config.SeparatorMeta = []string{META_SIZE, META_LINES, META_SHA256, META_MTIME}
+		}},
+		{"signed", map[string]string{
+			"a.txt": "a\n",
+		}, func(config *Options) { config.SignKey = "secret" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			config := testOptions(t, root, "**/*")
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			sections := splitCombined([]byte(combine(t, config)))
+			if len(sections) != len(tt.files) {
+				t.Fatalf("got %d sections, want %d", len(sections), len(tt.files))
+			}
+			for i, section := range sections {
+				want, ok := tt.files[section.Path]
+				if !ok {
+					t.Errorf("unexpected section %q", section.Path)
+					continue
+				}
+				if section.Index != i+1 {
+					t.Errorf("%s: index %d, want %d", section.Path, section.Index, i+1)
+				}
+				if string(section.Content) != want {
+					t.Errorf("%s: content %q, want %q", section.Path, section.Content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFinalNewlineMarker(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		setup      func(*Options)
+		wantMarker bool
+		wantTail   string
+	}{
+		{"default output", "no newline", nil, false, "no newline\n"},
+		{"exact newlines", "no newline", func(config *Options) { config.ExactNewlines = true }, true, "no newline\n"},
+		{"self-check", "no newline", func(config *Options) { config.SelfCheck = true }, true, "no newline\n"},
+		{"ends with a newline", "done\n", func(config *Options) { config.ExactNewlines = true }, false, "done\n"},
+		{"lf file in crlf output", "done\n", func(config *Options) {
+			config.NewlineType = "crlf"
+			config.ExactNewlines = true
+		}, false, "done\n"},
+		{"cr file", "done\r", func(config *Options) { config.ExactNewlines = true }, false, "done\r"},
+		{"crlf output", "no newline", func(config *Options) { config.NewlineType = "crlf" }, false, "no newline\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, map[string]string{"a.txt": tt.content})
+			config := testOptions(t, root, "*.txt")
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			var out string
+			captureStdout(t, func() { out = combine(t, config) })
+			if got := strings.Contains(out, NO_FINAL_NEWLINE); got != tt.wantMarker {
+				t.Errorf("marker = %v, want %v:\n%q", got, tt.wantMarker, out)
+			}
+			// The content follows the blank line closing the separator
+			if !strings.HasSuffix(out, "\n\n"+tt.wantTail) {
+				t.Errorf("output ends %q, want %q", out[len(out)-min(len(out), 16):], tt.wantTail)
+			}
+		})
+	}
+}
+
+func TestUnpackCombined(t *testing.T) {
+	files := map[string]string{"a.txt": "a\n", "sub/b.go": "package sub\n"}
+	config := testOptions(t, writeTree(t, files), "**/*")
+	combine(t, config)
+
+	target := t.TempDir()
+	unpack := DefaultOptions()
+	unpack.Root = target
+	unpack.Unpack = config.Output
+	if code := UnpackCombined(unpack); code != 0 {
+		t.Fatalf("UnpackCombined = %d", code)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(target, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, err, want)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	target := filepath.FromSlash("/out")
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"a.txt", true},
+		{"sub/a.txt", true},
+		{"sub/../a.txt", true},
+		{"../a.txt", false},
+		{"sub/../../a.txt", false},
+		{"..", false},
+		{"/etc/passwd", false},
+	}
+	for _, tt := range tests {
+		_, err := safeJoin(target, tt.path)
+		if (err == nil) != tt.ok {
+			t.Errorf("safeJoin(%q) error = %v, want ok=%v", tt.path, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// extensionStat tallies the files of one extension
+type extensionStat struct {
+	Ext   string
+	Files int
+	Bytes int64
+}
+
+// extensionStats groups files by extension, largest share of bytes first
+func extensionStats(files []string) ([]extensionStat, int64) {
+	byExt := make(map[string]*extensionStat)
+	var total int64
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(file))
+		if ext == "" {
+			ext = "(none)"
+		}
+		stat := byExt[ext]
+		if stat == nil {
+			stat = &extensionStat{Ext: ext}
+			byExt[ext] = stat
+		}
+		stat.Files++
+		stat.Bytes += info.Size()
+		total += info.Size()
+	}
+
+	stats := make([]extensionStat, 0, len(byExt))
+	for _, stat := range byExt {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Ext < stats[j].Ext
+	})
+	return stats, total
+}
+
+// printExtensionStats prints each extension's share of the total bytes with a bar
+func printExtensionStats(files []string) {
+	const barWidth = 30
+	stats, total := extensionStats(files)
+
+	fmt.Println("\nEXTENSIONS (by share of bytes):")
+	for _, stat := range stats {
+		percent := 0.0
+		if total > 0 {
+			percent = float64(stat.Bytes) * 100 / float64(total)
+		}
+		bar := strings.Repeat("#", int(percent*barWidth/100+0.5))
+		fmt.Printf("  %-10s %5d files %10s %6.1f%% %s\n", stat.Ext, stat.Files, formatSize(stat.Bytes), percent, bar)
+	}
+}
+
+// PrintExtensionScan prints every extension found by -scan-extensions, most files first
+func PrintExtensionScan(files []string) {
+	stats, total := extensionStats(files)
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Files != stats[j].Files {
+			return stats[i].Files > stats[j].Files
+		}
+		return stats[i].Ext < stats[j].Ext
+	})
+
+	fmt.Printf("%-12s %7s %10s\n", "EXTENSION", "FILES", "SIZE")
+	for _, stat := range stats {
+		fmt.Printf("%-12s %7d %10s\n", stat.Ext, stat.Files, formatSize(stat.Bytes))
+	}
+	fmt.Printf("%-12s %7d %10s\n", "total", len(files), formatSize(total))
+}
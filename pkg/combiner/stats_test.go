@@ -0,0 +1,103 @@
+package combiner
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExtensionStats(t *testing.T) {
+	files := map[string]string{
+		"a.go":     "1234567890",
+		"b.GO":     "1234567890",
+		"c.md":     "12345",
+		"Makefile": "12345",
+		"d.txt":    "123456789012345678901234567890",
+	}
+	root := writeTree(t, files)
+	stats, total := extensionStats(treeFiles(root, files))
+	if total != 60 {
+		t.Errorf("total = %d, want 60", total)
+	}
+	want := []extensionStat{
+		{".txt", 1, 30},
+		{".go", 2, 20},
+		{"(none)", 1, 5},
+		{".md", 1, 5},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("got %+v, want %+v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] = %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
+
+func TestPrintExtensionScan(t *testing.T) {
+	files := map[string]string{"a.go": "1", "b.go": "2", "c.go": "3", "big.md": "1234567890", "x.txt": "1"}
+	root := writeTree(t, files)
+	out := captureStdout(t, func() { PrintExtensionScan(treeFiles(root, files)) })
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	var exts []string
+	for _, line := range lines[1 : len(lines)-1] {
+		exts = append(exts, strings.Fields(line)[0])
+	}
+	if want := []string{".go", ".md", ".txt"}; !sameStrings(exts, want) {
+		t.Errorf("extensions listed %q, want %q (most files first)", exts, want)
+	}
+	if fields := strings.Fields(lines[len(lines)-1]); fields[0] != "total" || fields[1] != "5" {
+		t.Errorf("total line = %q", lines[len(lines)-1])
+	}
+}
+
+func TestPrintExtensionStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		largest string
+		bar     int // bar length of the largest extension
+	}{
+		{"single extension", map[string]string{"a.go": "12345", "b.go": "123"}, ".go", 30},
+		{"json bloat", map[string]string{"data.json": strings.Repeat("x", 60), "a.go": strings.Repeat("x", 30), "README.md": strings.Repeat("x", 10)}, ".json", 18},
+		{"thirds", map[string]string{"a.go": "1", "b.md": "2", "c.txt": "3"}, ".go", 10},
+		{"empty files", map[string]string{"a.go": "", "b.md": ""}, ".go", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTree(t, tt.files)
+			out := captureStdout(t, func() { printExtensionStats(treeFiles(root, tt.files)) })
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			if lines[0] != "EXTENSIONS (by share of bytes):" {
+				t.Fatalf("heading %q", lines[0])
+			}
+			sum := 0.0
+			for i, line := range lines[1:] {
+				fields := strings.Fields(line)
+				var percent float64
+				bar := 0
+				for j, field := range fields {
+					if strings.HasSuffix(field, "%") {
+						percent, _ = strconv.ParseFloat(strings.TrimSuffix(field, "%"), 64)
+						if j+1 < len(fields) {
+							bar = len(fields[j+1])
+						}
+					}
+				}
+				sum += percent
+				if i == 0 && (fields[0] != tt.largest || bar != tt.bar) {
+					t.Errorf("first line %q, want %s with a %d-character bar", line, tt.largest, tt.bar)
+				}
+			}
+			want := 100.0
+			if tt.bar == 0 {
+				want = 0 // no bytes, no shares
+			}
+			if math.Abs(sum-want) > 0.2 {
+				t.Errorf("percentages sum to %.1f, want %.0f:\n%s", sum, want, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package combiner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// separatorData is what a -separator-template is executed with
+type separatorData struct {
+	Index         int
+	RelPath       string
+	AbsPath       string
+	Name          string
+	Ext           string
+	Size          int64
+	ModTime       time.Time
+	Time          time.Time // the run's timestamp, as in the default separators
+	Language      string
+	CommentPrefix string // the file's line comment, "" when it only has block comments
+	CommentStart  string // the file's block comment delimiters, if it has them
+	CommentEnd    string
+
+	sample bool // the stand-in Validate tries the template on
+}
+
+// SHA256 is the hex SHA-256 of the file, computed only when a template
+// asks for it
+func (d separatorData) SHA256() string {
+	if d.sample {
+		return strings.Repeat("0", 64)
+	}
+	hash, err := hashFile(d.AbsPath)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// newSeparatorData collects the template values for a file
+func newSeparatorData(config *Options, path string, index int) separatorData {
+	relPath, _ := filepath.Rel(config.Root, path)
+	absPath, _ := filepath.Abs(path)
+	style := getCommentStyle(path)
+	data := separatorData{
+		Index:         index,
+		RelPath:       collapsePath(filepath.ToSlash(relPath), config.CollapseDepth),
+		AbsPath:       absPath,
+		Name:          filepath.Base(path),
+		Ext:           strings.TrimPrefix(filepath.Ext(path), "."),
+		Time:          config.combineTime(),
+		Language:      detectLanguage(path),
+		CommentPrefix: style.SingleLine,
+		CommentStart:  style.BlockStart,
+		CommentEnd:    style.BlockEnd,
+	}
+	if info, err := os.Stat(path); err == nil {
+		data.Size = info.Size()
+		data.ModTime = info.ModTime()
+	}
+	return data
+}
+
+// parseSeparatorTemplate parses the -separator-template once per run and
+// tries it on the root directory, so unknown fields fail up front instead
+// of at the first file. Validate only checks it; a run keeps the result.
+func parseSeparatorTemplate(config *Options) error {
+	if config.SeparatorTemplate == "" || config.separatorTemplate() != nil {
+		return nil
+	}
+	tmpl, err := template.New("separator").Option("missingkey=error").Parse(config.SeparatorTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid --separator-template: %v", err)
+	}
+	sample := newSeparatorData(config, config.Root, 1)
+	sample.sample = true
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return fmt.Errorf("invalid --separator-template: %v", err)
+	}
+	if config.run != nil {
+		config.run.separatorTemplate = tmpl
+	}
+	return nil
+}
+
+// renderSeparatorTemplate executes the -separator-template for a file; the
+// result is written as it is, without a comment frame
+func renderSeparatorTemplate(config *Options, path string, index int) string {
+	var sb strings.Builder
+	if err := config.separatorTemplate().Execute(&sb, newSeparatorData(config, path, index)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --separator-template failed for %s: %v\n", path, err)
+	}
+	return sb.String()
+}
+
+// documentData is what the -header and -footer templates are executed with
+type documentData struct {
+	Title     string
+	Root      string
+	Version   string
+	Time      time.Time // the run's timestamp
+	Files     []string  // root-relative paths of the files in the output
+	FileCount int
+	Size      int64 // total size of the files
+
+	config *Options
+	files  []string
+}
+
+// Tokens estimates the tokens of the files' sections with the
+// -token-estimator, computed only when a template asks for it
+func (d documentData) Tokens() int64 {
+	total := newTotals(d.config)
+	for _, section := range sectionTotals(d.config, d.files) {
+		total.add(section)
+	}
+	return total.tokens(d.config)
+}
+
+// documentFuncs are the functions the -header and -footer templates may use
+var documentFuncs = template.FuncMap{
+	"size": formatSize,
+}
+
+// newDocumentData collects the template values for the files of an output
+func newDocumentData(config *Options, files []string) documentData {
+	data := documentData{
+		Title:     documentTitle(config),
+		Root:      filepath.ToSlash(config.Root),
+		Version:   config.Version,
+		Time:      config.combineTime(),
+		Files:     make([]string, len(files)),
+		FileCount: len(files),
+		config:    config,
+		files:     files,
+	}
+	for i, file := range files {
+		relPath, _ := filepath.Rel(config.Root, file)
+		data.Files[i] = filepath.ToSlash(relPath)
+		if info, err := os.Stat(file); err == nil {
+			data.Size += info.Size()
+		}
+	}
+	return data
+}
+
+// parseDocumentTemplate parses -header or -footer text as a template
+func parseDocumentTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(documentFuncs).Option("missingkey=error").Parse(text)
+}
+
+// validateDocumentTemplates tries the -header and -footer templates on an
+// empty file list, so mistakes fail before anything is written
+func validateDocumentTemplates(config *Options) error {
+	for _, t := range []struct{ name, text string }{{"header", config.Header}, {"footer", config.Footer}} {
+		if !strings.Contains(t.text, "{{") {
+			continue
+		}
+		tmpl, err := parseDocumentTemplate(t.name, t.text)
+		if err == nil {
+			err = tmpl.Execute(&strings.Builder{}, newDocumentData(config, nil))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid --%s template: %v", t.name, err)
+		}
+	}
+	return nil
+}
+
+// renderDocumentTemplate executes -header or -footer text for the files;
+// text without template actions is returned as it is
+func renderDocumentTemplate(config *Options, name, text string, files []string) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := parseDocumentTemplate(name, text)
+	if err != nil {
+		return text // reported by Validate
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, newDocumentData(config, files)); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: --%s template failed: %v\n", name, err)
+	}
+	return sb.String()
+}
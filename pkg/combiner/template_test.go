@@ -0,0 +1,88 @@
+package combiner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSeparatorTemplate(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":     "package main\n",
+		"web/app.css": "p {}\n",
+		"notes.txt":   "hello\n",
+	})
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "index and path",
+			template: "== {{.Index}} {{.RelPath}} ==\n",
+			want: "== 1 main.go ==\npackage main\n" +
+				"== 2 notes.txt ==\nhello\n" +
+				"== 3 web/app.css ==\np {}\n",
+		},
+		{
+			name:     "file fields",
+			template: "{{.Name}} {{.Ext}} {{.Size}} {{.Language}}\n",
+			want: "main.go go 13 go\npackage main\n" +
+				"notes.txt txt 6 text\nhello\n" +
+				"app.css css 5 css\np {}\n",
+		},
+		{
+			name:     "comment delimiters",
+			template: "{{if .CommentPrefix}}{{.CommentPrefix}} {{.RelPath}}{{else}}{{.CommentStart}} {{.RelPath}} {{.CommentEnd}}{{end}}\n",
+			want: "// main.go\npackage main\n" +
+				"# notes.txt\nhello\n" +
+				"/* web/app.css */\np {}\n",
+		},
+		{
+			name:     "hash and time",
+			template: "{{.RelPath}} {{slice .SHA256 0 12}} {{.Time.UTC.Format \"2006-01-02\"}}\n",
+			want: "main.go df1d036cbbf3 2023-11-14\npackage main\n" +
+				"notes.txt 5891b5b522d5 2023-11-14\nhello\n" +
+				"web/app.css c9dd3e1410d3 2023-11-14\np {}\n",
+		},
+		{
+			name:     "no newline",
+			template: "[{{.RelPath}}]",
+			want:     "[main.go]package main\n[notes.txt]hello\n[web/app.css]p {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.SeparatorTemplate = tt.template
+			if got := combine(t, config); got != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeparatorTemplateValidation(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.go": "package a\n"})
+	tests := []struct {
+		template string
+		wantErr  string
+	}{
+		{"{{.RelPath}}\n", ""},
+		{"{{.SHA256}} {{.ModTime.Year}}\n", ""},
+		{"{{.Path}}\n", "can't evaluate field Path"},
+		{"{{.RelPath\n", "unclosed action"},
+		{"{{upper .RelPath}}\n", `function "upper" not defined`},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, root, "*.go")
+		config.SeparatorTemplate = tt.template
+		err := config.Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%q: unexpected error %v", tt.template, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), "invalid --separator-template") || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%q: error %v, want one containing %q", tt.template, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package combiner
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fileTiming is how long one file took to read and to write out
+type fileTiming struct {
+	Path  string
+	Read  time.Duration
+	Write time.Duration
+}
+
+// timingLog collects per-file timings under -debug; workers record concurrently
+type timingLog struct {
+	mu      sync.Mutex
+	entries []fileTiming
+}
+
+// record stores a timing and prints it next to the file's Processing line
+func (t *timingLog) record(path string, read, write time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, fileTiming{path, read, write})
+	fmt.Printf("  [debug] %s: read %v, write %v\n", filepath.Base(path), read, write)
+}
+
+// printSlowest lists the n files that took longest in total
+func (t *timingLog) printSlowest(root string, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.entries) == 0 {
+		return
+	}
+
+	entries := append([]fileTiming(nil), t.entries...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Read+entries[i].Write > entries[j].Read+entries[j].Write
+	})
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+
+	fmt.Printf("\nSLOWEST FILES (top %d):\n", len(entries))
+	for _, e := range entries {
+		relPath, _ := filepath.Rel(root, e.Path)
+		fmt.Printf("  %10v  %s (read %v, write %v)\n", e.Read+e.Write, relPath, e.Read, e.Write)
+	}
+}
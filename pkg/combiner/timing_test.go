@@ -0,0 +1,68 @@
+package combiner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebugTiming(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "a\n", "b.txt": "b\n", "sub/c.txt": "c\n"})
+	tests := []struct {
+		name  string
+		debug bool
+		jobs  int
+	}{
+		{"off", false, 0},
+		{"debug", true, 0},
+		{"debug with jobs", true, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.txt")
+			config.Rebuilding = false // the slowest files follow the final summary
+			config.Debug = tt.debug
+			config.Verbose = tt.debug
+			config.Jobs = tt.jobs
+			out := captureStdout(t, func() { combine(t, config) })
+
+			for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+				line := "  [debug] " + name + ": read "
+				if strings.Contains(out, line) != tt.debug {
+					t.Errorf("timing line for %s shown = %v, want %v:\n%s", name, !tt.debug, tt.debug, out)
+				}
+			}
+			if strings.Contains(out, "SLOWEST FILES (top 3):") != tt.debug {
+				t.Errorf("slowest files shown = %v, want %v:\n%s", !tt.debug, tt.debug, out)
+			}
+		})
+	}
+}
+
+func TestPrintSlowest(t *testing.T) {
+	root := t.TempDir()
+	var log timingLog
+	captureStdout(t, func() {
+		for i, name := range []string{"fast", "slowest", "medium", "slow", "quick", "instant"} {
+			read := []time.Duration{1, 90, 30, 40, 2, 0}[i] * time.Millisecond
+			log.record(filepath.Join(root, name), read, time.Millisecond)
+		}
+	})
+	out := captureStdout(t, func() { log.printSlowest(root, 3) })
+
+	var listed []string
+	for _, line := range strings.Split(out, "\n") {
+		if fields := strings.Fields(line); len(fields) > 1 && strings.HasSuffix(fields[0], "ms") {
+			listed = append(listed, fields[1])
+		}
+	}
+	if want := []string{"slowest", "slow", "medium"}; !sameStrings(listed, want) {
+		t.Errorf("listed %q, want %q:\n%s", listed, want, out)
+	}
+
+	var empty timingLog
+	if out := captureStdout(t, func() { empty.printSlowest(root, 3) }); out != "" {
+		t.Errorf("nothing timed, but printed:\n%s", out)
+	}
+}
@@ -0,0 +1,94 @@
+package combiner
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+// tocEntry is one file listed in the -toc manifest
+type tocEntry struct {
+	Index  int
+	Path   string
+	Size   int64
+	Line   int   // output line where the file's section body starts
+	Offset int64 // output byte where the file's content starts
+}
+
+// tocShift moves the positions of the entries past the header
+type tocShift struct {
+	Lines int
+	Bytes int64
+}
+
+// buildTOC renders every section once, without writing it, to learn where
+// each file lands in the output. Line numbers and byte offsets are counted
+// from the end of the document header.
+func buildTOC(config *Options, files []string) []tocEntry {
+	newline := getNewline(config.newlineType())
+	var entries []tocEntry
+	lines := 0
+	var offset int64
+	previous := ""
+
+	for idx, filePath := range files {
+		content, err := loadContent(config, filePath)
+		if err != nil {
+			continue // reported when the file is actually written
+		}
+
+		banner := directoryBanner(config, previous, filePath)
+		lines += countLines([]byte(banner))
+		offset += int64(len(banner))
+		previous = filePath
+		if !(config.NoLeadingSeparator && len(entries) == 0) {
+			separator := sectionSeparator(config, filePath, idx+1, content)
+			lines += countLines([]byte(separator))
+			offset += int64(len(separator))
+		}
+
+		var body bytes.Buffer
+		contentStart := writeSectionBody(&body, config, filePath, idx+1, content, newline)
+		relPath, _ := filepath.Rel(config.Root, filePath)
+		entries = append(entries, tocEntry{
+			Index:  idx + 1,
+			Path:   collapsePath(filepath.ToSlash(relPath), config.CollapseDepth),
+			Size:   int64(len(content)),
+			Line:   lines + 1,
+			Offset: offset + contentStart,
+		})
+		lines += countLines(body.Bytes())
+		offset += int64(body.Len())
+	}
+	return entries
+}
+
+// tocLines formats the manifest, shifting every position by the header.
+// Byte offsets are left out when the output is transcoded, as they are
+// counted in UTF-8.
+func tocLines(config *Options, entries []tocEntry, shift tocShift) []string {
+	lines := []string{"TABLE OF CONTENTS"}
+	var total int64
+	width := 1
+	if len(entries) > 0 {
+		width = len(strconv.Itoa(entries[len(entries)-1].Index))
+	}
+	enc, _ := lookupEncoding(config.Encoding)
+	for _, e := range entries {
+		if !enc.isUTF8() {
+			lines = append(lines, fmt.Sprintf("  %*d. %s (%s, line %d)", width, e.Index, e.Path, formatSize(e.Size), e.Line+shift.Lines))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %*d. %s (%s, line %d, byte %d)", width, e.Index, e.Path, formatSize(e.Size), e.Line+shift.Lines, e.Offset+shift.Bytes))
+		}
+		total += e.Size
+	}
+	lines = append(lines, fmt.Sprintf("Total: %d files, %s (%d bytes)", len(entries), formatSize(total), total))
+	return lines
+}
+
+// countLines counts the line terminators (LF, CRLF or CR) in data
+func countLines(data []byte) int {
+	counts := countLineEndings(data)
+	return counts.LF + counts.CRLF + counts.CR
+}
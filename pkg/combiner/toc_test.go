@@ -0,0 +1,82 @@
+package combiner
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var tocLine = regexp.MustCompile(`^# +\d+\. (\S+) \(.*, line (\d+), byte (\d+)\)$`)
+
+func TestTOCPositions(t *testing.T) {
+	files := map[string]string{
+		"a.go":       "package a\n",
+		"b.py":       "print('b')\n",
+		"docs/c.md":  "# c\n\ntext\n",
+		"docs/d.txt": "no newline",
+	}
+	tests := []struct {
+		name  string
+		setup func(*Options)
+	}{
+		{"plain", nil},
+		{"title", func(config *Options) { config.Title = "Project" }},
+		{"bom", func(config *Options) { config.BOM = "always" }},
+		{"tree and header", func(config *Options) { config.Tree = true; config.Header = "line one\nline two" }},
+		{"crlf", func(config *Options) { config.NewlineType = "crlf" }},
+		{"jobs", func(config *Options) { config.Jobs = 2 }},
+	}
+	root := writeTree(t, files)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.TOC = true
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+			lines := strings.Split(out, "\n")
+
+			found := 0
+			for _, line := range lines {
+				m := tocLine.FindStringSubmatch(strings.TrimRight(line, "\r"))
+				if m == nil {
+					continue
+				}
+				found++
+				want := files[m[1]]
+				byteOffset, _ := strconv.Atoi(m[3])
+				if !strings.HasPrefix(out[byteOffset:], want) {
+					t.Errorf("%s: byte %d starts %.20q, want %.20q", m[1], byteOffset, out[byteOffset:], want)
+				}
+				lineNo, _ := strconv.Atoi(m[2])
+				firstLine := strings.SplitN(want, "\n", 2)[0]
+				if lineNo < 1 || lineNo > len(lines) || strings.TrimRight(lines[lineNo-1], "\r") != firstLine {
+					t.Errorf("%s: line %d is not %q", m[1], lineNo, firstLine)
+				}
+			}
+			if found != len(files) {
+				t.Errorf("table of contents lists %d files, want %d:\n%s", found, len(files), out)
+			}
+		})
+	}
+}
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		data string
+		want int
+	}{
+		{"", 0},
+		{"no newline", 0},
+		{"a\n", 1},
+		{"a\r\nb\r\n", 2},
+		{"a\rb\nc\r\n", 3},
+	}
+	for _, tt := range tests {
+		if got := countLines([]byte(tt.data)); got != tt.want {
+			t.Errorf("countLines(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
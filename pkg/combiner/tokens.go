@@ -0,0 +1,67 @@
+package combiner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Tokenizers selectable with -token-estimator. Unlike the heuristics they
+// count real tokens, with the BPE encodings of OpenAI's tiktoken.
+const (
+	TOKENS_CL100K = "cl100k_base"
+	TOKENS_O200K  = "o200k_base"
+)
+
+// The BPE ranks are embedded in the binary, so counting tokens never
+// touches the network
+func init() {
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// tokenizers holds the encodings loaded so far; loading one decodes its
+// embedded BPE ranks
+var tokenizers = struct {
+	sync.Mutex
+	loaded map[string]*tiktoken.Tiktoken
+}{loaded: make(map[string]*tiktoken.Tiktoken)}
+
+// isTokenizer reports whether the -token-estimator counts real tokens
+func isTokenizer(name string) bool {
+	return name == TOKENS_CL100K || name == TOKENS_O200K
+}
+
+// loadTokenizer makes the named encoding ready for counting; the heuristic
+// estimators need nothing and always succeed
+func loadTokenizer(name string) error {
+	if !isTokenizer(name) {
+		return nil
+	}
+	tokenizers.Lock()
+	defer tokenizers.Unlock()
+	if tokenizers.loaded[name] != nil {
+		return nil
+	}
+	encoding, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		return fmt.Errorf("Cannot load the %s tokenizer: %v", name, err)
+	}
+	tokenizers.loaded[name] = encoding
+	return nil
+}
+
+// tokenizer returns the loaded encoding of the -token-estimator, or nil for
+// the heuristics
+func tokenizer(config *Options) *tiktoken.Tiktoken {
+	tokenizers.Lock()
+	defer tokenizers.Unlock()
+	return tokenizers.loaded[config.TokenEstimator]
+}
+
+// countTokens counts the tokens of text, taking special-token markers such
+// as <|endoftext|> for ordinary text
+func countTokens(encoding *tiktoken.Tiktoken, text []byte) int64 {
+	return int64(len(encoding.EncodeOrdinary(string(text))))
+}
@@ -0,0 +1,82 @@
+package combiner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int64
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"    indented", 3},
+		{"package main\n\nfunc main() {}\n", 7},
+		{"<|endoftext|>", 7}, // ordinary text, not the special token
+	}
+	for _, name := range []string{TOKENS_CL100K, TOKENS_O200K} {
+		if err := loadTokenizer(name); err != nil {
+			t.Fatal(err)
+		}
+		encoding := tokenizer(&Options{TokenEstimator: name})
+		for _, tt := range tests {
+			if got := countTokens(encoding, []byte(tt.text)); got != tt.want {
+				t.Errorf("%s: countTokens(%q) = %d, want %d", name, tt.text, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestHeuristicsHaveNoTokenizer(t *testing.T) {
+	for _, name := range []string{TOKENS_BYTES, TOKENS_WORDS} {
+		if err := loadTokenizer(name); err != nil {
+			t.Errorf("loadTokenizer(%s): %v", name, err)
+		}
+		if isTokenizer(name) || tokenizer(&Options{TokenEstimator: name}) != nil {
+			t.Errorf("%s is a heuristic, not a tokenizer", name)
+		}
+	}
+}
+
+func TestTokenizerTotals(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+		"b.md": "hello world\n",
+	})
+	for _, name := range []string{TOKENS_CL100K, TOKENS_O200K} {
+		t.Run(name, func(t *testing.T) {
+			config := testOptions(t, root, "*")
+			config.TokenEstimator = name
+			config.DryRun = true
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			report, err := New(config).Select(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			summary := captureStdout(t, func() { PrintSummary(config, report) })
+
+			// The per-file counts are the tokens of each rendered section
+			encoding := tokenizer(config)
+			var total int64
+			for i, section := range sectionTotals(config, report.Files) {
+				var rendered strings.Builder
+				content, _ := loadContent(config, report.Files[i])
+				rendered.WriteString(sectionSeparator(config, report.Files[i], i+1, content))
+				writeSectionBody(&rendered, config, report.Files[i], i+1, content, "\n")
+				if want := countTokens(encoding, []byte(rendered.String())); section.Tokens != want {
+					t.Errorf("section %d: %d tokens, want %d", i+1, section.Tokens, want)
+				}
+				total += section.Tokens
+			}
+			if want := fmt.Sprintf("~%d tokens, %s)", total, name); !strings.Contains(summary, want) {
+				t.Errorf("summary missing %q:\n%s", want, summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package combiner
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeNode is a directory (or, without children, a file) in the tree of
+// selected files
+type treeNode struct {
+	Name     string
+	Children map[string]*treeNode
+	Skipped  bool // a file excluded from the output, listed by -tree-skipped
+}
+
+// buildTree arranges the root-relative paths of files into a tree, adding
+// the skipped files when -tree-skipped asks for them
+func buildTree(config *Options, files []string, skipped []FileInfo) *treeNode {
+	root := &treeNode{}
+	add := func(file string, excluded bool) {
+		relPath, err := filepath.Rel(config.Root, file)
+		if err != nil || strings.HasPrefix(relPath, "..") {
+			return
+		}
+		node := root
+		for _, part := range strings.Split(filepath.ToSlash(relPath), "/") {
+			if node.Children == nil {
+				node.Children = make(map[string]*treeNode)
+			}
+			child, ok := node.Children[part]
+			if !ok {
+				child = &treeNode{Name: part, Skipped: excluded}
+				node.Children[part] = child
+			}
+			node = child
+		}
+	}
+	for _, file := range files {
+		add(file, false)
+	}
+	if config.TreeSkipped {
+		for _, file := range skipped {
+			add(file.Path, true)
+		}
+	}
+	return root
+}
+
+// sortedChildren lists a node's directories first, then its files, each by name
+func (n *treeNode) sortedChildren() []*treeNode {
+	children := make([]*treeNode, 0, len(n.Children))
+	for _, child := range n.Children {
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		if (children[i].Children != nil) != (children[j].Children != nil) {
+			return children[i].Children != nil
+		}
+		return children[i].Name < children[j].Name
+	})
+	return children
+}
+
+// label is the name shown for the node: directories end in a slash and
+// skipped files are marked
+func (n *treeNode) label() string {
+	switch {
+	case n.Children != nil:
+		return n.Name + "/"
+	case n.Skipped:
+		return n.Name + " (skipped)"
+	}
+	return n.Name
+}
+
+// indentedTree renders the tree with two spaces per level and a trailing
+// slash on directories, the layout of repomix's <directory_structure>
+func indentedTree(node *treeNode, depth int, lines []string) []string {
+	for _, child := range node.sortedChildren() {
+		lines = append(lines, strings.Repeat("  ", depth)+child.label())
+		lines = indentedTree(child, depth+1, lines)
+	}
+	return lines
+}
+
+// asciiTree renders the tree below a node with box-drawing branches, as the
+// tree command does; indent is the prefix of the node's own children
+func asciiTree(node *treeNode, indent string, lines []string) []string {
+	children := node.sortedChildren()
+	for i, child := range children {
+		branch, next := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, next = "└── ", "    "
+		}
+		lines = append(lines, indent+branch+child.label())
+		lines = asciiTree(child, indent+next, lines)
+	}
+	return lines
+}
+
+// treeLines is the -tree listing in the header of text and markdown output,
+// headed by the root directory's name
+func treeLines(config *Options, files []string) []string {
+	lines := []string{"DIRECTORY TREE", documentRootName(config) + "/"}
+	return asciiTree(buildTree(config, files, config.run.skipped), "", lines)
+}
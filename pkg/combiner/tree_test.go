@@ -0,0 +1,128 @@
+package combiner
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTreeRendering(t *testing.T) {
+	root := filepath.FromSlash("/project")
+	tests := []struct {
+		name     string
+		files    []string
+		skipped  []string
+		ascii    []string
+		indented []string
+	}{
+		{"no files", nil, nil, nil, nil},
+		{
+			name:     "flat",
+			files:    []string{"b.go", "a.go"},
+			ascii:    []string{"├── a.go", "└── b.go"},
+			indented: []string{"a.go", "b.go"},
+		},
+		{
+			name:  "directories first",
+			files: []string{"z.txt", "src/main.go", "src/util/str.go", "docs/a.md", "src/b.go"},
+			ascii: []string{
+				"├── docs/",
+				"│   └── a.md",
+				"├── src/",
+				"│   ├── util/",
+				"│   │   └── str.go",
+				"│   ├── b.go",
+				"│   └── main.go",
+				"└── z.txt",
+			},
+			indented: []string{"docs/", "  a.md", "src/", "  util/", "    str.go", "  b.go", "  main.go", "z.txt"},
+		},
+		{
+			name:     "skipped files",
+			files:    []string{"src/main.go"},
+			skipped:  []string{"src/main_test.go", "go.sum"},
+			ascii:    []string{"├── src/", "│   ├── main.go", "│   └── main_test.go (skipped)", "└── go.sum (skipped)"},
+			indented: []string{"src/", "  main.go", "  main_test.go (skipped)", "go.sum (skipped)"},
+		},
+		{
+			name:     "outside the root",
+			files:    []string{"../elsewhere.go", "in.go"},
+			ascii:    []string{"└── in.go"},
+			indented: []string{"in.go"},
+		},
+	}
+	abs := func(paths []string) []string {
+		var out []string
+		for _, path := range paths {
+			out = append(out, filepath.Join(root, filepath.FromSlash(path)))
+		}
+		return out
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultOptions()
+			config.Root = root
+			config.TreeSkipped = tt.skipped != nil
+			var skipped []FileInfo
+			for _, path := range abs(tt.skipped) {
+				skipped = append(skipped, FileInfo{Path: path, Reason: "excluded"})
+			}
+			tree := buildTree(config, abs(tt.files), skipped)
+			if got := asciiTree(tree, "", nil); !sameStrings(got, tt.ascii) {
+				t.Errorf("asciiTree:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.ascii, "\n"))
+			}
+			if got := indentedTree(tree, 0, nil); !sameStrings(got, tt.indented) {
+				t.Errorf("indentedTree:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.indented, "\n"))
+			}
+		})
+	}
+}
+
+func TestTreeHeader(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":          "package main\n",
+		"lib/util.go":      "package lib\n",
+		"lib/util_test.go": "package lib\n",
+	})
+	name := filepath.Base(root)
+	rule := strings.Repeat("=", 70)
+	tests := []struct {
+		name  string
+		setup func(*Options)
+		want  string
+	}{
+		{
+			name: "text",
+			want: "# " + rule + "\n# DIRECTORY TREE\n# " + name + "/\n# ├── lib/\n# │   └── util.go\n# └── main.go\n# " + rule + "\n",
+		},
+		{
+			name:  "with skipped files",
+			setup: func(config *Options) { config.TreeSkipped = true },
+			want:  "# " + rule + "\n# DIRECTORY TREE\n# " + name + "/\n# ├── lib/\n# │   ├── util.go\n# │   └── util_test.go (skipped)\n# └── main.go\n# " + rule + "\n",
+		},
+		{
+			name:  "below the title",
+			setup: func(config *Options) { config.Title = "Service" },
+			want:  "# " + rule + "\n# Service\n# \n# DIRECTORY TREE\n# " + name + "/\n# ├── lib/\n# │   └── util.go\n# └── main.go\n# " + rule + "\n",
+		},
+		{
+			name:  "markdown",
+			setup: func(config *Options) { config.Format = FORMAT_MARKDOWN },
+			want:  "# " + name + "\n<!--\n " + rule + "\n DIRECTORY TREE\n " + name + "/\n ├── lib/\n │   └── util.go\n └── main.go\n " + rule + "\n-->\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*.go")
+			config.Excludes = []string{"*_test.go"}
+			config.Tree = true
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			out := combine(t, config)
+			if !strings.HasPrefix(out, tt.want) {
+				t.Errorf("output starts:\n%s\nwant:\n%s", out[:min(len(out), len(tt.want)+40)], tt.want)
+			}
+		})
+	}
+}
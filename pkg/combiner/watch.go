@@ -0,0 +1,203 @@
+package combiner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// WATCH_DEBOUNCE is how long -watch waits by default after the last change
+// before it rebuilds, so a burst of saves triggers a single run
+const WATCH_DEBOUNCE = 300 * time.Millisecond
+
+// watchDirs registers root and every directory below it that the file
+// discovery would enter
+func watchDirs(watcher *fsnotify.Watcher, config *Options, root string) {
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && (d.Name() == ".git" || excludedDir(path, config.Root, config.Excludes, config.ExcludeSubstring, config.IgnoreCase)) {
+			return filepath.SkipDir
+		}
+		if err := watcher.Add(path); err != nil && config.Verbose {
+			fmt.Fprintf(os.Stderr, "Warning: Cannot watch %s: %v\n", path, err)
+		}
+		return nil
+	})
+}
+
+// Watch watches the roots for changes after the first combine, whose
+// selection is report, and runs Select and Combine again for each burst of
+// changes, so new matching files are picked up and deleted ones drop out.
+// It returns when interrupted or when ctx is done.
+func (c *Combiner) Watch(ctx context.Context, report Report) int {
+	config := c.Options
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: Cannot start watching: %v\n", err)
+		return 2
+	}
+	defer watcher.Close()
+	roots := searchRoots(config)
+	for _, root := range roots {
+		watchDirs(watcher, config, root)
+	}
+	current := fileSet(report.Files)
+
+	// Writing the output must not trigger another rebuild
+	ownFiles := make(map[string]bool)
+	for _, path := range []string{config.Output, config.Manifest} {
+		if path != "" {
+			abs, _ := filepath.Abs(path)
+			ownFiles[abs] = true
+		}
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	// The rebuilds keep their reports short, on a copy of the options
+	options := *config
+	options.Rebuilding = true
+	rebuilder := New(&options)
+	fmt.Printf("Watching %s for changes (Ctrl+C to stop)\n", strings.Join(roots, ", "))
+
+	timer := time.NewTimer(config.WatchDebounce)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return 0
+			}
+			if abs, _ := filepath.Abs(event.Name); ownFiles[abs] {
+				continue
+			}
+			created := false
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					watchDirs(watcher, config, event.Name)
+					created = true
+				}
+			}
+			if !created && !watchRelevant(config, current, event.Name) {
+				continue
+			}
+			if config.Debug {
+				fmt.Printf("  Change: %s\n", event)
+			}
+			timer.Reset(config.WatchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return 0
+			}
+			fmt.Fprintf(os.Stderr, "Warning: Watch error: %v\n", err)
+		case <-timer.C:
+			if files := rebuilder.rebuild(ctx); files != nil {
+				current = fileSet(files)
+			}
+		case <-interrupt:
+			fmt.Println("\nStopped watching")
+			return 0
+		case <-ctx.Done():
+			fmt.Println("Stopped watching")
+			return 0
+		}
+	}
+}
+
+// fileSet indexes files by absolute path
+func fileSet(files []string) map[string]bool {
+	set := make(map[string]bool, len(files))
+	for _, file := range files {
+		abs, _ := filepath.Abs(file)
+		set[abs] = true
+	}
+	return set
+}
+
+// watchRelevant reports whether a change to path can alter the output: a
+// file that is part of it (or a directory holding one), a file matching the
+// patterns, or one of the files that steer the selection
+func watchRelevant(config *Options, current map[string]bool, path string) bool {
+	abs, _ := filepath.Abs(path)
+	if current[abs] {
+		return true
+	}
+	switch filepath.Base(path) {
+	case ".gitignore", ".gitattributes":
+		return true
+	}
+	if config.FilesFrom != "" {
+		if list, _ := filepath.Abs(config.FilesFrom); list == abs {
+			return true
+		}
+	}
+
+	// A removed or renamed directory takes its files along
+	prefix := abs + string(filepath.Separator)
+	for file := range current {
+		if strings.HasPrefix(file, prefix) {
+			return true
+		}
+	}
+
+	root, _ := filepath.Abs(config.Root)
+	relPath, err := filepath.Rel(root, abs)
+	if err != nil {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range config.Patterns {
+		if !isNegation(pattern) && config.matchGlob(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuild runs one -watch combine through Select and Combine, so the git
+// selections, -min-files, -tree-hash and -self-check apply as on the first
+// run, and reports it on a single line. It returns the files combined, or
+// nil when nothing was written.
+func (c *Combiner) rebuild(ctx context.Context) []string {
+	config := c.Options
+	stamp := time.Now().Format("15:04:05")
+	report, err := c.Select(ctx)
+	if err != nil {
+		reportRebuildError(stamp, err)
+		return nil
+	}
+	combined := withoutOutput(config, report.Files)
+	if len(combined) == 0 {
+		fmt.Printf("[%s] No files match; %s left unchanged\n", stamp, config.Output)
+		return nil
+	}
+
+	if err := c.Combine(ctx, report); err != nil {
+		reportRebuildError(stamp, err)
+		return nil
+	}
+	fmt.Printf("[%s] Rebuilt %d files (%s)\n", stamp, len(combined), c.last.totals.describe(config))
+	return report.Files
+}
+
+// reportRebuildError prints why a rebuild failed, unless the run already did
+func reportRebuildError(stamp string, err error) {
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.Err == nil {
+		fmt.Fprintf(os.Stderr, "[%s] Rebuild failed\n", stamp)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[%s] Error: %v\n", stamp, err)
+}
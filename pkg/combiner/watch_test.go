@@ -0,0 +1,150 @@
+package combiner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchRelevant(t *testing.T) {
+	root := filepath.FromSlash("/project")
+	abs := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+	current := fileSet([]string{abs("a.go"), abs("sub/b.go")})
+	tests := []struct {
+		name      string
+		path      string
+		filesFrom string
+		want      bool
+	}{
+		{"combined file", "a.go", "", true},
+		{"new matching file", "new.go", "", true},
+		{"new matching file below", "sub/deep/new.go", "", true},
+		{"other file", "notes.txt", "", false},
+		{"gitignore", ".gitignore", "", true},
+		{"nested gitattributes", "sub/.gitattributes", "", true},
+		{"directory with combined files", "sub", "", true},
+		{"other directory", "docs", "", false},
+		{"files-from list", "list.txt", "list.txt", true},
+		{"not the files-from list", "other.txt", "list.txt", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultOptions()
+			config.Root = root
+			config.Patterns = []string{"**/*.go"}
+			if tt.filesFrom != "" {
+				config.FilesFrom = abs(tt.filesFrom)
+			}
+			if got := watchRelevant(config, current, abs(tt.path)); got != tt.want {
+				t.Errorf("watchRelevant(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchRebuilds(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt":        "a\n",
+		"b.txt":        "b\n",
+		"notes.md":     "notes\n",
+		"vendor/v.txt": "vendored\n",
+	})
+	config := testOptions(t, root, "**/*.txt")
+	config.Output = filepath.Join(root, "out.txt") // matches the pattern, but never triggers a rebuild
+	config.Excludes = []string{"vendor"}
+	config.WatchDebounce = 100 * time.Millisecond
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	c := New(config)
+	report, err := c.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor := func(what string, ok func(string) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if data, err := os.ReadFile(config.Output); err == nil && ok(string(data)) {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Fatalf("no rebuild after %s", what)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stdout := captureStdout(t, func() {
+		done := make(chan int)
+		go func() { done <- c.Watch(ctx, report) }()
+		time.Sleep(200 * time.Millisecond) // the watches are in place
+
+		// A burst of saves is a single rebuild
+		for i := 1; i <= 3; i++ {
+			write("a.txt", strings.Repeat("a", i)+" changed\n")
+		}
+		waitFor("changing a.txt", func(out string) bool { return strings.Contains(out, "aaa changed") })
+
+		write("c.txt", "new file\n")
+		waitFor("creating c.txt", func(out string) bool { return strings.Contains(out, "new file") })
+
+		if err := os.Remove(filepath.Join(root, "b.txt")); err != nil {
+			t.Fatal(err)
+		}
+		waitFor("removing b.txt", func(out string) bool { return !strings.Contains(out, "b.txt") })
+
+		// Neither a file outside the patterns nor an excluded one rebuilds
+		write("notes.md", "more notes\n")
+		write("vendor/v.txt", "vendored, changed\n")
+		time.Sleep(4 * config.WatchDebounce)
+
+		cancel()
+		if status := <-done; status != 0 {
+			t.Errorf("Watch returned %d", status)
+		}
+	})
+
+	if n := strings.Count(stdout, "] Rebuilt "); n != 3 {
+		t.Errorf("%d rebuilds, want 3:\n%s", n, stdout)
+	}
+	for _, want := range []string{"Watching " + root, "Rebuilt 2 files", "Rebuilt 3 files", "Stopped watching"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout lacks %q:\n%s", want, stdout)
+		}
+	}
+	out, _ := os.ReadFile(config.Output)
+	if strings.Contains(string(out), "vendored") || strings.Contains(string(out), "notes") {
+		t.Errorf("output has files outside the selection:\n%s", out)
+	}
+}
+
+func TestWatchValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(*Options)
+		wantErr string
+	}{
+		{"dry run", func(config *Options) { config.DryRun = true }, "--watch cannot be combined with --dry-run"},
+		{"list", func(config *Options) { config.List = true }, "--watch cannot be combined with --dry-run"},
+		{"append", func(config *Options) { config.Append = true }, "--watch cannot be combined with --append"},
+		{"files from stdin", func(config *Options) { config.FilesFrom = "-" }, "not stdin"},
+	}
+	for _, tt := range tests {
+		config := testOptions(t, t.TempDir(), "*")
+		config.Watch = true
+		tt.setup(config)
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Validate = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package combiner
+
+import (
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+)
+
+// xmlAttr escapes s for use inside a double-quoted XML attribute
+func xmlAttr(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// xmlFileTag opens a file's element in -format xml output
+func xmlFileTag(config *Options, path string) string {
+	relPath, _ := filepath.Rel(config.Root, path)
+	relPath = collapsePath(filepath.ToSlash(relPath), config.CollapseDepth)
+	return "\n<file path=\"" + xmlAttr(relPath) + "\">\n"
+}
+
+// xmlPreamble opens the -format xml document: the <directory_structure>
+// of the selected files with -tree, then the <files> element holding them.
+// File contents are written as they are, not escaped, as repomix does.
+func xmlPreamble(config *Options, files []string) string {
+	var sb strings.Builder
+	if config.Tree {
+		sb.WriteString("<directory_structure>\n")
+		for _, line := range indentedTree(buildTree(config, files, config.run.skipped), 0, nil) {
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("</directory_structure>\n\n")
+	}
+	sb.WriteString("<files>\n")
+	return sb.String()
+}
@@ -0,0 +1,89 @@
+package combiner
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestXMLAttr(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"src/main.go", "src/main.go"},
+		{"a & b.txt", "a &amp; b.txt"},
+		{`say "hi".md`, "say &#34;hi&#34;.md"},
+		{"<tag>.html", "&lt;tag&gt;.html"},
+		{"it's.txt", "it&#39;s.txt"},
+	}
+	for _, tt := range tests {
+		if got := xmlAttr(tt.in); got != tt.want {
+			t.Errorf("xmlAttr(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		var element struct {
+			Path string `xml:"path,attr"`
+		}
+		if err := xml.Unmarshal([]byte(`<file path="`+xmlAttr(tt.in)+`"/>`), &element); err != nil || element.Path != tt.in {
+			t.Errorf("attribute %q reads back as %q (%v)", xmlAttr(tt.in), element.Path, err)
+		}
+	}
+}
+
+func TestXMLOutput(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.go":          "package a\n",
+		"c.txt":         "no newline",
+		"sub/e.txt":     "a < b && c\n",
+		`sub/"q" & .md`: "# q\n",
+	})
+	tests := []struct {
+		name  string
+		setup func(*Options)
+		want  string
+	}{
+		{
+			name: "files",
+			want: "<files>\n" +
+				"\n<file path=\"a.go\">\npackage a\n</file>\n" +
+				"\n<file path=\"c.txt\">\nno newline\n</file>\n" +
+				"\n<file path=\"sub/&#34;q&#34; &amp; .md\">\n# q\n</file>\n" +
+				"\n<file path=\"sub/e.txt\">\na < b && c\n</file>\n" +
+				"\n</files>\n",
+		},
+		{
+			name:  "directory structure",
+			setup: func(config *Options) { config.Tree = true },
+			want: "<directory_structure>\nsub/\n  \"q\" & .md\n  e.txt\na.go\nc.txt\n</directory_structure>\n\n" +
+				"<files>\n" +
+				"\n<file path=\"a.go\">\npackage a\n</file>\n" +
+				"\n<file path=\"c.txt\">\nno newline\n</file>\n" +
+				"\n<file path=\"sub/&#34;q&#34; &amp; .md\">\n# q\n</file>\n" +
+				"\n<file path=\"sub/e.txt\">\na < b && c\n</file>\n" +
+				"\n</files>\n",
+		},
+		{
+			name: "skipped files in the structure",
+			setup: func(config *Options) {
+				config.Tree = true
+				config.TreeSkipped = true
+				config.Excludes = []string{"*.md", "*.txt"}
+			},
+			want: "<directory_structure>\nsub/\n  \"q\" & .md (skipped)\n  e.txt (skipped)\na.go\nc.txt (skipped)\n</directory_structure>\n\n" +
+				"<files>\n" +
+				"\n<file path=\"a.go\">\npackage a\n</file>\n" +
+				"\n</files>\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testOptions(t, root, "**/*")
+			config.Format = FORMAT_XML
+			if tt.setup != nil {
+				tt.setup(config)
+			}
+			if out := combine(t, config); out != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out, tt.want)
+			}
+		})
+	}
+}